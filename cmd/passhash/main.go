@@ -0,0 +1,49 @@
+// Command passhash prints the argon2id hash of a password read from stdin for
+// BOOTSTRAP_ADMIN_HASH. With -totp it also prints a new BOOTSTRAP_ADMIN_TOTP
+// secret and the otpauth URL to add to an authenticator app.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/pkg/passhash"
+	"github.com/anton1ks96/college-auth-svc/pkg/totp"
+)
+
+func main() {
+	withTOTP := flag.Bool("totp", false, "also generate a TOTP secret")
+	flag.Parse()
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		fmt.Fprintln(os.Stderr, "failed to read password:", err)
+		os.Exit(1)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		fmt.Fprintln(os.Stderr, "empty password")
+		os.Exit(1)
+	}
+
+	hash, err := passhash.Hash(password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("BOOTSTRAP_ADMIN_HASH='%s'\n", hash)
+
+	if *withTOTP {
+		secret, err := totp.NewSecret()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("BOOTSTRAP_ADMIN_TOTP=%s\n", secret)
+		fmt.Fprintln(os.Stderr, totp.URL("college-auth-svc", "admin", secret))
+	}
+}
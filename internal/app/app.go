@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/anton1ks96/college-auth-svc/internal/config"
@@ -10,7 +11,9 @@ import (
 	service "github.com/anton1ks96/college-auth-svc/internal/services"
 	"github.com/anton1ks96/college-auth-svc/pkg/auth"
 	"github.com/anton1ks96/college-auth-svc/pkg/database/mongodb"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
 	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/notify"
 )
 
 func Run() {
@@ -24,20 +27,63 @@ func Run() {
 		logger.Fatal(err)
 	}
 
-	userRepo := repository.NewUserRepository(cfg)
+	ldapPool := ldappool.New(cfg.LDAP)
+
+	userRepo := repository.NewUserRepository(cfg, ldapPool)
 	sessRepo := repository.NewSessionsRepository(cfg, db)
+	profileRepo := repository.NewProfileRepository(cfg, db)
+	resetRepo := repository.NewPasswordResetRepository(cfg, db)
+	localAccountRepo := repository.NewLocalAccountRepository(cfg, db)
+	var directoryRepo repository.DirectoryLDAPRepository = repository.NewDirectoryRepository(cfg, ldapPool)
+	var fixtureRepo repository.FixtureUserRepository
+	if cfg.App.Test {
+		fixtures, err := repository.NewFixtureRepository(cfg)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		directoryRepo = fixtures
+		fixtureRepo = fixtures
+		logger.Warn(fmt.Sprintf("Test mode: users, searches and rosters are served from %s", cfg.App.Fixtures))
+	}
+	mirrorRepo := repository.NewMirrorRepository(cfg, db)
+	if cfg.Sync.Enabled && cfg.Sync.Fallback {
+		userRepo.UseMirror(mirrorRepo)
+	}
+	auditRepo := repository.NewAuditRepository(cfg, db)
+	accessRepo := repository.NewAccessRepository(cfg, db)
+	personalTokenRepo := repository.NewPersonalTokenRepository(cfg, db)
+
+	notifier, err := notify.New(cfg)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	tokenManager := auth.NewManager(cfg)
 
 	services := service.NewServices(service.Deps{
 		Repos: &service.Repositories{
-			UserRepo:    userRepo,
-			SessionRepo: sessRepo,
+			UserRepo:          userRepo,
+			SessionRepo:       sessRepo,
+			ProfileRepo:       profileRepo,
+			ResetRepo:         resetRepo,
+			LocalAccountRepo:  localAccountRepo,
+			FixtureRepo:       fixtureRepo,
+			AuditRepo:         auditRepo,
+			AccessRepo:        accessRepo,
+			PersonalTokenRepo: personalTokenRepo,
+			DirectoryRepo:     directoryRepo,
+			MirrorRepo:        mirrorRepo,
 		},
 		TokenManager: tokenManager,
+		LDAPPool:     ldapPool,
+		Notifier:     notifier,
 		Config:       cfg,
 	})
 
+	if !cfg.App.Test {
+		services.DirectorySyncService.Start(context.Background())
+	}
+
 	handler := handlers.NewHandler(services, *tokenManager, cfg)
 
 	router := handler.Init()
@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/anton1ks96/college-auth-svc/pkg/logger"
@@ -13,15 +15,29 @@ import (
 
 type (
 	Config struct {
-		Server  Server
-		Limiter LimiterConfig
-		Mongo   MongoConfig
-		JWT     JWTConfig
-		LDAP    LDAPConfig
-		App     App
-		Tokens  Tokens
+		Server        Server
+		Limiter       LimiterConfig
+		Mongo         MongoConfig
+		JWT           JWTConfig
+		LDAP          LDAPConfig
+		App           App
+		Tokens        Tokens
+		Roles         RolesConfig
+		Groups        GroupsConfig
+		Password      PasswordConfig
+		Reset         ResetConfig
+		SMTP          SMTPConfig
+		Sync          DirectorySyncConfig
+		Profile       ProfileConfig
+		Directory     DirectoryConfig
+		Search        SearchConfig
+		Auth          AuthConfig
+		Bootstrap     BootstrapConfig
+		Impersonation ImpersonationConfig
+		PersonalToken PersonalTokenConfig
 	}
 	Server struct {
+		Host           string
 		Port           string
 		ReadTimeout    time.Duration
 		WriteTimeout   time.Duration
@@ -29,7 +45,9 @@ type (
 	}
 
 	App struct {
-		Test bool
+		Test      bool
+		AllowTest bool
+		Fixtures  string
 	}
 
 	LimiterConfig struct {
@@ -39,13 +57,32 @@ type (
 	}
 
 	LDAPConfig struct {
-		URL string
+		URL              string
+		Endpoints        []string
+		Strategy         string
+		DialTimeout      time.Duration
+		Timeout          time.Duration
+		FailureThreshold int
+		OpenTimeout      time.Duration
+		BindDN           string
+		BindPassword     string
 	}
 
 	MongoConfig struct {
-		URI      string
-		DBName   string
-		CollName string
+		URI                   string
+		DBName                string
+		CollName              string
+		ResetCollName         string
+		ResetAttemptsCollName string
+		UsersCollName         string
+		LocalUsersCollName    string
+		DirUsersCollName      string
+		DirGroupsCollName     string
+		SyncCollName          string
+		AuditCollName         string
+		SuspensionsCollName   string
+		SettingsCollName      string
+		TokensCollName        string
 	}
 
 	JWTConfig struct {
@@ -57,6 +94,116 @@ type (
 	Tokens struct {
 		InternalToken string
 	}
+
+	RolesConfig struct {
+		Default string
+		Rules   []RoleRule
+	}
+
+	RoleRule struct {
+		Name      string
+		Role      string
+		Group     string
+		OU        string
+		Attribute string
+		Value     string
+		Stop      bool
+	}
+
+	PasswordConfig struct {
+		MinLength      int
+		RequireUpper   bool
+		RequireLower   bool
+		RequireDigit   bool
+		RequireSpecial bool
+		ForbidUserID   bool
+	}
+
+	ResetConfig struct {
+		TokenTTL   time.Duration
+		LinkURL    string
+		Notifier   string
+		FilePath   string
+		Window     time.Duration
+		MaxPerUser int
+		MaxPerIP   int
+	}
+
+	SMTPConfig struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+		From     string
+	}
+
+	DirectoryConfig struct {
+		BatchLimit int
+	}
+
+	AuthConfig struct {
+		Providers []AuthProvider
+	}
+
+	// BootstrapConfig describes the built-in admin. The credentials come from
+	// the environment only.
+	BootstrapConfig struct {
+		Enabled      bool
+		PasswordHash string
+		TOTPSecret   string
+	}
+
+	ImpersonationConfig struct {
+		TokenTTL time.Duration
+	}
+
+	PersonalTokenConfig struct {
+		Scopes     []string
+		DefaultTTL time.Duration
+		MaxTTL     time.Duration
+		MaxPerUser int
+	}
+
+	AuthProvider struct {
+		Type    string
+		Pattern string
+	}
+
+	SearchConfig struct {
+		CacheSize int
+		CacheTTL  time.Duration
+	}
+
+	ProfileConfig struct {
+		StaleAfter time.Duration
+	}
+
+	DirectorySyncConfig struct {
+		Enabled      bool
+		Interval     time.Duration
+		FullInterval time.Duration
+		PageSize     int
+		Fallback     bool
+	}
+
+	GroupsConfig struct {
+		Categories []GroupCategory
+		Nested     NestedGroupsConfig
+	}
+
+	NestedGroupsConfig struct {
+		Enabled  bool
+		Strategy string
+		MaxDepth int
+		CacheTTL time.Duration
+	}
+
+	GroupCategory struct {
+		Name        string
+		Description string
+		Pattern     string
+		Allowed     []string
+	}
 )
 
 func Init() (*Config, error) {
@@ -81,6 +228,11 @@ func Init() (*Config, error) {
 		return nil, fmt.Errorf("failed to set environment variables: %w", err)
 	}
 
+	if err := checkTestMode(&cfg); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
@@ -97,7 +249,12 @@ func setFromEnv(cfg *Config) error {
 	cfg.Mongo.DBName = os.Getenv("MONGODB_DBNAME")
 	cfg.Mongo.CollName = os.Getenv("MONGODB_CNAME")
 	cfg.JWT.SigningKey = os.Getenv("SIGNING_KEY")
+	cfg.SMTP.Username = os.Getenv("SMTP_USERNAME")
+	cfg.SMTP.Password = os.Getenv("SMTP_PASSWORD")
 	cfg.LDAP.URL = os.Getenv("LDAP_URL")
+	cfg.LDAP.Endpoints = SplitList(cfg.LDAP.URL)
+	cfg.LDAP.BindDN = os.Getenv("BIND_USERNAME")
+	cfg.LDAP.BindPassword = os.Getenv("BIND_PASSWORD")
 
 	if cfg.Mongo.URI == "" {
 		return errors.New("MONGODB_URI environment variable is required")
@@ -105,19 +262,70 @@ func setFromEnv(cfg *Config) error {
 	if cfg.JWT.SigningKey == "" {
 		return errors.New("SIGNING_KEY environment variable is required")
 	}
-	if cfg.LDAP.URL == "" {
+	if len(cfg.LDAP.Endpoints) == 0 {
 		return errors.New("LDAP_URL environment variable is required")
 	}
 	cfg.Tokens.InternalToken = os.Getenv("INTERNAL_SERVICE_TOKEN")
 	if cfg.Tokens.InternalToken == "" {
 		return errors.New("INTERNAL_SERVICE_TOKEN environment variable is required")
 	}
-	//if cfg.LDAP.BindPassword == "" {
-	//	return errors.New("BIND_PASSWORD environment variable is required")
-	//}
-	//if cfg.LDAP.BindUsername == "" {
-	//	return errors.New("BIND_USERNAME environment variable is required")
-	//}
+	if cfg.LDAP.BindDN != "" && cfg.LDAP.BindPassword == "" {
+		return errors.New("BIND_PASSWORD environment variable is required when BIND_USERNAME is set")
+	}
+
+	var err error
+	if cfg.Bootstrap.PasswordHash, err = envOrFile("BOOTSTRAP_ADMIN_HASH"); err != nil {
+		return err
+	}
+	if cfg.Bootstrap.TOTPSecret, err = envOrFile("BOOTSTRAP_ADMIN_TOTP"); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// envOrFile reads name, or the file named by name_FILE as used for Docker
+// and Kubernetes secrets.
+func envOrFile(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// checkTestMode refuses test mode, which signs in fixture users with known
+// passwords, unless the server only listens on loopback or it is allowed
+// explicitly.
+func checkTestMode(cfg *Config) error {
+	if !cfg.App.Test || cfg.App.AllowTest {
+		return nil
+	}
+
+	host := cfg.Server.Host
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+
+	return errors.New("test mode requires server.host to be a loopback address or app.allowTest to be set")
+}
+
+// SplitList parses a comma-separated environment value, dropping empty items.
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
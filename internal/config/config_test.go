@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestCheckTestMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "test mode off", cfg: Config{}},
+		{name: "all interfaces", cfg: Config{App: App{Test: true}}, wantErr: true},
+		{name: "public address", cfg: Config{Server: Server{Host: "10.0.0.5"}, App: App{Test: true}}, wantErr: true},
+		{name: "loopback", cfg: Config{Server: Server{Host: "127.0.0.1"}, App: App{Test: true}}},
+		{name: "ipv6 loopback", cfg: Config{Server: Server{Host: "::1"}, App: App{Test: true}}},
+		{name: "localhost", cfg: Config{Server: Server{Host: "localhost"}, App: App{Test: true}}},
+		{name: "allowed explicitly", cfg: Config{App: App{Test: true, AllowTest: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTestMode(&tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// Audited actions.
+const (
+	AuditBootstrapSignIn       = "bootstrap.sign_in"
+	AuditBootstrapSignInFailed = "bootstrap.sign_in_failed"
+	AuditImpersonation         = "user.impersonate"
+	AuditSuspend               = "user.suspend"
+	AuditUnsuspend             = "user.unsuspend"
+	AuditAccessPolicy          = "access.policy"
+)
+
+// AuditEvent records a security-relevant action. Actor is the user who acted
+// and Subject the user acted upon, if different.
+type AuditEvent struct {
+	Action    string    `json:"action" bson:"action"`
+	Actor     string    `json:"actor" bson:"actor"`
+	Subject   string    `json:"subject,omitempty" bson:"subject,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty" bson:"client_ip,omitempty"`
+	Details   string    `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+const (
+	DirectoryBranchPeople   = "People"
+	DirectoryBranchTeachers = "Teachers"
+
+	SyncModeFull        = "full"
+	SyncModeIncremental = "incremental"
+)
+
+// DirectoryUser is an account mirrored from LDAP.
+type DirectoryUser struct {
+	UserID          string    `json:"userid" bson:"_id"`
+	DN              string    `json:"dn" bson:"dn"`
+	Username        string    `json:"username" bson:"username"`
+	Mail            string    `json:"mail,omitempty" bson:"mail,omitempty"`
+	Branch          string    `json:"branch" bson:"branch"`
+	ModifyTimestamp string    `json:"modify_timestamp,omitempty" bson:"modify_timestamp,omitempty"`
+	SyncedAt        time.Time `json:"synced_at" bson:"synced_at"`
+}
+
+// DirectoryGroup is a group under ou=Current mirrored from LDAP. Members holds
+// lower-cased member DNs so lookups by DN are case-insensitive.
+type DirectoryGroup struct {
+	DN              string    `json:"dn" bson:"_id"`
+	Name            string    `json:"name" bson:"name"`
+	Description     string    `json:"description,omitempty" bson:"description,omitempty"`
+	Members         []string  `json:"members,omitempty" bson:"members,omitempty"`
+	MemberUIDs      []string  `json:"member_uids,omitempty" bson:"member_uids,omitempty"`
+	ModifyTimestamp string    `json:"modify_timestamp,omitempty" bson:"modify_timestamp,omitempty"`
+	SyncedAt        time.Time `json:"synced_at" bson:"synced_at"`
+}
+
+// SyncStatus describes the last run of the directory mirror sync. Watermark
+// is the newest modifyTimestamp seen and bounds the next incremental run.
+type SyncStatus struct {
+	Running        bool       `json:"running" bson:"-"`
+	Mode           string     `json:"mode,omitempty" bson:"mode,omitempty"`
+	Watermark      string     `json:"watermark,omitempty" bson:"watermark,omitempty"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty" bson:"last_success_at,omitempty"`
+	LastFullSyncAt *time.Time `json:"last_full_sync_at,omitempty" bson:"last_full_sync_at,omitempty"`
+	Duration       string     `json:"duration,omitempty" bson:"duration,omitempty"`
+	UsersSynced    int        `json:"users_synced" bson:"users_synced"`
+	GroupsSynced   int        `json:"groups_synced" bson:"groups_synced"`
+	UsersRemoved   int64      `json:"users_removed" bson:"users_removed"`
+	GroupsRemoved  int64      `json:"groups_removed" bson:"groups_removed"`
+	LastError      string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "errors"
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountExpired     = errors.New("account has expired")
+	ErrAccountSuspended   = errors.New("account is suspended")
+	ErrAccessRestricted   = errors.New("sign-in is restricted")
+)
+
+// Reasons a signed-in user no longer has access, found when their profile is
+// reloaded from the directory.
+var (
+	ErrAccountNotFound = errors.New("account not found in directory")
+	ErrAccountLocked   = errors.New("account is locked")
+	ErrAccountInactive = errors.New("account is not in the current academic year")
+)
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// LocalAccount is a user kept in MongoDB rather than LDAP, such as a visiting
+// lecturer or an exam proctor.
+type LocalAccount struct {
+	ID           string      `json:"id" bson:"_id"`
+	Username     string      `json:"username" bson:"username"`
+	PasswordHash string      `json:"-" bson:"password_hash"` // argon2id, PHC string format
+	Role         string      `json:"role" bson:"role"`
+	Groups       *UserGroups `json:"groups,omitempty" bson:"groups,omitempty"`
+	ExpiresAt    *time.Time  `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at" bson:"updated_at"`
+}
+
+func (a *LocalAccount) Expired(now time.Time) bool {
+	return a.ExpiresAt != nil && !now.Before(*a.ExpiresAt)
+}
+
+// Extended returns the account as a signed-in user. Groups are only kept
+// for students, as for directory users.
+func (a *LocalAccount) Extended() *UserExtended {
+	user := &UserExtended{
+		ID:       a.ID,
+		Username: a.Username,
+		Role:     a.Role,
+		Roles:    []string{a.Role},
+	}
+
+	if a.Groups != nil && a.Role == "student" {
+		user.AcademicGroup = a.Groups.AcademicGroup
+		user.Profile = a.Groups.Profile
+		user.Subgroup = a.Groups.Subgroup
+		user.EnglishGroup = a.Groups.EnglishGroup
+		user.ExtraGroups = a.Groups.ExtraGroups
+	}
+
+	return user
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "fmt"
+
+const (
+	PasswordExpired    = "password_expired"
+	AccountLocked      = "account_locked"
+	PasswordMustChange = "password_must_change"
+)
+
+// PasswordPolicyError is a sign-in rejection reported by the directory's
+// password policy (draft-behera-ldap-password-policy).
+type PasswordPolicyError struct {
+	Code    string
+	Message string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// PasswordPolicyStatus carries the warnings returned with a successful bind.
+type PasswordPolicyStatus struct {
+	GraceLoginsRemaining *int   `json:"grace_logins_remaining,omitempty"`
+	ExpiresInSeconds     *int64 `json:"expires_in_seconds,omitempty"`
+	ExpiresInDays        *int   `json:"expires_in_days,omitempty"`
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type PasswordReset struct {
+	TokenHash string     `json:"-" bson:"token_hash"`
+	UserID    string     `json:"userid" bson:"userid"`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" bson:"used_at,omitempty"`
+}
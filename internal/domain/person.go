@@ -0,0 +1,108 @@
+package domain
+
+import "strings"
+
+// PersonQuery selects people from the directory. Empty filters match
+// everyone; group filters and Role are compared case-insensitively. With
+// Fuzzy set, Query also matches transliterated and misspelt names and the
+// results are ranked by closeness instead of sorted by name.
+type PersonQuery struct {
+	Query         string
+	Fuzzy         bool
+	Role          string
+	AcademicGroup string
+	Profile       string
+	Subgroup      string
+	EnglishGroup  string
+	Offset        int
+	Limit         int
+}
+
+// Matches reports whether p passes the role and group filters of q; Query
+// is matched by the search itself.
+func (q PersonQuery) Matches(p Person) bool {
+	if q.Role != "" {
+		found := false
+		for _, role := range p.Roles {
+			if strings.EqualFold(role, q.Role) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, f := range [][2]string{
+		{q.AcademicGroup, p.AcademicGroup},
+		{q.Profile, p.Profile},
+		{q.Subgroup, p.Subgroup},
+		{q.EnglishGroup, p.EnglishGroup},
+	} {
+		if f[0] != "" && !strings.EqualFold(f[0], f[1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Person is a directory account with its role and classified groups.
+type Person struct {
+	DN            string            `json:"-"`
+	ID            string            `json:"id"`
+	Username      string            `json:"username"`
+	Mail          string            `json:"mail,omitempty"`
+	Role          string            `json:"role"`
+	Roles         []string          `json:"roles,omitempty"`
+	AcademicGroup string            `json:"academic_group,omitempty"`
+	Profile       string            `json:"profile,omitempty"`
+	Subgroup      string            `json:"subgroup,omitempty"`
+	EnglishGroup  string            `json:"english_group,omitempty"`
+	ExtraGroups   map[string]string `json:"extra_groups,omitempty"`
+}
+
+func (p *Person) Extended() UserExtended {
+	return UserExtended{
+		ID:            p.ID,
+		Username:      p.Username,
+		Role:          p.Role,
+		Roles:         p.Roles,
+		AcademicGroup: p.AcademicGroup,
+		Profile:       p.Profile,
+		Subgroup:      p.Subgroup,
+		EnglishGroup:  p.EnglishGroup,
+		ExtraGroups:   p.ExtraGroups,
+	}
+}
+
+// UserBatch answers a lookup by IDs. NotFound lists the requested IDs with no
+// directory account, in request order.
+type UserBatch struct {
+	Users    []UserExtended `json:"users"`
+	NotFound []string       `json:"not_found"`
+}
+
+// PersonPage is one page of people sorted by name. Total counts every match.
+type PersonPage struct {
+	Items  []Person `json:"items"`
+	Total  int      `json:"total"`
+	Offset int      `json:"offset"`
+	Limit  int      `json:"limit"`
+}
+
+// GroupInfo is a classified group under ou=Current.
+type GroupInfo struct {
+	DN          string `json:"dn"`
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description,omitempty"`
+}
+
+type GroupPage struct {
+	Items  []GroupInfo `json:"items"`
+	Total  int         `json:"total"`
+	Offset int         `json:"offset"`
+	Limit  int         `json:"limit"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// PersonalToken is a long-lived credential a user creates for scripts and
+// integrations. Only the hash of the token is stored.
+type PersonalToken struct {
+	ID         string     `json:"id" bson:"_id"`
+	UserID     string     `json:"-" bson:"userid"`
+	Name       string     `json:"name" bson:"name"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	TokenHash  string     `json:"-" bson:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" bson:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+func (t *PersonalToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
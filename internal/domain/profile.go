@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+// Where a profile comes from. Directory and local profiles are reloaded from
+// their source when stale.
+const (
+	ProfileSourceLDAP    = "ldap"
+	ProfileSourceLocal   = "local"
+	ProfileSourceBuiltin = "builtin"
+	ProfileSourceFixture = "fixture"
+)
+
+// UserProfile is the stored identity of a user who has signed in at least
+// once. Refresh sessions reference it by ID. Groups stays nil until the user
+// signs in through the app flow, which is the only one that reads them.
+// Flags are markers set by admins; they never block sign-in, suspensions do.
+type UserProfile struct {
+	ID          string      `json:"id" bson:"_id"`
+	Username    string      `json:"username" bson:"username"`
+	Role        string      `json:"role" bson:"role"`
+	Roles       []string    `json:"roles,omitempty" bson:"roles,omitempty"`
+	Groups      *UserGroups `json:"groups,omitempty" bson:"groups,omitempty"`
+	Source      string      `json:"source" bson:"source"`
+	Flags       []string    `json:"flags,omitempty" bson:"flags,omitempty"`
+	CreatedAt   time.Time   `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" bson:"updated_at"`
+	SyncedAt    time.Time   `json:"synced_at" bson:"synced_at"` // Last time the data was read from its source
+	LastLoginAt *time.Time  `json:"last_login_at,omitempty" bson:"last_login_at,omitempty"`
+}
+
+func (p *UserProfile) User() *User {
+	return &User{
+		ID:       p.ID,
+		Username: p.Username,
+		Role:     p.Role,
+		Roles:    p.Roles,
+	}
+}
+
+func (p *UserProfile) Extended() *UserExtended {
+	user := &UserExtended{
+		ID:       p.ID,
+		Username: p.Username,
+		Role:     p.Role,
+		Roles:    p.Roles,
+	}
+
+	if p.Groups != nil {
+		user.AcademicGroup = p.Groups.AcademicGroup
+		user.Profile = p.Groups.Profile
+		user.Subgroup = p.Groups.Subgroup
+		user.EnglishGroup = p.Groups.EnglishGroup
+		user.ExtraGroups = p.Groups.ExtraGroups
+	}
+
+	return user
+}
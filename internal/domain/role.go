@@ -0,0 +1,17 @@
+package domain
+
+type RoleDecision struct {
+	UserID         string      `json:"userid"`
+	DN             string      `json:"dn"`
+	Role           string      `json:"role"`
+	Roles          []string    `json:"roles"`
+	Matches        []RuleMatch `json:"matches"`
+	DefaultApplied bool        `json:"default_applied"`
+}
+
+type RuleMatch struct {
+	Rule    string   `json:"rule"`
+	Role    string   `json:"role"`
+	Reasons []string `json:"reasons"`
+	Stop    bool     `json:"stop"`
+}
@@ -1,6 +0,0 @@
-package domain
-
-type StudentInfo struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-}
@@ -1,24 +1,48 @@
 package domain
 
 type User struct {
-	ID       string `json:"id"`       // Student/Teacher ID
-	Username string `json:"username"` // FIO Student
-	Role     string `json:"role"`     // Teacher, Admin, People (Students)
+	ID       string   `json:"id"`              // Student/Teacher ID
+	Username string   `json:"username"`        // FIO Student
+	Role     string   `json:"role"`            // Teacher, Admin, People (Students)
+	Roles    []string `json:"roles,omitempty"` // All roles granted by the mapping rules
+
+	PasswordPolicy *PasswordPolicyStatus `json:"password_policy,omitempty"` // Set on sign-in only
+	Impersonator   string                `json:"impersonator,omitempty"`    // Admin acting as this user
+	Scopes         []string              `json:"scopes,omitempty"`          // Set for personal access tokens only
 }
 
 type UserGroups struct {
-	AcademicGroup string `json:"academic_group"`
-	Profile       string `json:"profile,omitempty"`
-	Subgroup      string `json:"subgroup,omitempty"`
-	EnglishGroup  string `json:"english_group,omitempty"`
+	AcademicGroup string            `json:"academic_group" bson:"academic_group,omitempty"`
+	Profile       string            `json:"profile,omitempty" bson:"profile,omitempty"`
+	Subgroup      string            `json:"subgroup,omitempty" bson:"subgroup,omitempty"`
+	EnglishGroup  string            `json:"english_group,omitempty" bson:"english_group,omitempty"`
+	ExtraGroups   map[string]string `json:"extra_groups,omitempty" bson:"extra_groups,omitempty"` // Configured categories beyond the four above
 }
 
 type UserExtended struct {
-	ID            string `json:"id"`
-	Username      string `json:"username"`
-	Role          string `json:"role"`
-	AcademicGroup string `json:"academic_group"`
-	Profile       string `json:"profile,omitempty"`
-	Subgroup      string `json:"subgroup,omitempty"`
-	EnglishGroup  string `json:"english_group,omitempty"`
+	ID            string            `json:"id"`
+	Username      string            `json:"username"`
+	Role          string            `json:"role"`
+	Roles         []string          `json:"roles,omitempty"`
+	AcademicGroup string            `json:"academic_group"`
+	Profile       string            `json:"profile,omitempty"`
+	Subgroup      string            `json:"subgroup,omitempty"`
+	EnglishGroup  string            `json:"english_group,omitempty"`
+	ExtraGroups   map[string]string `json:"extra_groups,omitempty"`
+
+	PasswordPolicy *PasswordPolicyStatus `json:"password_policy,omitempty"` // Set on sign-in only
+	Impersonator   string                `json:"impersonator,omitempty"`    // Admin acting as this user
+	Scopes         []string              `json:"scopes,omitempty"`          // Set for personal access tokens only
+}
+
+func (u *UserExtended) User() *User {
+	return &User{
+		ID:             u.ID,
+		Username:       u.Username,
+		Role:           u.Role,
+		Roles:          u.Roles,
+		PasswordPolicy: u.PasswordPolicy,
+		Impersonator:   u.Impersonator,
+		Scopes:         u.Scopes,
+	}
 }
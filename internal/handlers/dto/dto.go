@@ -1,32 +1,49 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+)
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required,min=6"`
+	OTP      string `json:"otp"`
 }
 
 type UserInfo struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Role     string   `json:"role"`
+	Roles    []string `json:"roles,omitempty"`
 }
 
 type AppUserInfo struct {
-	ID            string `json:"id"`
-	Username      string `json:"username"`
-	Role          string `json:"role"`
-	AcademicGroup string `json:"academic_group,omitempty"`
-	Profile       string `json:"profile,omitempty"`
-	Subgroup      string `json:"subgroup,omitempty"`
-	EnglishGroup  string `json:"english_group,omitempty"`
+	ID            string            `json:"id"`
+	Username      string            `json:"username"`
+	Role          string            `json:"role"`
+	Roles         []string          `json:"roles,omitempty"`
+	AcademicGroup string            `json:"academic_group,omitempty"`
+	Profile       string            `json:"profile,omitempty"`
+	Subgroup      string            `json:"subgroup,omitempty"`
+	EnglishGroup  string            `json:"english_group,omitempty"`
+	ExtraGroups   map[string]string `json:"extra_groups,omitempty"`
+}
+
+type PasswordPolicyInfo struct {
+	GraceLoginsRemaining *int   `json:"grace_logins_remaining,omitempty"`
+	ExpiresInSeconds     *int64 `json:"expires_in_seconds,omitempty"`
+	ExpiresInDays        *int   `json:"expires_in_days,omitempty"`
 }
 
 type AppSignInResponse struct {
-	AccessToken      string      `json:"access_token"`
-	RefreshToken     string      `json:"refresh_token"`
-	AccessExpiresIn  int         `json:"access_expires_in"`
-	RefreshExpiresIn int         `json:"refresh_expires_in"`
-	User             AppUserInfo `json:"user"`
+	AccessToken      string              `json:"access_token"`
+	RefreshToken     string              `json:"refresh_token"`
+	AccessExpiresIn  int                 `json:"access_expires_in"`
+	RefreshExpiresIn int                 `json:"refresh_expires_in"`
+	User             AppUserInfo         `json:"user"`
+	PasswordPolicy   *PasswordPolicyInfo `json:"password_policy,omitempty"`
 }
 
 type AppRefreshRequest struct {
@@ -43,14 +60,65 @@ type AppSignOutRequest struct {
 }
 
 type AppValidateResponse struct {
-	Valid bool        `json:"valid"`
-	User  AppUserInfo `json:"user,omitempty"`
+	Valid        bool        `json:"valid"`
+	User         AppUserInfo `json:"user,omitempty"`
+	Impersonator string      `json:"impersonator,omitempty"`
+	Scopes       []string    `json:"scopes,omitempty"`
 }
 
 type StudentSearchRequest struct {
 	Query string `json:"query"`
 }
 
+// RoleSearchRequest selects people by role: student, teacher, admin or any.
+type RoleSearchRequest struct {
+	Query string `json:"query"`
+	Role  string `json:"role"`
+}
+
+type PeopleSearchRequest struct {
+	Query         string `form:"q"`
+	Fuzzy         bool   `form:"fuzzy"`
+	Role          string `form:"role"`
+	AcademicGroup string `form:"academic_group"`
+	Profile       string `form:"profile"`
+	Subgroup      string `form:"subgroup"`
+	EnglishGroup  string `form:"english_group"`
+	Offset        int    `form:"offset" binding:"min=0"`
+	Limit         int    `form:"limit" binding:"min=0"`
+}
+
+// ExportRequest lists columns comma-separated, e.g. "id,name,subgroup".
+type ExportRequest struct {
+	Format   string `form:"format"`
+	Columns  string `form:"columns"`
+	Query    string `form:"q"`
+	Role     string `form:"role"`
+	Category string `form:"category"`
+	Subgroup string `form:"subgroup"`
+}
+
+type GroupListRequest struct {
+	Category string `form:"category"`
+	Offset   int    `form:"offset" binding:"min=0"`
+	Limit    int    `form:"limit" binding:"min=0"`
+}
+
+type GroupMembersRequest struct {
+	Category      string `form:"category"`
+	Role          string `form:"role"`
+	AcademicGroup string `form:"academic_group"`
+	Profile       string `form:"profile"`
+	Subgroup      string `form:"subgroup"`
+	EnglishGroup  string `form:"english_group"`
+	Offset        int    `form:"offset" binding:"min=0"`
+	Limit         int    `form:"limit" binding:"min=0"`
+}
+
+type BatchGetUsersRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1"`
+}
+
 type AppGetAccessRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -60,3 +128,70 @@ type AppGetAccessTokenResponse struct {
 	ExpiresIn   int         `json:"expires_in"`
 	User        AppUserInfo `json:"user"`
 }
+
+type RoleDryRunRequest struct {
+	UserID string `json:"userid" binding:"required"`
+}
+
+type DirectorySyncRequest struct {
+	Full bool `json:"full"`
+}
+
+type SetFlagsRequest struct {
+	Flags []string `json:"flags" binding:"required"`
+}
+
+type LocalAccountRequest struct {
+	ID        string             `json:"id"`
+	Username  string             `json:"username" binding:"required"`
+	Password  string             `json:"password"`
+	Role      string             `json:"role" binding:"required"`
+	Groups    *domain.UserGroups `json:"groups"`
+	ExpiresAt *time.Time         `json:"expires_at"`
+}
+
+type SetLocalPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+type PersonalTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type SuspendRequest struct {
+	Reason string     `json:"reason" binding:"required"`
+	Until  *time.Time `json:"until"`
+}
+
+// AccessPolicyRequest limits sign-in to the listed roles and groups; empty
+// lists allow everyone.
+type AccessPolicyRequest struct {
+	AllowRoles  []string   `json:"allow_roles"`
+	AllowGroups []string   `json:"allow_groups"`
+	Message     string     `json:"message"`
+	Until       *time.Time `json:"until"`
+}
+
+type ChangePasswordRequest struct {
+	// UserID is only read when the request carries no session.
+	UserID              string `json:"userid"`
+	OldPassword         string `json:"old_password" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+	RefreshToken        string `json:"refresh_token"`
+}
+
+type PasswordResetRequest struct {
+	UserID string `json:"userid" binding:"required"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
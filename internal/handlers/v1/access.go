@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listSuspensions(c *gin.Context) {
+	suspensions, err := h.services.AccessService.ListSuspensions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to list suspensions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suspensions": suspensions,
+	})
+}
+
+func (h *Handler) suspendUser(c *gin.Context) {
+	var req dto.SuspendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	suspension, err := h.services.AccessService.Suspend(c.Request.Context(), service.SuspendInput{
+		ActorID:  c.GetString(userIDCtx),
+		UserID:   c.Param("userid"),
+		Reason:   req.Reason,
+		Until:    req.Until,
+		ClientIP: c.ClientIP(),
+	})
+	if err != nil {
+		accessError(c, err, "failed to suspend user")
+		return
+	}
+
+	c.JSON(http.StatusOK, suspension)
+}
+
+func (h *Handler) unsuspendUser(c *gin.Context) {
+	if err := h.services.AccessService.Unsuspend(c.Request.Context(), c.GetString(userIDCtx), c.Param("userid"), c.ClientIP()); err != nil {
+		accessError(c, err, "failed to lift suspension")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) getAccessPolicy(c *gin.Context) {
+	policy, err := h.services.AccessService.GetPolicy(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to get access policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *Handler) setAccessPolicy(c *gin.Context) {
+	var req dto.AccessPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	policy := &domain.AccessPolicy{
+		AllowRoles:  req.AllowRoles,
+		AllowGroups: req.AllowGroups,
+		Message:     req.Message,
+		Until:       req.Until,
+	}
+	if err := h.services.AccessService.SetPolicy(c.Request.Context(), c.GetString(userIDCtx), c.ClientIP(), policy); err != nil {
+		accessError(c, err, "failed to set access policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (h *Handler) clearAccessPolicy(c *gin.Context) {
+	if err := h.services.AccessService.SetPolicy(c.Request.Context(), c.GetString(userIDCtx), c.ClientIP(), &domain.AccessPolicy{}); err != nil {
+		accessError(c, err, "failed to clear access policy")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func accessError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSuspension), errors.Is(err, service.ErrInvalidAccessPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repository.ErrSuspensionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) changePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID := c.GetString(userIDCtx)
+	if userID == "" {
+		userID = strings.ToLower(strings.TrimSpace(req.UserID))
+	}
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authorization required",
+		})
+		return
+	}
+
+	refreshToken := req.RefreshToken
+	if refreshToken == "" {
+		refreshToken, _ = c.Cookie("refresh_token")
+	}
+
+	err := h.services.PasswordService.ChangePassword(c.Request.Context(), service.ChangePasswordInput{
+		UserID:              userID,
+		OldPassword:         req.OldPassword,
+		NewPassword:         req.NewPassword,
+		RevokeOtherSessions: req.RevokeOtherSessions,
+		CurrentRefreshToken: refreshToken,
+	})
+	if err != nil {
+		var weakErr *service.WeakPasswordError
+		switch {
+		case errors.As(err, &weakErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "password does not meet requirements",
+				"violations": weakErr.Violations,
+			})
+		case errors.Is(err, domain.ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "current password is incorrect",
+			})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "password change was not completed",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password changed",
+	})
+}
@@ -0,0 +1,170 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) roleDryRun(c *gin.Context) {
+	var req dto.RoleDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	decision, err := h.services.RoleService.DryRun(c.Request.Context(), req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+func (h *Handler) directorySyncStatus(c *gin.Context) {
+	status, err := h.services.DirectorySyncService.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to get sync status",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) triggerDirectorySync(c *gin.Context) {
+	var req dto.DirectorySyncRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid request body",
+			})
+			return
+		}
+	}
+
+	if err := h.services.DirectorySyncService.Trigger(req.Full); err != nil {
+		if errors.Is(err, service.ErrSyncRunning) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "directory sync started",
+	})
+}
+
+func (h *Handler) getUserProfile(c *gin.Context) {
+	profile, err := h.services.ProfileService.Get(c.Request.Context(), c.Param("userid"))
+	if err != nil {
+		if errors.Is(err, repository.ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to get user profile",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) setUserFlags(c *gin.Context) {
+	var req dto.SetFlagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	if err := h.services.ProfileService.SetFlags(c.Request.Context(), c.Param("userid"), req.Flags); err != nil {
+		if errors.Is(err, repository.ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update user profile",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) impersonate(c *gin.Context) {
+	var req dto.ImpersonateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid request body",
+			})
+			return
+		}
+	}
+
+	result, err := h.services.ImpersonationService.Impersonate(c.Request.Context(), service.ImpersonateInput{
+		ActorID:  c.GetString(userIDCtx),
+		TargetID: c.Param("userid"),
+		Reason:   req.Reason,
+		ClientIP: c.ClientIP(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, service.ErrImpersonationForbidden):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to impersonate user",
+			})
+		}
+		return
+	}
+
+	// No cookies: the token is for the admin's debugging client, not for
+	// replacing the admin's own browser session.
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": result.AccessToken,
+		"expires_in":   int(result.ExpiresIn.Seconds()),
+		"user":         result.User,
+		"impersonator": result.User.Impersonator,
+	})
+}
+
+func (h *Handler) searchCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.services.SearchCacheService.Stats())
+}
+
+func (h *Handler) flushSearchCache(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"flushed": h.services.SearchCacheService.Flush(),
+	})
+}
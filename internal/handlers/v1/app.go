@@ -23,12 +23,18 @@ func (h *Handler) appSignIn(c *gin.Context) {
 	tokens, user, err := h.services.AppUserService.SignIn(c.Request.Context(), service.SignInInput{
 		UserID:   loginReq.Username,
 		Password: loginReq.Password,
+		OTP:      loginReq.OTP,
+		ClientIP: c.ClientIP(),
 	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		response := gin.H{
 			"error":   "authentication failed",
 			"details": err.Error(),
-		})
+		}
+		if code := signInErrorCode(err); code != "" {
+			response["code"] = code
+		}
+		c.JSON(http.StatusUnauthorized, response)
 		return
 	}
 
@@ -56,11 +62,14 @@ func (h *Handler) appSignIn(c *gin.Context) {
 			ID:            user.ID,
 			Username:      user.Username,
 			Role:          user.Role,
+			Roles:         user.Roles,
 			AcademicGroup: user.AcademicGroup,
 			Profile:       user.Profile,
 			Subgroup:      user.Subgroup,
 			EnglishGroup:  user.EnglishGroup,
+			ExtraGroups:   user.ExtraGroups,
 		},
+		PasswordPolicy: passwordPolicyInfo(user.PasswordPolicy),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -123,10 +132,12 @@ func (h *Handler) appGetAccess(c *gin.Context) {
 			ID:            user.ID,
 			Username:      user.Username,
 			Role:          user.Role,
+			Roles:         user.Roles,
 			AcademicGroup: user.AcademicGroup,
 			Profile:       user.Profile,
 			Subgroup:      user.Subgroup,
 			EnglishGroup:  user.EnglishGroup,
+			ExtraGroups:   user.ExtraGroups,
 		},
 	}
 
@@ -207,11 +218,15 @@ func (h *Handler) appValidateToken(c *gin.Context) {
 			ID:            user.ID,
 			Username:      user.Username,
 			Role:          user.Role,
+			Roles:         user.Roles,
 			AcademicGroup: user.AcademicGroup,
 			Profile:       user.Profile,
 			Subgroup:      user.Subgroup,
 			EnglishGroup:  user.EnglishGroup,
+			ExtraGroups:   user.ExtraGroups,
 		},
+		Impersonator: user.Impersonator,
+		Scopes:       user.Scopes,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) searchPeople(c *gin.Context) {
+	var req dto.PeopleSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid query parameters",
+		})
+		return
+	}
+
+	page, err := h.services.DirectoryService.SearchPeople(c.Request.Context(), domain.PersonQuery{
+		Query:         req.Query,
+		Fuzzy:         req.Fuzzy,
+		Role:          req.Role,
+		AcademicGroup: req.AcademicGroup,
+		Profile:       req.Profile,
+		Subgroup:      req.Subgroup,
+		EnglishGroup:  req.EnglishGroup,
+		Offset:        req.Offset,
+		Limit:         req.Limit,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaging) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) listGroups(c *gin.Context) {
+	var req dto.GroupListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid query parameters",
+		})
+		return
+	}
+
+	page, err := h.services.DirectoryService.ListGroups(c.Request.Context(), req.Category, req.Offset, req.Limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaging) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) groupMembers(c *gin.Context) {
+	var req dto.GroupMembersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid query parameters",
+		})
+		return
+	}
+
+	page, err := h.services.DirectoryService.GroupMembers(c.Request.Context(), c.Param("name"), req.Category, domain.PersonQuery{
+		Role:          req.Role,
+		AcademicGroup: req.AcademicGroup,
+		Profile:       req.Profile,
+		Subgroup:      req.Subgroup,
+		EnglishGroup:  req.EnglishGroup,
+		Offset:        req.Offset,
+		Limit:         req.Limit,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaging) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, repository.ErrGroupNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) batchGetUsers(c *gin.Context) {
+	var req dto.BatchGetUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	batch, err := h.services.DirectoryService.BatchGet(c.Request.Context(), req.IDs)
+	if err != nil {
+		if errors.Is(err, service.ErrBatchTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
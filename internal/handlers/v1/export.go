@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) exportPeople(c *gin.Context) {
+	h.export(c, "")
+}
+
+func (h *Handler) exportGroupMembers(c *gin.Context) {
+	h.export(c, c.Param("name"))
+}
+
+func (h *Handler) export(c *gin.Context, group string) {
+	var req dto.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid query parameters",
+		})
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = service.ExportFormatCSV
+	}
+	var columns []string
+	if req.Columns != "" {
+		columns = strings.Split(req.Columns, ",")
+	}
+
+	export, err := h.services.ExportService.Prepare(c.Request.Context(), service.ExportRequest{
+		Format:   strings.ToLower(req.Format),
+		Columns:  columns,
+		Role:     req.Role,
+		Query:    req.Query,
+		Group:    group,
+		Category: req.Category,
+		Subgroup: req.Subgroup,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUnknownExportFormat),
+			errors.Is(err, service.ErrUnknownExportColumn),
+			errors.Is(err, service.ErrUnknownRole):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		case errors.Is(err, repository.ErrGroupNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType())
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": export.FileName(),
+	}))
+	c.Status(http.StatusOK)
+
+	// The status is already sent, so a failure can only cut the download.
+	if err := export.Write(c.Writer); err != nil {
+		logger.Error(fmt.Errorf("export of %s failed: %w", export.FileName(), err))
+	}
+}
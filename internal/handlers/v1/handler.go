@@ -48,10 +48,73 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 			app.POST("/access", h.appGetAccess)
 		}
 
-		search := v1.Group("/search")
+		search := v1.Group("/search", h.internalAuth)
 		{
 			search.POST("/students", h.searchStudents)
 			search.POST("/teachers", h.searchTeachers)
+			search.POST("/people", h.searchPeopleByRole)
+		}
+
+		directory := v1.Group("/directory", h.internalAuth)
+		{
+			directory.GET("/people", h.searchPeople)
+			directory.GET("/groups", h.listGroups)
+			directory.GET("/groups/:name/members", h.groupMembers)
+			// gin reads the colon of a custom method as a parameter, so
+			// exactPath rejects anything but the literal users:batchGet.
+			directory.POST("/users:batchGet", exactPath, h.batchGetUsers)
+		}
+
+		export := v1.Group("/export", h.userIdentity, h.requireScope("export"), h.requireRole("teacher", "admin"))
+		{
+			export.GET("/people", h.exportPeople)
+			export.GET("/groups/:name/members", h.exportGroupMembers)
+		}
+
+		reset := v1.Group("/password-reset")
+		{
+			reset.POST("/request", h.requestPasswordReset)
+			reset.POST("/confirm", h.confirmPasswordReset)
+		}
+
+		// A password that must be changed after a reset blocks sign-in, so
+		// the current password alone is enough to change it.
+		v1.POST("/account/password", h.optionalIdentity, h.requireSession, h.changePassword)
+
+		account := v1.Group("/account", h.userIdentity, h.requireSession)
+		{
+			account.GET("/tokens", h.listPersonalTokens)
+			account.POST("/tokens", h.createPersonalToken)
+			account.DELETE("/tokens/:id", h.revokePersonalToken)
+		}
+
+		health := v1.Group("/health", h.internalAuth)
+		{
+			health.GET("/ldap", h.ldapHealth)
+		}
+
+		admin := v1.Group("/admin", h.userIdentity, h.requireSession, h.requireRole("admin"))
+		{
+			admin.POST("/roles/dry-run", h.roleDryRun)
+			admin.GET("/directory/sync", h.directorySyncStatus)
+			admin.POST("/directory/sync", h.triggerDirectorySync)
+			admin.GET("/users/:userid", h.getUserProfile)
+			admin.PUT("/users/:userid/flags", h.setUserFlags)
+			admin.POST("/users/:userid/impersonate", h.impersonate)
+			admin.GET("/suspensions", h.listSuspensions)
+			admin.PUT("/suspensions/:userid", h.suspendUser)
+			admin.DELETE("/suspensions/:userid", h.unsuspendUser)
+			admin.GET("/access-policy", h.getAccessPolicy)
+			admin.PUT("/access-policy", h.setAccessPolicy)
+			admin.DELETE("/access-policy", h.clearAccessPolicy)
+			admin.GET("/search/cache", h.searchCacheStats)
+			admin.DELETE("/search/cache", h.flushSearchCache)
+			admin.GET("/local-users", h.listLocalAccounts)
+			admin.POST("/local-users", h.createLocalAccount)
+			admin.GET("/local-users/:userid", h.getLocalAccount)
+			admin.PUT("/local-users/:userid", h.updateLocalAccount)
+			admin.DELETE("/local-users/:userid", h.deleteLocalAccount)
+			admin.PUT("/local-users/:userid/password", h.setLocalAccountPassword)
 		}
 	}
 }
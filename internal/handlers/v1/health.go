@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) ldapHealth(c *gin.Context) {
+	endpoints := h.services.HealthService.LDAPEndpoints()
+
+	healthy := 0
+	for _, ep := range endpoints {
+		if ep.State != ldappool.StateOpen {
+			healthy++
+		}
+	}
+
+	status := http.StatusOK
+	if healthy == 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"healthy":   healthy,
+		"total":     len(endpoints),
+		"endpoints": endpoints,
+	})
+}
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listLocalAccounts(c *gin.Context) {
+	accounts, err := h.services.LocalAccountService.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to list local users",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": accounts,
+	})
+}
+
+func (h *Handler) getLocalAccount(c *gin.Context) {
+	account, err := h.services.LocalAccountService.Get(c.Request.Context(), c.Param("userid"))
+	if err != nil {
+		localAccountError(c, err, "failed to get local user")
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *Handler) createLocalAccount(c *gin.Context) {
+	var req dto.LocalAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ID == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	account, err := h.services.LocalAccountService.Create(c.Request.Context(), service.LocalAccountInput{
+		ID:        req.ID,
+		Username:  req.Username,
+		Password:  req.Password,
+		Role:      req.Role,
+		Groups:    req.Groups,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		localAccountError(c, err, "failed to create local user")
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+func (h *Handler) updateLocalAccount(c *gin.Context) {
+	var req dto.LocalAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	account, err := h.services.LocalAccountService.Update(c.Request.Context(), service.LocalAccountInput{
+		ID:        c.Param("userid"),
+		Username:  req.Username,
+		Role:      req.Role,
+		Groups:    req.Groups,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		localAccountError(c, err, "failed to update local user")
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+func (h *Handler) setLocalAccountPassword(c *gin.Context) {
+	var req dto.SetLocalPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	if err := h.services.LocalAccountService.SetPassword(c.Request.Context(), c.Param("userid"), req.Password); err != nil {
+		localAccountError(c, err, "failed to set password")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) deleteLocalAccount(c *gin.Context) {
+	if err := h.services.LocalAccountService.Delete(c.Request.Context(), c.Param("userid")); err != nil {
+		localAccountError(c, err, "failed to delete local user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func localAccountError(c *gin.Context, err error, message string) {
+	var weakErr *service.WeakPasswordError
+	switch {
+	case errors.As(err, &weakErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "password does not meet requirements",
+			"violations": weakErr.Violations,
+		})
+	case errors.Is(err, service.ErrInvalidLocalAccount):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repository.ErrLocalAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, repository.ErrLocalAccountExists), errors.Is(err, service.ErrDirectoryUserExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+		})
+	}
+}
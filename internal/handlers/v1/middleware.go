@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userIDCtx    = "userID"
+	userRoleCtx  = "userRole"
+	userRolesCtx = "userRoles"
+	actorIDCtx   = "actorID"
+	scopesCtx    = "scopes"
+)
+
+func (h *Handler) internalAuth(c *gin.Context) {
+	internalToken := c.GetHeader("X-Internal-Token")
+	if internalToken == "" || internalToken != h.cfg.Tokens.InternalToken {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "unauthorized",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// exactPath lets a request through only when its path is the registered
+// route itself, for routes whose colon is literal rather than a parameter.
+func exactPath(c *gin.Context) {
+	if c.Request.URL.Path != c.FullPath() {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "unknown method",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+func (h *Handler) userIdentity(c *gin.Context) {
+	token, err := h.getFromHeader(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "authorization required",
+		})
+		return
+	}
+
+	if auth.IsPersonalToken(token) {
+		h.personalTokenIdentity(c, token)
+		return
+	}
+
+	claims, err := h.tokenManager.ValidateAccessToken(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid or expired token",
+		})
+		return
+	}
+
+	userID, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	if userID == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid token claims",
+		})
+		return
+	}
+
+	c.Set(userIDCtx, userID)
+	c.Set(userRoleCtx, role)
+	c.Set(userRolesCtx, auth.StringSliceClaim(claims, "roles"))
+	c.Set(actorIDCtx, auth.ActorClaim(claims))
+
+	c.Next()
+}
+
+// optionalIdentity runs userIdentity when a token is sent and otherwise lets
+// the request through, for endpoints that also accept credentials instead.
+func (h *Handler) optionalIdentity(c *gin.Context) {
+	if _, err := h.getFromHeader(c); err != nil && c.GetHeader("Authorization") == "" {
+		c.Next()
+		return
+	}
+
+	h.userIdentity(c)
+}
+
+// personalTokenIdentity authenticates a personal access token. The user
+// comes from the stored profile, not from claims.
+func (h *Handler) personalTokenIdentity(c *gin.Context, token string) {
+	user, err := h.services.PersonalTokenService.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid or expired token",
+		})
+		return
+	}
+
+	c.Set(userIDCtx, user.ID)
+	c.Set(userRoleCtx, user.Role)
+	c.Set(userRolesCtx, user.Roles)
+	c.Set(scopesCtx, user.Scopes)
+
+	c.Next()
+}
+
+// requireSession must run after userIdentity. It keeps personal access
+// tokens and admins acting as someone else away from account and admin
+// settings.
+func (h *Handler) requireSession(c *gin.Context) {
+	if c.GetString(actorIDCtx) != "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "not allowed while impersonating",
+		})
+		return
+	}
+
+	if _, ok := c.Get(scopesCtx); ok {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "not allowed with a personal access token",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// requireScope must run after userIdentity. Session tokens pass; personal
+// access tokens need the scope.
+func (h *Handler) requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(scopesCtx); ok && !slices.Contains(c.GetStringSlice(scopesCtx), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "token is missing scope " + scope,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireRole must run after userIdentity. A user passes when the primary
+// role or any of the additional roles is allowed.
+func (h *Handler) requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := append([]string{c.GetString(userRoleCtx)}, c.GetStringSlice(userRolesCtx)...)
+
+		for _, allowed := range roles {
+			for _, role := range granted {
+				if strings.EqualFold(role, allowed) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "forbidden",
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserIdentityRejectsRefreshTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWT: config.JWTConfig{AccessTokenTTL: "60m", RefreshTokenTTL: "720h", SigningKey: "test-key"}}
+	tm := auth.NewManager(cfg)
+	h := NewHandler(nil, *tm, cfg)
+
+	router := gin.New()
+	router.POST("/account/password", h.userIdentity, h.requireSession, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	access, err := tm.NewAccessToken(auth.AccessTokenClaims{UserID: "i24s0001", Username: "Иванов Иван", Role: "student"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refresh, err := tm.NewRefreshToken("i24s0001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{name: "access token", token: access, want: http.StatusNoContent},
+		{name: "refresh token", token: refresh, want: http.StatusUnauthorized},
+		{name: "garbage", token: "not-a-token", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/account/password", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestOptionalIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWT: config.JWTConfig{AccessTokenTTL: "60m", RefreshTokenTTL: "720h", SigningKey: "test-key"}}
+	tm := auth.NewManager(cfg)
+	h := NewHandler(nil, *tm, cfg)
+
+	router := gin.New()
+	router.POST("/account/password", h.optionalIdentity, h.requireSession, func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(userIDCtx))
+	})
+
+	access, err := tm.NewAccessToken(auth.AccessTokenClaims{UserID: "i24s0001", Username: "Иванов Иван", Role: "student"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refresh, err := tm.NewRefreshToken("i24s0001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		want     int
+		wantUser string
+	}{
+		{name: "no token", want: http.StatusOK},
+		{name: "access token", header: "Bearer " + access, want: http.StatusOK, wantUser: "i24s0001"},
+		{name: "refresh token", header: "Bearer " + refresh, want: http.StatusUnauthorized},
+		{name: "malformed header", header: "Basic abc", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/account/password", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, rec.Code)
+			}
+			if tt.want == http.StatusOK && rec.Body.String() != tt.wantUser {
+				t.Errorf("expected user %q, got %q", tt.wantUser, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestExactPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/directory/users:batchGet", exactPath, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/directory/users:batchGet", want: http.StatusOK},
+		{path: "/directory/users:batchDelete", want: http.StatusNotFound},
+		{path: "/directory/usersX", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
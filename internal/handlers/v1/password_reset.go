@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) requestPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	if err := h.services.PasswordResetService.RequestReset(c.Request.Context(), req.UserID, c.ClientIP()); err != nil {
+		if errors.Is(err, service.ErrResetThrottled) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "if the account exists, reset instructions have been sent",
+	})
+}
+
+func (h *Handler) confirmPasswordReset(c *gin.Context) {
+	var req dto.PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	err := h.services.PasswordResetService.ConfirmReset(c.Request.Context(), req.Token, req.NewPassword)
+	if err != nil {
+		var weakErr *service.WeakPasswordError
+		if errors.As(err, &weakErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "password does not meet requirements",
+				"violations": weakErr.Violations,
+			})
+			return
+		}
+
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password has been reset",
+	})
+}
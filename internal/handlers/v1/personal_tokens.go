@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listPersonalTokens(c *gin.Context) {
+	tokens, err := h.services.PersonalTokenService.List(c.Request.Context(), c.GetString(userIDCtx))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to list tokens",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": tokens,
+	})
+}
+
+func (h *Handler) createPersonalToken(c *gin.Context) {
+	var req dto.PersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	token, record, err := h.services.PersonalTokenService.Create(c.Request.Context(), service.PersonalTokenInput{
+		UserID:    c.GetString(userIDCtx),
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPersonalToken) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to create token",
+		})
+		return
+	}
+
+	// The token is shown once; only its hash is stored.
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   token,
+		"details": record,
+	})
+}
+
+func (h *Handler) revokePersonalToken(c *gin.Context) {
+	if err := h.services.PersonalTokenService.Revoke(c.Request.Context(), c.GetString(userIDCtx), c.Param("id")); err != nil {
+		if errors.Is(err, repository.ErrPersonalTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to revoke token",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
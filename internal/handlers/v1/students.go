@@ -1,21 +1,15 @@
 package v1
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
+	service "github.com/anton1ks96/college-auth-svc/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
 func (h *Handler) searchStudents(c *gin.Context) {
-	internalToken := c.GetHeader("X-Internal-Token")
-	if internalToken == "" || internalToken != h.cfg.Tokens.InternalToken {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "unauthorized",
-		})
-		return
-	}
-
 	var req dto.StudentSearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -43,14 +37,6 @@ func (h *Handler) searchStudents(c *gin.Context) {
 }
 
 func (h *Handler) searchTeachers(c *gin.Context) {
-	internalToken := c.GetHeader("X-Internal-Token")
-	if internalToken == "" || internalToken != h.cfg.Tokens.InternalToken {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "unauthorized",
-		})
-		return
-	}
-
 	var req dto.StudentSearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -71,8 +57,44 @@ func (h *Handler) searchTeachers(c *gin.Context) {
 		return
 	}
 
+	// "students" is kept for clients written against the old response.
 	c.JSON(http.StatusOK, gin.H{
+		"teachers": teachers,
 		"students": teachers,
 		"total":    len(teachers),
 	})
 }
+
+func (h *Handler) searchPeopleByRole(c *gin.Context) {
+	var req dto.RoleSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	people, err := h.services.StudentService.SearchPeople(
+		c.Request.Context(),
+		req.Role,
+		req.Query,
+	)
+
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownRole) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"people": people,
+		"total":  len(people),
+	})
+}
@@ -1,11 +1,13 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
 	"github.com/anton1ks96/college-auth-svc/internal/handlers/dto"
 	service "github.com/anton1ks96/college-auth-svc/internal/services"
 	"github.com/gin-gonic/gin"
@@ -23,11 +25,17 @@ func (h *Handler) signIn(c *gin.Context) {
 	tokens, user, err := h.services.UserService.SignIn(c.Request.Context(), service.SignInInput{
 		UserID:   loginReq.Username,
 		Password: loginReq.Password,
+		OTP:      loginReq.OTP,
+		ClientIP: c.ClientIP(),
 	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		response := gin.H{
 			"error": err.Error(),
-		})
+		}
+		if code := signInErrorCode(err); code != "" {
+			response["code"] = code
+		}
+		c.JSON(http.StatusUnauthorized, response)
 		return
 	}
 
@@ -67,7 +75,7 @@ func (h *Handler) signIn(c *gin.Context) {
 		true,
 	)
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"access_token":  tokens.AccessToken,
 		"refresh_token": tokens.RefreshToken,
 		"expires_in":    int(accessTTL.Seconds()),
@@ -75,8 +83,14 @@ func (h *Handler) signIn(c *gin.Context) {
 			"id":       user.ID,
 			"username": user.Username,
 			"role":     user.Role,
+			"roles":    user.Roles,
 		},
-	})
+	}
+	if policy := passwordPolicyInfo(user.PasswordPolicy); policy != nil {
+		response["password_policy"] = policy
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) signOut(c *gin.Context) {
@@ -161,13 +175,22 @@ func (h *Handler) validateToken(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
 			"role":     user.Role,
+			"roles":    user.Roles,
 		},
-	})
+	}
+	if user.Impersonator != "" {
+		response["impersonator"] = user.Impersonator
+	}
+	if user.Scopes != nil {
+		response["scopes"] = user.Scopes
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 func (h *Handler) getFromHeader(c *gin.Context) (string, error) {
@@ -190,3 +213,31 @@ func (h *Handler) getFromHeader(c *gin.Context) (string, error) {
 
 	return "", fmt.Errorf("authorization header is missing")
 }
+
+// signInErrorCode returns a machine-readable reason for a refused sign-in.
+func signInErrorCode(err error) string {
+	var ppErr *domain.PasswordPolicyError
+	switch {
+	case errors.As(err, &ppErr):
+		return ppErr.Code
+	case errors.Is(err, domain.ErrAccountSuspended):
+		return "account_suspended"
+	case errors.Is(err, domain.ErrAccessRestricted):
+		return "access_restricted"
+	case errors.Is(err, domain.ErrAccountInactive):
+		return "account_inactive"
+	}
+	return ""
+}
+
+func passwordPolicyInfo(status *domain.PasswordPolicyStatus) *dto.PasswordPolicyInfo {
+	if status == nil {
+		return nil
+	}
+
+	return &dto.PasswordPolicyInfo{
+		GraceLoginsRemaining: status.GraceLoginsRemaining,
+		ExpiresInSeconds:     status.ExpiresInSeconds,
+		ExpiresInDays:        status.ExpiresInDays,
+	}
+}
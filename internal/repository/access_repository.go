@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	accessPolicyID  = "access_policy"
+	otpStepIDPrefix = "otp_step:"
+)
+
+var (
+	ErrSuspensionNotFound = errors.New("suspension not found")
+	ErrOTPStepUsed        = errors.New("one-time code step already used")
+)
+
+type AccessRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewAccessRepository(cfg *config.Config, db *mongo.Client) *AccessRepository {
+	return &AccessRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// Suspend stores a suspension, replacing an earlier one for the same user.
+func (r *AccessRepository) Suspend(ctx context.Context, suspension *domain.Suspension) error {
+	_, err := r.suspensions().ReplaceOne(ctx, bson.M{"_id": suspension.UserID}, suspension, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to suspend user %s: %w", suspension.UserID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *AccessRepository) GetSuspension(ctx context.Context, userID string) (*domain.Suspension, error) {
+	var suspension domain.Suspension
+	if err := r.suspensions().FindOne(ctx, bson.M{"_id": userID}).Decode(&suspension); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSuspensionNotFound
+		}
+		return nil, fmt.Errorf("failed to get suspension: %w", err)
+	}
+
+	return &suspension, nil
+}
+
+func (r *AccessRepository) ListSuspensions(ctx context.Context) ([]domain.Suspension, error) {
+	cursor, err := r.suspensions().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list suspensions: %w", err)
+	}
+
+	suspensions := []domain.Suspension{}
+	if err := cursor.All(ctx, &suspensions); err != nil {
+		return nil, fmt.Errorf("failed to decode suspensions: %w", err)
+	}
+
+	return suspensions, nil
+}
+
+func (r *AccessRepository) Unsuspend(ctx context.Context, userID string) error {
+	result, err := r.suspensions().DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to lift suspension of %s: %w", userID, err))
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSuspensionNotFound
+	}
+
+	return nil
+}
+
+// GetPolicy returns the stored access policy, or an empty one that allows
+// everyone.
+func (r *AccessRepository) GetPolicy(ctx context.Context) (*domain.AccessPolicy, error) {
+	var policy domain.AccessPolicy
+	if err := r.settings().FindOne(ctx, bson.M{"_id": accessPolicyID}).Decode(&policy); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &domain.AccessPolicy{}, nil
+		}
+		return nil, fmt.Errorf("failed to get access policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+func (r *AccessRepository) SavePolicy(ctx context.Context, policy *domain.AccessPolicy) error {
+	_, err := r.settings().ReplaceOne(ctx, bson.M{"_id": accessPolicyID}, policy, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to save access policy: %w", err))
+		return err
+	}
+
+	return nil
+}
+
+// ClaimOTPStep records step as the newest one-time code step of userID. A
+// step that is not newer than the stored one makes the upsert collide on _id
+// and is reported as ErrOTPStepUsed, so a code works once across replicas.
+func (r *AccessRepository) ClaimOTPStep(ctx context.Context, userID string, step int64) error {
+	filter := bson.M{"_id": otpStepIDPrefix + userID, "step": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"step": step}}
+
+	_, err := r.settings().UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrOTPStepUsed
+	}
+	if err != nil {
+		return fmt.Errorf("failed to claim one-time code step: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AccessRepository) suspensions() *mongo.Collection {
+	return r.db.Database(r.cfg.Mongo.DBName).Collection(r.cfg.Mongo.SuspensionsCollName)
+}
+
+func (r *AccessRepository) settings() *mongo.Collection {
+	return r.db.Database(r.cfg.Mongo.DBName).Collection(r.cfg.Mongo.SettingsCollName)
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type AuditRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewAuditRepository(cfg *config.Config, db *mongo.Client) *AuditRepository {
+	return &AuditRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+func (a *AuditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	coll := a.db.Database(a.cfg.Mongo.DBName).Collection(a.cfg.Mongo.AuditCollName)
+
+	if _, err := coll.InsertOne(ctx, event); err != nil {
+		logger.Error(fmt.Errorf("failed to record audit event %s by %s: %w", event.Action, event.Actor, err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,688 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	defaultDirectoryPageSize = 500
+	// lookupChunkSize bounds the number of uids in one OR filter.
+	lookupChunkSize = 100
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+
+var directoryBranches = []string{domain.DirectoryBranchPeople, domain.DirectoryBranchTeachers}
+
+// DirectoryRepository searches the directory through the service account, as
+// opposed to UserRepository which works on behalf of a single signed-in user.
+type DirectoryRepository struct {
+	cfg      *config.Config
+	pool     *ldappool.Pool
+	pageSize uint32
+	roles    *RoleMapper
+	groups   *GroupClassifier
+
+	mu       sync.Mutex
+	index    *groupIndex
+	indexTTL time.Duration
+}
+
+func NewDirectoryRepository(cfg *config.Config, pool *ldappool.Pool) *DirectoryRepository {
+	pageSize := cfg.Sync.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultDirectoryPageSize
+	}
+
+	roles, err := NewRoleMapper(cfg.Roles)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid role mapping rules: %w", err))
+	}
+
+	groups, err := NewGroupClassifier(cfg.Groups)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid group classification: %w", err))
+	}
+
+	indexTTL := cfg.Groups.Nested.CacheTTL
+	if indexTTL <= 0 {
+		indexTTL = defaultNestedCacheTTL
+	}
+
+	return &DirectoryRepository{
+		cfg:      cfg,
+		pool:     pool,
+		pageSize: uint32(pageSize),
+		roles:    roles,
+		groups:   groups,
+		indexTTL: indexTTL,
+	}
+}
+
+// ExportUsers returns the accounts of ou=People and ou=Teachers. A non-empty
+// since limits the result to entries with modifyTimestamp >= since.
+func (d *DirectoryRepository) ExportUsers(ctx context.Context, since string) ([]domain.DirectoryUser, error) {
+	var users []domain.DirectoryUser
+
+	for _, branch := range directoryBranches {
+		entries, err := d.export(ctx, branchBaseDN(branch), "(objectClass=person)(uid=*)", since,
+			[]string{"uid", "cn", "mail", "modifyTimestamp"})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			users = append(users, domain.DirectoryUser{
+				UserID:          entry.GetAttributeValue("uid"),
+				DN:              entry.DN,
+				Username:        entry.GetAttributeValue("cn"),
+				Mail:            entry.GetAttributeValue("mail"),
+				Branch:          branch,
+				ModifyTimestamp: entry.GetAttributeValue("modifyTimestamp"),
+			})
+		}
+	}
+
+	return users, nil
+}
+
+// ExportGroups returns the groups under ou=Current with their direct members.
+func (d *DirectoryRepository) ExportGroups(ctx context.Context, since string) ([]domain.DirectoryGroup, error) {
+	entries, err := d.export(ctx, groupsBaseDN, groupObjectClasses, since,
+		[]string{"cn", "description", "member", "memberUid", "modifyTimestamp"})
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]domain.DirectoryGroup, 0, len(entries))
+	for _, entry := range entries {
+		members := entry.GetAttributeValues("member")
+		for i, m := range members {
+			members[i] = strings.ToLower(m)
+		}
+
+		groups = append(groups, domain.DirectoryGroup{
+			DN:              entry.DN,
+			Name:            entry.GetAttributeValue("cn"),
+			Description:     entry.GetAttributeValue("description"),
+			Members:         members,
+			MemberUIDs:      entry.GetAttributeValues("memberUid"),
+			ModifyTimestamp: entry.GetAttributeValue("modifyTimestamp"),
+		})
+	}
+
+	return groups, nil
+}
+
+// SearchPeople returns one page of the people in ou=People and ou=Teachers
+// whose uid or cn contains q.Query, sorted by name. The query, group and
+// role filters are sent to LDAP, so only candidates are read; every
+// candidate is then classified and checked exactly. Group filters match
+// members of nested groups too. Fuzzy is not supported here; DirectoryService
+// runs fuzzy searches over the mirror.
+func (d *DirectoryRepository) SearchPeople(ctx context.Context, q domain.PersonQuery) (*domain.PersonPage, error) {
+	index, err := d.groupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, ok := d.groupFilter(index, q)
+	if !ok {
+		return emptyPage(q.Offset, q.Limit), nil
+	}
+
+	if q.Query != "" {
+		filter += fmt.Sprintf("(|(uid=*%s*)(cn=*%s*))", ldap.EscapeFilter(q.Query), ldap.EscapeFilter(q.Query))
+	}
+
+	return d.findPeople(ctx, index, filter, q)
+}
+
+// SuggestPeople is the bounded search behind autocompletion: it reads at most
+// q.Limit people per branch whose uid or cn contains q.Query, using the
+// server-side size limit instead of paging, and returns up to q.Limit of them
+// sorted by name. Group filters and paging fields are ignored.
+func (d *DirectoryRepository) SuggestPeople(ctx context.Context, q domain.PersonQuery) ([]domain.Person, error) {
+	index, err := d.groupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := fmt.Sprintf("(objectClass=person)(uid=*)(|(uid=*%s*)(cn=*%s*))", ldap.EscapeFilter(q.Query), ldap.EscapeFilter(q.Query))
+	attributes := append([]string{"uid", "cn", "mail", "memberOf"}, d.roles.Attributes()...)
+
+	var people []domain.Person
+	for _, branch := range directoryBranches {
+		branchFilter := filter
+		if q.Role != "" {
+			roleFilter, ok := d.roleFilter(index, q.Role, branch)
+			if !ok {
+				continue
+			}
+			branchFilter += roleFilter
+		}
+
+		entries, err := d.searchLimited(ctx, branchBaseDN(branch), branchFilter, attributes, q.Limit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			p := d.person(entry, index)
+			if (domain.PersonQuery{Role: q.Role}).Matches(p) {
+				people = append(people, p)
+			}
+		}
+	}
+
+	sortPeople(people)
+	if q.Limit > 0 && len(people) > q.Limit {
+		people = people[:q.Limit]
+	}
+
+	return people, nil
+}
+
+// ListGroups returns the classified groups under ou=Current, optionally only
+// those of one category, sorted by category and name.
+func (d *DirectoryRepository) ListGroups(ctx context.Context, category string, offset, limit int) (*domain.GroupPage, error) {
+	index, err := d.groupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []domain.GroupInfo
+	for _, entry := range index.entries {
+		cn := entry.GetAttributeValue("cn")
+		description := entry.GetAttributeValue("description")
+
+		c := d.groups.match(cn, description, false)
+		if c == "" || (category != "" && c != category) {
+			continue
+		}
+
+		groups = append(groups, domain.GroupInfo{
+			DN:          entry.DN,
+			Name:        cn,
+			Category:    c,
+			Description: description,
+		})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Category != groups[j].Category {
+			return groups[i].Category < groups[j].Category
+		}
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].DN < groups[j].DN
+	})
+
+	page := &domain.GroupPage{
+		Items:  []domain.GroupInfo{},
+		Total:  len(groups),
+		Offset: offset,
+		Limit:  limit,
+	}
+	if start, end, ok := pageBounds(len(groups), offset, limit); ok {
+		page.Items = groups[start:end]
+	}
+
+	return page, nil
+}
+
+// GroupMembers returns one page of the people in every classified group named
+// name (of the given category, if set), including members of nested groups.
+// The remaining fields of q narrow the roster, e.g. a subgroup of an
+// academic group.
+func (d *DirectoryRepository) GroupMembers(ctx context.Context, name, category string, q domain.PersonQuery) (*domain.PersonPage, error) {
+	index, err := d.groupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roots := index.find(d.groups, name, category)
+	if len(roots) == 0 {
+		return nil, ErrGroupNotFound
+	}
+
+	filter, ok := d.groupFilter(index, q)
+	if !ok {
+		return emptyPage(q.Offset, q.Limit), nil
+	}
+
+	return d.findPeople(ctx, index, index.membersFilter(roots)+filter, q)
+}
+
+// GetPeople looks up people by uid with one OR filter per chunk of IDs and
+// returns those found, in no particular order.
+func (d *DirectoryRepository) GetPeople(ctx context.Context, ids []string) ([]domain.Person, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	index, err := d.groupIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var people []domain.Person
+	for start := 0; start < len(ids); start += lookupChunkSize {
+		end := min(start+lookupChunkSize, len(ids))
+
+		var uids strings.Builder
+		for _, id := range ids[start:end] {
+			uids.WriteString(fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(id)))
+		}
+
+		err := d.scanPeople(ctx, index, "(|"+uids.String()+")", "", func(p domain.Person) {
+			people = append(people, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return people, nil
+}
+
+// findPeople returns one page of the people matching filter and q, sorted by
+// name. Only the first offset+limit matches are kept while the result pages
+// stream in; the rest are just counted.
+func (d *DirectoryRepository) findPeople(ctx context.Context, index *groupIndex, filter string, q domain.PersonQuery) (*domain.PersonPage, error) {
+	keep := 0
+	if q.Limit > 0 {
+		keep = q.Offset + q.Limit
+	}
+
+	var kept []domain.Person
+	total := 0
+
+	err := d.scanPeople(ctx, index, filter, q.Role, func(p domain.Person) {
+		if !q.Matches(p) {
+			return
+		}
+		total++
+		kept = append(kept, p)
+		if keep > 0 && len(kept) >= 2*keep {
+			sortPeople(kept)
+			kept = kept[:keep]
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortPeople(kept)
+	page := emptyPage(q.Offset, q.Limit)
+	page.Total = total
+	if start, end, ok := pageBounds(len(kept), q.Offset, q.Limit); ok {
+		page.Items = kept[start:end]
+	}
+
+	return page, nil
+}
+
+// scanPeople searches both branches for people matching filter, narrowed to
+// the candidates for role, and passes each one to fn as the pages arrive.
+func (d *DirectoryRepository) scanPeople(ctx context.Context, index *groupIndex, filter, role string, fn func(domain.Person)) error {
+	attributes := append([]string{"uid", "cn", "mail", "memberOf"}, d.roles.Attributes()...)
+
+	for _, branch := range directoryBranches {
+		branchFilter := "(objectClass=person)(uid=*)" + filter
+		if role != "" {
+			roleFilter, ok := d.roleFilter(index, role, branch)
+			if !ok {
+				continue
+			}
+			branchFilter += roleFilter
+		}
+
+		err := d.search(ctx, branchBaseDN(branch), branchFilter, attributes, func(entry *ldap.Entry) {
+			fn(d.person(entry, index))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupFilter turns the group fields of q into membership filters. It
+// reports false when a named group does not exist, so nobody can match.
+func (d *DirectoryRepository) groupFilter(index *groupIndex, q domain.PersonQuery) (string, bool) {
+	var filter strings.Builder
+	for _, f := range [][2]string{
+		{CategoryAcademicGroup, q.AcademicGroup},
+		{CategoryProfile, q.Profile},
+		{CategorySubgroup, q.Subgroup},
+		{CategoryEnglishGroup, q.EnglishGroup},
+	} {
+		if f[1] == "" {
+			continue
+		}
+
+		roots := index.find(d.groups, f[1], f[0])
+		if len(roots) == 0 {
+			return "", false
+		}
+		filter.WriteString(index.membersFilter(roots))
+	}
+
+	return filter.String(), true
+}
+
+// roleFilter returns a filter selecting at least every person under branch
+// the rules could give role, or false when none of them can. Rules that
+// precede a match can still take the role away, so the result is only a
+// candidate set. Group rules are matched against the groups under
+// ou=Current.
+func (d *DirectoryRepository) roleFilter(index *groupIndex, role, branch string) (string, bool) {
+	if strings.EqualFold(d.roles.defaultRole, role) {
+		return "", true
+	}
+
+	var clauses []string
+	for _, r := range d.roles.rules {
+		if !strings.EqualFold(r.role, role) {
+			continue
+		}
+		if r.ou != "" && !strings.EqualFold(r.ou, branch) && containsFold(directoryBranches, r.ou) {
+			continue
+		}
+
+		clause := ""
+		if r.group != nil {
+			var groups []*ldap.Entry
+			for _, g := range index.entries {
+				if r.group.MatchString(g.DN) {
+					groups = append(groups, g)
+				}
+			}
+			if len(groups) == 0 {
+				continue
+			}
+
+			if index.nested {
+				clause += index.membersFilter(groups)
+			} else {
+				var memberOf strings.Builder
+				for _, g := range groups {
+					memberOf.WriteString(fmt.Sprintf("(memberOf=%s)", ldap.EscapeFilter(g.DN)))
+				}
+				clause += "(|" + memberOf.String() + ")"
+			}
+		}
+		if r.attribute != "" {
+			clause += fmt.Sprintf("(%s=*)", ldap.EscapeFilter(r.attribute))
+		}
+
+		if clause == "" {
+			return "", true
+		}
+		clauses = append(clauses, "(&"+clause+")")
+	}
+
+	if len(clauses) == 0 {
+		return "", false
+	}
+
+	return "(|" + strings.Join(clauses, "") + ")", true
+}
+
+// groupIndex returns the cached group index, reading it again once the
+// nested group cache TTL has passed.
+func (d *DirectoryRepository) groupIndex(ctx context.Context) (*groupIndex, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.index != nil && time.Now().Before(d.index.expiresAt) {
+		return d.index, nil
+	}
+
+	entries, err := d.export(ctx, groupsBaseDN, groupObjectClasses, "",
+		[]string{"cn", "description", "member", "memberUid"})
+	if err != nil {
+		return nil, err
+	}
+
+	d.index = newGroupIndex(entries, d.cfg.Groups.Nested.Enabled, time.Now().Add(d.indexTTL))
+	return d.index, nil
+}
+
+// person evaluates the role of an entry and classifies its groups. With
+// nesting enabled, role rules also see the groups reached through nesting,
+// as they do on sign-in.
+func (d *DirectoryRepository) person(entry *ldap.Entry, index *groupIndex) domain.Person {
+	uid := entry.GetAttributeValue("uid")
+	groups := index.groupsOf(entry.DN, uid)
+
+	subject := roleSubject(entry, entry.DN)
+	if index.nested {
+		for _, g := range groups {
+			if !containsFold(subject.MemberOf, g.DN) {
+				subject.MemberOf = append(subject.MemberOf, g.DN)
+			}
+		}
+	}
+	decision := d.roles.Evaluate(subject)
+
+	// Walk backwards so that direct groups win over inherited ones.
+	var classified domain.UserGroups
+	for i := len(groups) - 1; i >= 0; i-- {
+		cn := groups[i].GetAttributeValue("cn")
+		assignCategory(&classified, d.groups.match(cn, groups[i].GetAttributeValue("description"), false), cn)
+	}
+
+	return domain.Person{
+		DN:            entry.DN,
+		ID:            uid,
+		Username:      entry.GetAttributeValue("cn"),
+		Mail:          entry.GetAttributeValue("mail"),
+		Role:          decision.Role,
+		Roles:         decision.Roles,
+		AcademicGroup: classified.AcademicGroup,
+		Profile:       classified.Profile,
+		Subgroup:      classified.Subgroup,
+		EnglishGroup:  classified.EnglishGroup,
+		ExtraGroups:   classified.ExtraGroups,
+	}
+}
+
+// pagePeople sorts people by name and cuts out one page.
+func pagePeople(people []domain.Person, offset, limit int) *domain.PersonPage {
+	sortPeople(people)
+	return cutPage(people, offset, limit)
+}
+
+func sortPeople(people []domain.Person) {
+	sort.Slice(people, func(i, j int) bool {
+		a, b := strings.ToLower(people[i].Username), strings.ToLower(people[j].Username)
+		if a != b {
+			return a < b
+		}
+		return people[i].ID < people[j].ID
+	})
+}
+
+func cutPage(people []domain.Person, offset, limit int) *domain.PersonPage {
+	page := emptyPage(offset, limit)
+	page.Total = len(people)
+	if start, end, ok := pageBounds(len(people), offset, limit); ok {
+		page.Items = people[start:end]
+	}
+
+	return page
+}
+
+func emptyPage(offset, limit int) *domain.PersonPage {
+	return &domain.PersonPage{
+		Items:  []domain.Person{},
+		Offset: offset,
+		Limit:  limit,
+	}
+}
+
+// pageBounds returns the slice bounds of a page; limit 0 means no limit.
+func pageBounds(total, offset, limit int) (int, int, bool) {
+	if offset < 0 || offset >= total {
+		return 0, 0, false
+	}
+
+	end := total
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	return offset, end, true
+}
+
+func (d *DirectoryRepository) export(ctx context.Context, baseDN, filter, since string, attributes []string) ([]*ldap.Entry, error) {
+	if since != "" {
+		filter += fmt.Sprintf("(modifyTimestamp>=%s)", ldap.EscapeFilter(since))
+	}
+
+	var entries []*ldap.Entry
+	err := d.search(ctx, baseDN, filter, attributes, func(entry *ldap.Entry) {
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// search runs a subtree search with the paged results control and passes
+// every entry to fn as each page arrives, so callers decide what to keep.
+// A cancelled context abandons the search between pages. Once entries have
+// been handed out, a lost connection is not retried on another endpoint.
+func (d *DirectoryRepository) search(ctx context.Context, baseDN, filter string, attributes []string, fn func(*ldap.Entry)) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err := d.pool.Do(ctx, func(l *ldap.Conn) error {
+		if err := serviceBind(l, d.cfg.LDAP); err != nil {
+			logger.Error(fmt.Errorf("service bind failed for directory search of %s: %w", baseDN, err))
+			return opError("service account bind failed", err)
+		}
+
+		paging := ldap.NewControlPaging(d.pageSize)
+		searchRequest := ldap.NewSearchRequest(
+			baseDN,
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			0,
+			0,
+			false,
+			"(&"+filter+")",
+			attributes,
+			[]ldap.Control{paging},
+		)
+
+		delivered := 0
+		for {
+			sr, err := l.Search(searchRequest)
+			if err != nil {
+				logger.Error(fmt.Errorf("directory search of %s failed: %w", baseDN, err))
+				if delivered > 0 {
+					return fmt.Errorf("directory search failed after %d entries", delivered)
+				}
+				return opError("directory search failed", err)
+			}
+
+			for _, entry := range sr.Entries {
+				fn(entry)
+			}
+			delivered += len(sr.Entries)
+
+			control, ok := ldap.FindControl(sr.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+			if !ok || len(control.Cookie) == 0 {
+				return nil
+			}
+			paging.SetCookie(control.Cookie)
+
+			if ctx.Err() != nil {
+				// A page size of 0 tells the server to release the search.
+				paging.PagingSize = 0
+				if _, err := l.Search(searchRequest); err != nil {
+					logger.Warn(fmt.Sprintf("failed to abandon directory search of %s: %v", baseDN, err))
+				}
+				return ctx.Err()
+			}
+		}
+	})
+	if err != nil {
+		if errors.Is(err, ldappool.ErrUnavailable) {
+			return fmt.Errorf("LDAP connection failed: %w", err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// searchLimited runs a single subtree search capped at sizeLimit entries. A
+// search that hits the limit returns the entries the server sent.
+func (d *DirectoryRepository) searchLimited(ctx context.Context, baseDN, filter string, attributes []string, sizeLimit int) ([]*ldap.Entry, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var entries []*ldap.Entry
+	err := d.pool.Do(ctx, func(l *ldap.Conn) error {
+		if err := serviceBind(l, d.cfg.LDAP); err != nil {
+			logger.Error(fmt.Errorf("service bind failed for directory search of %s: %w", baseDN, err))
+			return opError("service account bind failed", err)
+		}
+
+		searchRequest := ldap.NewSearchRequest(
+			baseDN,
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			sizeLimit,
+			0,
+			false,
+			"(&"+filter+")",
+			attributes,
+			nil,
+		)
+
+		sr, err := l.Search(searchRequest)
+		if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			logger.Error(fmt.Errorf("directory search of %s failed: %w", baseDN, err))
+			return opError("directory search failed", err)
+		}
+		if sr != nil {
+			entries = sr.Entries
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ldappool.ErrUnavailable) {
+			return nil, fmt.Errorf("LDAP connection failed: %w", err)
+		}
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func branchBaseDN(branch string) string {
+	return fmt.Sprintf("ou=%s,dc=it-college,dc=ru", branch)
+}
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+func newTestDirectoryRepository(t *testing.T) *DirectoryRepository {
+	t.Helper()
+
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          srv.URL(),
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+		},
+		// A small page size makes every search span several pages.
+		Sync:   config.DirectorySyncConfig{PageSize: 2},
+		Groups: config.GroupsConfig{Nested: config.NestedGroupsConfig{Enabled: true}},
+	}
+
+	return NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP))
+}
+
+func TestSearchPeople(t *testing.T) {
+	repo := newTestDirectoryRepository(t)
+
+	tests := []struct {
+		name      string
+		query     domain.PersonQuery
+		wantIDs   []string
+		wantTotal int
+	}{
+		{
+			name:      "first page sorted by name",
+			query:     domain.PersonQuery{Limit: 3},
+			wantIDs:   []string{"i19s0500", "t003", "i24s0001"},
+			wantTotal: 11,
+		},
+		{
+			name:      "last page",
+			query:     domain.PersonQuery{Offset: 9, Limit: 3},
+			wantIDs:   []string{"i23s0101", "t001"},
+			wantTotal: 11,
+		},
+		{
+			name:      "offset past the end",
+			query:     domain.PersonQuery{Offset: 20, Limit: 3},
+			wantIDs:   []string{},
+			wantTotal: 11,
+		},
+		{
+			name:      "by name",
+			query:     domain.PersonQuery{Query: "петрова", Limit: 10},
+			wantIDs:   []string{"i24s0002"},
+			wantTotal: 1,
+		},
+		{
+			name:      "academic group case-insensitively",
+			query:     domain.PersonQuery{AcademicGroup: "ит24-11", Limit: 10},
+			wantIDs:   []string{"i24s0001", "i24s0777", "i24s0002"},
+			wantTotal: 3,
+		},
+		{
+			name:      "academic group and profile",
+			query:     domain.PersonQuery{AcademicGroup: "ИТ24-11", Profile: "FE", Limit: 10},
+			wantIDs:   []string{"i24s0002"},
+			wantTotal: 1,
+		},
+		{
+			name:      "english group",
+			query:     domain.PersonQuery{EnglishGroup: "B1.21", Limit: 10},
+			wantIDs:   []string{"i24s0001"},
+			wantTotal: 1,
+		},
+		{
+			name:      "posixGroup membership",
+			query:     domain.PersonQuery{AcademicGroup: "ИТ25-01", Limit: 10},
+			wantIDs:   []string{"i25s0003"},
+			wantTotal: 1,
+		},
+		{
+			name:      "academic group through a nested subgroup",
+			query:     domain.PersonQuery{AcademicGroup: "ИТ21-31", Subgroup: "Подгр2", Limit: 10},
+			wantIDs:   []string{"i21s0100"},
+			wantTotal: 1,
+		},
+		{
+			name:      "unknown group",
+			query:     domain.PersonQuery{AcademicGroup: "ИТ99-99", Limit: 10},
+			wantIDs:   []string{},
+			wantTotal: 0,
+		},
+		{
+			name:      "role",
+			query:     domain.PersonQuery{Role: "teacher", Limit: 10},
+			wantIDs:   []string{"i22s0042", "t002", "t001"},
+			wantTotal: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.SearchPeople(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for _, p := range page.Items {
+				ids = append(ids, p.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("expected %v, got %v", tt.wantIDs, ids)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("expected total %d, got %d", tt.wantTotal, page.Total)
+			}
+		})
+	}
+}
+
+func TestSearchPeopleClassifiesGroups(t *testing.T) {
+	repo := newTestDirectoryRepository(t)
+
+	page, err := repo.SearchPeople(context.Background(), domain.PersonQuery{Query: "i24s0001", Limit: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := domain.Person{
+		DN:            "uid=i24s0001,ou=People,dc=it-college,dc=ru",
+		ID:            "i24s0001",
+		Username:      "Иванов Иван Иванович",
+		Mail:          "i24s0001@it-college.ru",
+		Role:          "student",
+		Roles:         []string{"student"},
+		AcademicGroup: "ИТ24-11",
+		Profile:       "BE",
+		Subgroup:      "Подгр1",
+		EnglishGroup:  "B1.21",
+	}
+
+	if len(page.Items) != 1 || !reflect.DeepEqual(page.Items[0], want) {
+		t.Errorf("expected %+v, got %+v", want, page.Items)
+	}
+}
+
+func TestListGroups(t *testing.T) {
+	repo := newTestDirectoryRepository(t)
+
+	page, err := repo.ListGroups(context.Background(), CategorySubgroup, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, g := range page.Items {
+		names = append(names, g.Name)
+	}
+
+	if want := []string{"Подгр1", "Подгр2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	// QA is not an allowed profile; role groups and clubs are not classified.
+	page, err = repo.ListGroups(context.Background(), "", 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Total != 10 {
+		t.Errorf("expected 10 classified groups, got %d", page.Total)
+	}
+}
+
+func TestGroupMembers(t *testing.T) {
+	repo := newTestDirectoryRepository(t)
+
+	tests := []struct {
+		name     string
+		group    string
+		category string
+		query    domain.PersonQuery
+		wantIDs  []string
+	}{
+		{
+			name:    "academic group",
+			group:   "ИТ24-11",
+			wantIDs: []string{"i24s0001", "i24s0777", "i24s0002"},
+		},
+		{
+			name:    "subgroup of an academic group",
+			group:   "ИТ24-11",
+			query:   domain.PersonQuery{Subgroup: "Подгр1"},
+			wantIDs: []string{"i24s0001"},
+		},
+		{
+			name:    "through a nested subgroup",
+			group:   "ИТ21-31",
+			wantIDs: []string{"i21s0100"},
+		},
+		{
+			name:     "posixGroup",
+			group:    "ИТ25-01",
+			category: CategoryAcademicGroup,
+			wantIDs:  []string{"i25s0003"},
+		},
+		{
+			name:    "paged",
+			group:   "ИТ24-11",
+			query:   domain.PersonQuery{Offset: 1, Limit: 1},
+			wantIDs: []string{"i24s0777"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.GroupMembers(context.Background(), tt.group, tt.category, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for _, p := range page.Items {
+				ids = append(ids, p.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("expected %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+
+	if _, err := repo.GroupMembers(context.Background(), "ИТ24-11", CategoryProfile, domain.PersonQuery{}); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("expected %v, got %v", ErrGroupNotFound, err)
+	}
+	if _, err := repo.GroupMembers(context.Background(), "ИТ19-11", "", domain.PersonQuery{}); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("expected archived group to be unknown, got %v", err)
+	}
+}
+
+func TestGetPeople(t *testing.T) {
+	repo := newTestDirectoryRepository(t)
+
+	people, err := repo.GetPeople(context.Background(), []string{"t001", "i24s0002", "nobody", "i25s0003"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := map[string]domain.Person{}
+	for _, p := range people {
+		got[p.ID] = p
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 people, got %v", people)
+	}
+	if got["t001"].Role != "teacher" {
+		t.Errorf("expected role %q, got %q", "teacher", got["t001"].Role)
+	}
+	if got["i24s0002"].AcademicGroup != "ИТ24-11" {
+		t.Errorf("expected academic group %q, got %q", "ИТ24-11", got["i24s0002"].AcademicGroup)
+	}
+	if got["i25s0003"].AcademicGroup != "ИТ25-01" {
+		t.Errorf("expected academic group %q from posixGroup, got %q", "ИТ25-01", got["i25s0003"].AcademicGroup)
+	}
+}
+
+func TestSuggestPeople(t *testing.T) {
+	repo := newTestDirectoryRepository(t)
+
+	// Six students match; the size limit stops the search after two.
+	people, err := repo.SuggestPeople(context.Background(), domain.PersonQuery{Query: "i2", Role: "student", Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(people) != 2 {
+		t.Errorf("expected 2 people, got %v", people)
+	}
+
+	people, err = repo.SuggestPeople(context.Background(), domain.PersonQuery{Query: "t00", Role: "student", Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(people) != 0 {
+		t.Errorf("expected no students, got %v", people)
+	}
+}
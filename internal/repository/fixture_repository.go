@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/translit"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrFixtureUserNotFound   = errors.New("fixture user not found")
+	errFixtureExportDisabled = errors.New("directory export is not available from fixtures")
+)
+
+type fixtureFile struct {
+	Users []fixtureUser `yaml:"users"`
+}
+
+// fixtureUser is one entry of the fixtures file. Users without a password
+// only show up in searches and rosters.
+type fixtureUser struct {
+	ID            string            `yaml:"id"`
+	Username      string            `yaml:"username"`
+	Password      string            `yaml:"password"`
+	Mail          string            `yaml:"mail"`
+	Role          string            `yaml:"role"`
+	Roles         []string          `yaml:"roles"`
+	AcademicGroup string            `yaml:"academic_group"`
+	Profile       string            `yaml:"profile"`
+	Subgroup      string            `yaml:"subgroup"`
+	EnglishGroup  string            `yaml:"english_group"`
+	ExtraGroups   map[string]string `yaml:"extra_groups"`
+}
+
+// FixtureRepository serves users, searches and rosters from the test mode
+// fixtures file in place of LDAP.
+type FixtureRepository struct {
+	users        []fixtureUser
+	byID         map[string]int
+	descriptions map[string]string
+}
+
+func NewFixtureRepository(cfg *config.Config) (*FixtureRepository, error) {
+	data, err := os.ReadFile(cfg.App.Fixtures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	var file fixtureFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", cfg.App.Fixtures, err)
+	}
+
+	r := &FixtureRepository{
+		users:        file.Users,
+		byID:         make(map[string]int, len(file.Users)),
+		descriptions: make(map[string]string),
+	}
+	for i := range r.users {
+		u := &r.users[i]
+		if u.ID == "" || u.Username == "" || u.Role == "" {
+			return nil, fmt.Errorf("fixture user #%d: id, username and role are required", i+1)
+		}
+		if _, ok := r.byID[u.ID]; ok {
+			return nil, fmt.Errorf("fixture user %s is listed twice", u.ID)
+		}
+		if len(u.Roles) == 0 {
+			u.Roles = []string{u.Role}
+		}
+		r.byID[u.ID] = i
+	}
+	for _, c := range cfg.Groups.Categories {
+		r.descriptions[c.Name] = c.Description
+	}
+
+	return r, nil
+}
+
+// Authenticate returns the fixture user with the given ID and password.
+func (r *FixtureRepository) Authenticate(ctx context.Context, userID, password string) (*domain.UserExtended, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	i, ok := r.byID[userID]
+	if !ok {
+		return nil, ErrFixtureUserNotFound
+	}
+
+	u := r.users[i]
+	if u.Password == "" || subtle.ConstantTimeCompare([]byte(password), []byte(u.Password)) != 1 {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	p := u.person()
+	user := p.Extended()
+	return &user, nil
+}
+
+func (r *FixtureRepository) ExportUsers(context.Context, string) ([]domain.DirectoryUser, error) {
+	return nil, errFixtureExportDisabled
+}
+
+func (r *FixtureRepository) ExportGroups(context.Context, string) ([]domain.DirectoryGroup, error) {
+	return nil, errFixtureExportDisabled
+}
+
+// SearchPeople matches query against uids and names like the LDAP search,
+// or ranks them with the fuzzy matcher.
+func (r *FixtureRepository) SearchPeople(ctx context.Context, q domain.PersonQuery) (*domain.PersonPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	query := strings.ToLower(q.Query)
+	fuzzy := q.Fuzzy && q.Query != ""
+
+	var matched []domain.Person
+	scores := make(map[string]int)
+	for _, u := range r.users {
+		p := u.person()
+		if !q.Matches(p) {
+			continue
+		}
+
+		if fuzzy {
+			score, ok := translit.Score(q.Query, p.Username+" "+p.ID)
+			if !ok {
+				continue
+			}
+			scores[p.ID] = score
+		} else if !strings.Contains(strings.ToLower(p.ID), query) && !strings.Contains(strings.ToLower(p.Username), query) {
+			continue
+		}
+
+		matched = append(matched, p)
+	}
+
+	sortPeople(matched)
+	if fuzzy {
+		sort.SliceStable(matched, func(i, j int) bool {
+			return scores[matched[i].ID] < scores[matched[j].ID]
+		})
+	}
+
+	return cutPage(matched, q.Offset, q.Limit), nil
+}
+
+func (r *FixtureRepository) SuggestPeople(ctx context.Context, q domain.PersonQuery) ([]domain.Person, error) {
+	page, err := r.SearchPeople(ctx, domain.PersonQuery{Query: q.Query, Role: q.Role, Limit: q.Limit})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+func (r *FixtureRepository) GetPeople(ctx context.Context, ids []string) ([]domain.Person, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var people []domain.Person
+	for _, id := range ids {
+		if i, ok := r.byID[id]; ok {
+			people = append(people, r.users[i].person())
+		}
+	}
+
+	return people, nil
+}
+
+// ListGroups returns every group named by some fixture user, sorted by
+// category and name.
+func (r *FixtureRepository) ListGroups(ctx context.Context, category string, offset, limit int) (*domain.GroupPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	seen := make(map[string]bool)
+	var groups []domain.GroupInfo
+	for _, u := range r.users {
+		for c, name := range u.groups() {
+			key := c + "\x00" + name
+			if seen[key] || (category != "" && c != category) {
+				continue
+			}
+			seen[key] = true
+
+			groups = append(groups, domain.GroupInfo{
+				DN:          fmt.Sprintf("cn=%s,ou=%s,ou=fixtures", name, c),
+				Name:        name,
+				Category:    c,
+				Description: r.descriptions[c],
+			})
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Category != groups[j].Category {
+			return groups[i].Category < groups[j].Category
+		}
+		return groups[i].Name < groups[j].Name
+	})
+
+	page := &domain.GroupPage{
+		Items:  []domain.GroupInfo{},
+		Total:  len(groups),
+		Offset: offset,
+		Limit:  limit,
+	}
+	if start, end, ok := pageBounds(len(groups), offset, limit); ok {
+		page.Items = groups[start:end]
+	}
+
+	return page, nil
+}
+
+func (r *FixtureRepository) GroupMembers(ctx context.Context, name, category string, q domain.PersonQuery) (*domain.PersonPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	found := false
+	var matched []domain.Person
+	for _, u := range r.users {
+		member := false
+		for c, group := range u.groups() {
+			if strings.EqualFold(group, name) && (category == "" || c == category) {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+
+		found = true
+		if p := u.person(); q.Matches(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	if !found {
+		return nil, ErrGroupNotFound
+	}
+
+	return pagePeople(matched, q.Offset, q.Limit), nil
+}
+
+func (u fixtureUser) person() domain.Person {
+	return domain.Person{
+		ID:            u.ID,
+		Username:      u.Username,
+		Mail:          u.Mail,
+		Role:          u.Role,
+		Roles:         u.Roles,
+		AcademicGroup: u.AcademicGroup,
+		Profile:       u.Profile,
+		Subgroup:      u.Subgroup,
+		EnglishGroup:  u.EnglishGroup,
+		ExtraGroups:   u.ExtraGroups,
+	}
+}
+
+// groups returns the user's groups keyed by category.
+func (u fixtureUser) groups() map[string]string {
+	groups := make(map[string]string)
+	for category, name := range u.ExtraGroups {
+		groups[category] = name
+	}
+	for category, name := range map[string]string{
+		CategoryAcademicGroup: u.AcademicGroup,
+		CategoryProfile:       u.Profile,
+		CategorySubgroup:      u.Subgroup,
+		CategoryEnglishGroup:  u.EnglishGroup,
+	} {
+		if name != "" {
+			groups[category] = name
+		}
+	}
+	return groups
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+)
+
+func newTestFixtureRepository(t *testing.T) *FixtureRepository {
+	t.Helper()
+
+	repo, err := NewFixtureRepository(&config.Config{App: config.App{Fixtures: "../../configs/fixtures.yml"}})
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	return repo
+}
+
+func TestFixtureAuthenticate(t *testing.T) {
+	repo := newTestFixtureRepository(t)
+
+	user, err := repo.Authenticate(context.Background(), "i24s0291", "student")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.AcademicGroup != "ИТ24-11" || user.Subgroup != "Подгр1" {
+		t.Errorf("expected ИТ24-11/Подгр1, got %s/%s", user.AcademicGroup, user.Subgroup)
+	}
+
+	if _, err := repo.Authenticate(context.Background(), "i24s0291", "teacher"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+	// Users without a password cannot sign in.
+	if _, err := repo.Authenticate(context.Background(), "i24s0015", ""); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+	if _, err := repo.Authenticate(context.Background(), "nobody", "student"); !errors.Is(err, ErrFixtureUserNotFound) {
+		t.Errorf("expected %v, got %v", ErrFixtureUserNotFound, err)
+	}
+}
+
+func TestFixtureDirectory(t *testing.T) {
+	repo := newTestFixtureRepository(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		page    func() (*domain.PersonPage, error)
+		wantIDs []string
+	}{
+		{
+			name: "search by name",
+			page: func() (*domain.PersonPage, error) {
+				return repo.SearchPeople(ctx, domain.PersonQuery{Query: "Коло"})
+			},
+			wantIDs: []string{"i24s0291"},
+		},
+		{
+			name: "search by role",
+			page: func() (*domain.PersonPage, error) {
+				return repo.SearchPeople(ctx, domain.PersonQuery{Role: "teacher"})
+			},
+			wantIDs: []string{"t003", "t002", "t001"},
+		},
+		{
+			name: "fuzzy search in latin",
+			page: func() (*domain.PersonPage, error) {
+				return repo.SearchPeople(ctx, domain.PersonQuery{Query: "kolomatskiy", Fuzzy: true})
+			},
+			wantIDs: []string{"i24s0291"},
+		},
+		{
+			name: "roster of a subgroup",
+			page: func() (*domain.PersonPage, error) {
+				return repo.GroupMembers(ctx, "ИТ24-11", "", domain.PersonQuery{Subgroup: "Подгр1"})
+			},
+			wantIDs: []string{"i24s0291", "i24s0015"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := tt.page()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for _, p := range page.Items {
+				ids = append(ids, p.ID)
+			}
+
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("expected %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+
+	if _, err := repo.GroupMembers(ctx, "ИТ99-99", "", domain.PersonQuery{}); !errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("expected %v, got %v", ErrGroupNotFound, err)
+	}
+
+	groups, err := repo.ListGroups(ctx, CategoryAcademicGroup, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, g := range groups.Items {
+		names = append(names, g.Name)
+	}
+	if want := []string{"ИТ24-11", "ИТ25-01"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+}
+
+func TestFixtureRepositoryRejectsDuplicates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixtures.yml")
+	data := "users:\n  - {id: t001, username: A, role: teacher}\n  - {id: t001, username: B, role: teacher}\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFixtureRepository(&config.Config{App: config.App{Fixtures: path}}); err == nil {
+		t.Error("expected an error for a duplicate user id")
+	}
+}
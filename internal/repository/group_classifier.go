@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+)
+
+const (
+	CategoryAcademicGroup = "academic_group"
+	CategoryProfile       = "profile"
+	CategorySubgroup      = "subgroup"
+	CategoryEnglishGroup  = "english_group"
+)
+
+// defaultGroupCategories mirror the historical hard-coded classification and
+// are used when no categories are configured.
+var defaultGroupCategories = []config.GroupCategory{
+	{Name: CategoryAcademicGroup, Description: "Группа", Pattern: "^ИТ"},
+	{Name: CategoryProfile, Description: "Профиль", Allowed: []string{"BE", "FE", "PM", "CD", "GD", "SA"}},
+	{Name: CategorySubgroup, Description: "Подгруппа", Allowed: []string{"Подгр1", "Подгр2"}},
+	{Name: CategoryEnglishGroup, Description: "Английский язык подгруппа"},
+}
+
+type groupCategory struct {
+	name        string
+	description string
+	pattern     *regexp.Regexp
+	allowed     map[string]bool
+}
+
+type GroupClassifier struct {
+	categories []groupCategory
+}
+
+func NewGroupClassifier(cfg config.GroupsConfig) (*GroupClassifier, error) {
+	categories := cfg.Categories
+	if len(categories) == 0 {
+		categories = defaultGroupCategories
+	}
+
+	g := &GroupClassifier{}
+	seen := make(map[string]bool)
+
+	for _, c := range categories {
+		if c.Name == "" || c.Description == "" {
+			return nil, fmt.Errorf("group category requires name and description")
+		}
+
+		if seen[c.Name] {
+			return nil, fmt.Errorf("group category %s is defined twice", c.Name)
+		}
+		seen[c.Name] = true
+
+		category := groupCategory{
+			name:        c.Name,
+			description: strings.TrimSpace(c.Description),
+		}
+
+		if c.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + c.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("group category %s: invalid pattern: %w", c.Name, err)
+			}
+			category.pattern = re
+		}
+
+		if len(c.Allowed) > 0 {
+			category.allowed = make(map[string]bool, len(c.Allowed))
+			for _, v := range c.Allowed {
+				category.allowed[v] = true
+			}
+		}
+
+		g.categories = append(g.categories, category)
+	}
+
+	return g, nil
+}
+
+// Category returns the name of the category a group belongs to, or an empty
+// string when the group is not classified.
+func (g *GroupClassifier) Category(cn, description string) string {
+	return g.match(cn, description, true)
+}
+
+// match classifies a group; verbose logs why a group with a known
+// description was skipped, which is noise when classifying in bulk.
+func (g *GroupClassifier) match(cn, description string, verbose bool) string {
+	description = strings.TrimSpace(description)
+
+	for _, c := range g.categories {
+		if !strings.EqualFold(c.description, description) {
+			continue
+		}
+
+		if c.pattern != nil && !c.pattern.MatchString(cn) {
+			if verbose {
+				logger.Debug(fmt.Sprintf("group %s does not match pattern of category %s", cn, c.name))
+			}
+			continue
+		}
+
+		if c.allowed != nil && !c.allowed[cn] {
+			if verbose {
+				logger.Warn(fmt.Sprintf("group %s is not in the allowed values of category %s", cn, c.name))
+			}
+			continue
+		}
+
+		return c.name
+	}
+
+	return ""
+}
+
+// Apply classifies a group and stores it in the matching field.
+func (g *GroupClassifier) Apply(groups *domain.UserGroups, cn, description string) string {
+	category := g.Category(cn, description)
+	assignCategory(groups, category, cn)
+	return category
+}
+
+func assignCategory(groups *domain.UserGroups, category, cn string) {
+	switch category {
+	case "":
+	case CategoryAcademicGroup:
+		groups.AcademicGroup = cn
+	case CategoryProfile:
+		groups.Profile = cn
+	case CategorySubgroup:
+		groups.Subgroup = cn
+	case CategoryEnglishGroup:
+		groups.EnglishGroup = cn
+	default:
+		if groups.ExtraGroups == nil {
+			groups.ExtraGroups = make(map[string]string)
+		}
+		groups.ExtraGroups[category] = cn
+	}
+}
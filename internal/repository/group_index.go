@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// groupIndex holds the groups under ou=Current with their members. It is
+// read with one paged search and kept for the nested group cache TTL; people
+// are never cached but searched with filters built from it.
+type groupIndex struct {
+	entries []*ldap.Entry
+	byDN    map[string]*ldap.Entry
+	// parents maps lower-cased member DNs and "uid:"-prefixed memberUid
+	// values to the groups that list them directly.
+	parents   map[string][]*ldap.Entry
+	nested    bool
+	expiresAt time.Time
+}
+
+func newGroupIndex(entries []*ldap.Entry, nested bool, expiresAt time.Time) *groupIndex {
+	index := &groupIndex{
+		entries:   entries,
+		byDN:      make(map[string]*ldap.Entry, len(entries)),
+		parents:   make(map[string][]*ldap.Entry),
+		nested:    nested,
+		expiresAt: expiresAt,
+	}
+
+	for _, g := range entries {
+		index.byDN[strings.ToLower(g.DN)] = g
+
+		for _, member := range g.GetAttributeValues("member") {
+			key := strings.ToLower(member)
+			index.parents[key] = append(index.parents[key], g)
+		}
+		for _, uid := range g.GetAttributeValues("memberUid") {
+			index.parents["uid:"+uid] = append(index.parents["uid:"+uid], g)
+		}
+	}
+
+	return index
+}
+
+// find returns the groups named name that classify into category, or into
+// any category when category is empty.
+func (x *groupIndex) find(classifier *GroupClassifier, name, category string) []*ldap.Entry {
+	var groups []*ldap.Entry
+	for _, g := range x.entries {
+		cn := g.GetAttributeValue("cn")
+		if !strings.EqualFold(cn, name) {
+			continue
+		}
+
+		c := classifier.match(cn, g.GetAttributeValue("description"), false)
+		if c != "" && (category == "" || c == category) {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// groupsOf returns the groups a user belongs to, direct ones first and, with
+// nesting enabled, followed by the groups reached through them.
+func (x *groupIndex) groupsOf(dn, uid string) []*ldap.Entry {
+	seen := make(map[string]bool)
+	var groups []*ldap.Entry
+
+	queue := append(append([]*ldap.Entry{}, x.parents[strings.ToLower(dn)]...), x.parents["uid:"+uid]...)
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+
+		key := strings.ToLower(g.DN)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		groups = append(groups, g)
+
+		if x.nested {
+			queue = append(queue, x.parents[key]...)
+		}
+	}
+
+	return groups
+}
+
+// membersFilter returns a filter matching the members of roots and of every
+// group nested in them, by memberOf for member DNs and by uid for memberUid.
+func (x *groupIndex) membersFilter(roots []*ldap.Entry) string {
+	var b strings.Builder
+	b.WriteString("(|")
+
+	seen := make(map[string]bool)
+	queue := roots
+	for len(queue) > 0 {
+		g := queue[0]
+		queue = queue[1:]
+
+		key := strings.ToLower(g.DN)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		b.WriteString(fmt.Sprintf("(memberOf=%s)", ldap.EscapeFilter(g.DN)))
+		for _, member := range g.GetAttributeValues("member") {
+			if nested, ok := x.byDN[strings.ToLower(member)]; ok {
+				queue = append(queue, nested)
+			}
+		}
+		for _, uid := range g.GetAttributeValues("memberUid") {
+			b.WriteString(fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(uid)))
+		}
+	}
+
+	b.WriteString(")")
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	NestedStrategyAuto      = "auto"
+	NestedStrategyInChain   = "in_chain"
+	NestedStrategyTraversal = "traversal"
+)
+
+const (
+	groupsBaseDN       = "ou=Current,dc=it-college,dc=ru"
+	groupObjectClasses = "(|(objectClass=groupOfNames)(objectClass=posixGroup)(objectClass=group))"
+
+	// matchingRuleInChain is LDAP_MATCHING_RULE_IN_CHAIN, supported by Active
+	// Directory and a few other servers but not by OpenLDAP.
+	matchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+	defaultNestedMaxDepth = 5
+	defaultNestedCacheTTL = 5 * time.Minute
+	parentFilterChunk     = 50
+)
+
+type resolvedGroups struct {
+	entries   []*ldap.Entry
+	expiresAt time.Time
+}
+
+// GroupResolver finds the groups under ou=Current a user belongs to, either
+// directly or, when nesting is enabled, through groups that are members of
+// other groups.
+type GroupResolver struct {
+	nested   bool
+	strategy string
+	maxDepth int
+	ttl      time.Duration
+
+	inChainUnsupported atomic.Bool
+
+	mu    sync.Mutex
+	cache map[string]resolvedGroups
+}
+
+func NewGroupResolver(cfg config.NestedGroupsConfig) (*GroupResolver, error) {
+	r := &GroupResolver{
+		nested:   cfg.Enabled,
+		strategy: cfg.Strategy,
+		maxDepth: cfg.MaxDepth,
+		ttl:      cfg.CacheTTL,
+		cache:    make(map[string]resolvedGroups),
+	}
+
+	switch r.strategy {
+	case "":
+		r.strategy = NestedStrategyAuto
+	case NestedStrategyAuto, NestedStrategyInChain, NestedStrategyTraversal:
+	default:
+		return nil, fmt.Errorf("unknown nested group strategy %q", cfg.Strategy)
+	}
+
+	if r.maxDepth <= 0 {
+		r.maxDepth = defaultNestedMaxDepth
+	}
+	if r.ttl <= 0 {
+		r.ttl = defaultNestedCacheTTL
+	}
+
+	return r, nil
+}
+
+func (r *GroupResolver) Nested() bool {
+	return r.nested
+}
+
+// Resolve returns the group entries (cn and description) of the user. Nested
+// results are cached per user DN for the configured TTL.
+func (r *GroupResolver) Resolve(l *ldap.Conn, userDN, userID string) ([]*ldap.Entry, error) {
+	if !r.nested {
+		return r.search(l, memberFilter(userDN, userID))
+	}
+
+	key := strings.ToLower(userDN)
+	if groups, ok := r.cached(key); ok {
+		return groups, nil
+	}
+
+	groups, err := r.resolveNested(l, userDN, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store(key, groups)
+	return groups, nil
+}
+
+func (r *GroupResolver) resolveNested(l *ldap.Conn, userDN, userID string) ([]*ldap.Entry, error) {
+	if r.strategy == NestedStrategyInChain {
+		return r.search(l, inChainFilter(userDN, userID))
+	}
+
+	direct, err := r.search(l, memberFilter(userDN, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if r.strategy == NestedStrategyAuto && !r.inChainUnsupported.Load() {
+		groups, err := r.search(l, inChainFilter(userDN, userID))
+		if err != nil && ldappool.IsConnectionError(err) {
+			return nil, err
+		}
+
+		// Servers without the rule either reject the filter or evaluate it as
+		// Undefined, which silently drops the direct groups.
+		if err == nil && containsAllGroups(groups, direct) {
+			return groups, nil
+		}
+
+		r.inChainUnsupported.Store(true)
+		logger.Info("LDAP server does not support the in-chain matching rule, resolving nested groups client-side")
+	}
+
+	return r.traverse(l, direct)
+}
+
+// traverse walks up from the direct groups one level per query. Visited groups
+// are skipped, which both breaks cycles and avoids re-expanding shared parents.
+func (r *GroupResolver) traverse(l *ldap.Conn, direct []*ldap.Entry) ([]*ldap.Entry, error) {
+	seen := make(map[string]bool, len(direct))
+	groups := make([]*ldap.Entry, 0, len(direct))
+	var frontier []string
+
+	for _, g := range direct {
+		key := strings.ToLower(g.DN)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		groups = append(groups, g)
+		frontier = append(frontier, g.DN)
+	}
+
+	for depth := 1; len(frontier) > 0; depth++ {
+		var next []*ldap.Entry
+
+		for start := 0; start < len(frontier); start += parentFilterChunk {
+			end := min(start+parentFilterChunk, len(frontier))
+
+			parents, err := r.search(l, parentFilter(frontier[start:end]))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, p := range parents {
+				key := strings.ToLower(p.DN)
+				if seen[key] {
+					logger.Debug(fmt.Sprintf("group %s already resolved, skipping (cycle or shared parent)", p.DN))
+					continue
+				}
+				seen[key] = true
+				next = append(next, p)
+			}
+		}
+
+		if depth >= r.maxDepth {
+			if len(next) > 0 {
+				logger.Warn(fmt.Sprintf("nested group resolution stopped at depth %d, %d parent groups ignored", r.maxDepth, len(next)))
+			}
+			break
+		}
+
+		frontier = frontier[:0]
+		for _, g := range next {
+			groups = append(groups, g)
+			frontier = append(frontier, g.DN)
+		}
+	}
+
+	return groups, nil
+}
+
+func (r *GroupResolver) search(l *ldap.Conn, filter string) ([]*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		groupsBaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		"(&"+groupObjectClasses+filter+")",
+		[]string{"cn", "description"},
+		nil,
+	)
+
+	sr, err := l.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.Entries, nil
+}
+
+func (r *GroupResolver) cached(key string) ([]*ldap.Entry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.cache[key]
+	if !ok || time.Now().After(c.expiresAt) {
+		return nil, false
+	}
+	return c.entries, true
+}
+
+func (r *GroupResolver) store(key string, groups []*ldap.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for k, c := range r.cache {
+		if now.After(c.expiresAt) {
+			delete(r.cache, k)
+		}
+	}
+
+	r.cache[key] = resolvedGroups{entries: groups, expiresAt: now.Add(r.ttl)}
+}
+
+func memberFilter(userDN, userID string) string {
+	return fmt.Sprintf("(|(member=%s)(memberUid=%s))", ldap.EscapeFilter(userDN), ldap.EscapeFilter(userID))
+}
+
+func inChainFilter(userDN, userID string) string {
+	return fmt.Sprintf("(|(member:%s:=%s)(memberUid=%s))", matchingRuleInChain, ldap.EscapeFilter(userDN), ldap.EscapeFilter(userID))
+}
+
+func parentFilter(groupDNs []string) string {
+	var b strings.Builder
+	b.WriteString("(|")
+	for _, dn := range groupDNs {
+		b.WriteString("(member=")
+		b.WriteString(ldap.EscapeFilter(dn))
+		b.WriteString(")")
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+func containsAllGroups(groups, subset []*ldap.Entry) bool {
+	have := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		have[strings.ToLower(g.DN)] = true
+	}
+	for _, g := range subset {
+		if !have[strings.ToLower(g.DN)] {
+			return false
+		}
+	}
+	return true
+}
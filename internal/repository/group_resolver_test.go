@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+	"github.com/go-ldap/ldap/v3"
+)
+
+func nestedConfig(strategy string, maxDepth int) *config.Config {
+	return &config.Config{
+		Groups: config.GroupsConfig{
+			Nested: config.NestedGroupsConfig{Enabled: true, Strategy: strategy, MaxDepth: maxDepth},
+		},
+	}
+}
+
+func TestNestedGroupsDisabled(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+	ctx := context.Background()
+
+	groups, err := repo.GetUserGroups(ctx, "i21s0100", "i21s0100-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if groups.AcademicGroup != "" || groups.Subgroup != "Подгр2" {
+		t.Errorf("expected only the direct subgroup, got %+v", *groups)
+	}
+
+	user, err := repo.GetByID(ctx, "i21s0100", "i21s0100-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.Role != "" {
+		t.Errorf("expected no role without nesting, got %q", user.Role)
+	}
+}
+
+func TestNestedGroupsStrategies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		opts    []ldaptest.Option
+		inChain bool
+	}{
+		{name: "traversal", cfg: nestedConfig(NestedStrategyTraversal, 0)},
+		{name: "in chain", cfg: nestedConfig(NestedStrategyInChain, 0), opts: []ldaptest.Option{ldaptest.WithInChainMatching()}, inChain: true},
+		{name: "auto with in-chain support", cfg: nestedConfig(NestedStrategyAuto, 0), opts: []ldaptest.Option{ldaptest.WithInChainMatching()}, inChain: true},
+		{name: "auto falls back to traversal", cfg: nestedConfig(NestedStrategyAuto, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, _ := newDirectoryRepository(t, tt.cfg, tt.opts...)
+			ctx := context.Background()
+
+			groups, err := repo.GetUserGroups(ctx, "i21s0100", "i21s0100-pass")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if groups.AcademicGroup != "ИТ21-31" {
+				t.Errorf("expected group %q, got %q", "ИТ21-31", groups.AcademicGroup)
+			}
+			if groups.Subgroup != "Подгр2" {
+				t.Errorf("expected subgroup %q, got %q", "Подгр2", groups.Subgroup)
+			}
+
+			user, err := repo.GetByID(ctx, "i21s0100", "i21s0100-pass")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if user.Role != "student" {
+				t.Errorf("expected role %q through the nested academic group, got %q", "student", user.Role)
+			}
+
+			if unsupported := repo.resolver.inChainUnsupported.Load(); tt.cfg.Groups.Nested.Strategy == NestedStrategyAuto && unsupported == tt.inChain {
+				t.Errorf("expected in-chain support %v, got %v", tt.inChain, !unsupported)
+			}
+		})
+	}
+}
+
+func TestNestedGroupsCycleAndDepthLimit(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nestedConfig(NestedStrategyTraversal, 1))
+
+	groups, err := repo.GetUserGroups(context.Background(), "i21s0100", "i21s0100-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if groups.AcademicGroup != "" {
+		t.Errorf("expected depth limit to stop before the academic group, got %q", groups.AcademicGroup)
+	}
+
+	repo, _ = newDirectoryRepository(t, nestedConfig(NestedStrategyTraversal, 50))
+
+	decision, err := repo.ExplainRole(context.Background(), "i21s0100")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Direct subgroup and loop-b, then ИТ21-31 and loop-a; the loop back to
+	// loop-b must not be expanded again.
+	want := map[string]bool{
+		"cn=Подгр2,cn=ИТ21-31,ou=Groups,ou=Current,dc=it-college,dc=ru": true,
+		"cn=ИТ21-31,ou=Groups,ou=Current,dc=it-college,dc=ru":           true,
+		"cn=loop-a,ou=Groups,ou=Current,dc=it-college,dc=ru":            true,
+		"cn=loop-b,ou=Groups,ou=Current,dc=it-college,dc=ru":            true,
+	}
+
+	var resolved []string
+	err = repo.pool.Do(context.Background(), func(l *ldap.Conn) error {
+		entries, err := repo.resolver.Resolve(l, "uid=i21s0100,ou=People,dc=it-college,dc=ru", "i21s0100")
+		for _, e := range entries {
+			resolved = append(resolved, e.DN)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resolved) != len(want) {
+		t.Errorf("expected %d groups, got %v", len(want), resolved)
+	}
+	for _, dn := range resolved {
+		if !want[dn] {
+			t.Errorf("unexpected group %s", dn)
+		}
+	}
+
+	if decision.Role != "student" {
+		t.Errorf("expected role %q, got %q", "student", decision.Role)
+	}
+}
+
+func TestNestedGroupsAreCached(t *testing.T) {
+	repo, srv := newDirectoryRepository(t, nestedConfig(NestedStrategyTraversal, 0))
+	ctx := context.Background()
+
+	if _, err := repo.GetUserGroups(ctx, "i21s0100", "i21s0100-pass"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := srv.Modify("cn=ИТ21-31,ou=Groups,ou=Current,dc=it-college,dc=ru", "member"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	groups, err := repo.GetUserGroups(ctx, "i21s0100", "i21s0100-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if groups.AcademicGroup != "ИТ21-31" {
+		t.Errorf("expected cached group %q, got %q", "ИТ21-31", groups.AcademicGroup)
+	}
+}
+
+func TestNewGroupResolverRejectsUnknownStrategy(t *testing.T) {
+	if _, err := NewGroupResolver(config.NestedGroupsConfig{Enabled: true, Strategy: "magic"}); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
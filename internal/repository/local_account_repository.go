@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrLocalAccountNotFound = errors.New("local account not found")
+	ErrLocalAccountExists   = errors.New("local account already exists")
+)
+
+type LocalAccountRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewLocalAccountRepository(cfg *config.Config, db *mongo.Client) *LocalAccountRepository {
+	return &LocalAccountRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+func (r *LocalAccountRepository) coll() *mongo.Collection {
+	return r.db.Database(r.cfg.Mongo.DBName).Collection(r.cfg.Mongo.LocalUsersCollName)
+}
+
+func (r *LocalAccountRepository) Create(ctx context.Context, account *domain.LocalAccount) error {
+	if _, err := r.coll().InsertOne(ctx, account); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrLocalAccountExists
+		}
+		logger.Error(fmt.Errorf("failed to create local account %s: %w", account.ID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *LocalAccountRepository) Get(ctx context.Context, userID string) (*domain.LocalAccount, error) {
+	var account domain.LocalAccount
+	if err := r.coll().FindOne(ctx, bson.M{"_id": userID}).Decode(&account); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrLocalAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get local account: %w", err)
+	}
+
+	return &account, nil
+}
+
+func (r *LocalAccountRepository) List(ctx context.Context) ([]domain.LocalAccount, error) {
+	cursor, err := r.coll().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local accounts: %w", err)
+	}
+
+	accounts := []domain.LocalAccount{}
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to decode local accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// Update replaces the profile fields of an account; the password hash and
+// creation time are kept.
+func (r *LocalAccountRepository) Update(ctx context.Context, account *domain.LocalAccount) error {
+	set := bson.M{
+		"username":   account.Username,
+		"role":       account.Role,
+		"updated_at": time.Now(),
+	}
+	unset := bson.M{}
+
+	if account.Groups != nil {
+		set["groups"] = account.Groups
+	} else {
+		unset["groups"] = ""
+	}
+	if account.ExpiresAt != nil {
+		set["expires_at"] = account.ExpiresAt
+	} else {
+		unset["expires_at"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return r.update(ctx, account.ID, update)
+}
+
+func (r *LocalAccountRepository) SetPassword(ctx context.Context, userID, passwordHash string) error {
+	return r.update(ctx, userID, bson.M{"$set": bson.M{
+		"password_hash": passwordHash,
+		"updated_at":    time.Now(),
+	}})
+}
+
+func (r *LocalAccountRepository) Delete(ctx context.Context, userID string) error {
+	result, err := r.coll().DeleteOne(ctx, bson.M{"_id": userID})
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to delete local account %s: %w", userID, err))
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrLocalAccountNotFound
+	}
+
+	return nil
+}
+
+func (r *LocalAccountRepository) update(ctx context.Context, userID string, update bson.M) error {
+	result, err := r.coll().UpdateByID(ctx, userID, update)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to update local account %s: %w", userID, err))
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLocalAccountNotFound
+	}
+
+	return nil
+}
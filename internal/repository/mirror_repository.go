@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	syncStatusID = "directory"
+	syncLeaseID  = "directory_lease"
+)
+
+// ErrSyncLeaseHeld means another replica is running the directory sync.
+var ErrSyncLeaseHeld = errors.New("directory sync lease is held by another replica")
+
+type MirrorRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewMirrorRepository(cfg *config.Config, db *mongo.Client) *MirrorRepository {
+	return &MirrorRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+func (m *MirrorRepository) UpsertUsers(ctx context.Context, users []domain.DirectoryUser) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(users))
+	for _, u := range users {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": u.UserID}).
+			SetReplacement(u).
+			SetUpsert(true))
+	}
+
+	if _, err := m.users().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		logger.Error(fmt.Errorf("failed to upsert %d mirrored users: %w", len(users), err))
+		return err
+	}
+
+	return nil
+}
+
+func (m *MirrorRepository) UpsertGroups(ctx context.Context, groups []domain.DirectoryGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(groups))
+	for _, g := range groups {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": g.DN}).
+			SetReplacement(g).
+			SetUpsert(true))
+	}
+
+	if _, err := m.groups().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		logger.Error(fmt.Errorf("failed to upsert %d mirrored groups: %w", len(groups), err))
+		return err
+	}
+
+	return nil
+}
+
+// PruneUsers removes users not seen by a full sync started at before.
+func (m *MirrorRepository) PruneUsers(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.users().DeleteMany(ctx, bson.M{"synced_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (m *MirrorRepository) PruneGroups(ctx context.Context, before time.Time) (int64, error) {
+	result, err := m.groups().DeleteMany(ctx, bson.M{"synced_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// SearchUsers matches query case-insensitively against userid and username.
+func (m *MirrorRepository) SearchUsers(ctx context.Context, branch, query string, limit int) ([]domain.DirectoryUser, error) {
+	pattern := bson.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	filter := bson.M{
+		"branch": branch,
+		"$or": bson.A{
+			bson.M{"_id": pattern},
+			bson.M{"username": pattern},
+		},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := m.users().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search mirrored users: %w", err)
+	}
+
+	var users []domain.DirectoryUser
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode mirrored users: %w", err)
+	}
+
+	return users, nil
+}
+
+// ListNames returns the userid and username of every mirrored user.
+func (m *MirrorRepository) ListNames(ctx context.Context) ([]domain.DirectoryUser, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "username": 1})
+
+	cursor, err := m.users().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mirrored users: %w", err)
+	}
+
+	var users []domain.DirectoryUser
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("failed to decode mirrored users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (m *MirrorRepository) GetUser(ctx context.Context, userID string) (*domain.DirectoryUser, error) {
+	var user domain.DirectoryUser
+	if err := m.users().FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get mirrored user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// GetUserGroups returns the groups listing the user directly, by DN or uid.
+func (m *MirrorRepository) GetUserGroups(ctx context.Context, userDN, userID string) ([]domain.DirectoryGroup, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"members": strings.ToLower(userDN)},
+			bson.M{"member_uids": userID},
+		},
+	}
+
+	cursor, err := m.groups().Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mirrored groups: %w", err)
+	}
+
+	var groups []domain.DirectoryGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, fmt.Errorf("failed to decode mirrored groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+func (m *MirrorRepository) GetSyncStatus(ctx context.Context) (*domain.SyncStatus, error) {
+	var status domain.SyncStatus
+	if err := m.state().FindOne(ctx, bson.M{"_id": syncStatusID}).Decode(&status); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return &domain.SyncStatus{}, nil
+		}
+		return nil, fmt.Errorf("failed to get sync status: %w", err)
+	}
+
+	return &status, nil
+}
+
+func (m *MirrorRepository) SaveSyncStatus(ctx context.Context, status *domain.SyncStatus) error {
+	_, err := m.state().ReplaceOne(ctx, bson.M{"_id": syncStatusID}, status, options.Replace().SetUpsert(true))
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to save sync status: %w", err))
+		return err
+	}
+
+	return nil
+}
+
+// AcquireSyncLease takes the sync lease for holder until ttl from now, or
+// renews it. A lease of another holder that has not expired yet makes the
+// upsert collide on _id and is reported as ErrSyncLeaseHeld.
+func (m *MirrorRepository) AcquireSyncLease(ctx context.Context, holder string, ttl time.Duration) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id": syncLeaseID,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"holder": holder, "expires_at": now.Add(ttl)}}
+
+	_, err := m.state().UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSyncLeaseHeld
+	}
+	if err != nil {
+		return fmt.Errorf("failed to acquire sync lease: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseSyncLease gives the lease up if holder still has it.
+func (m *MirrorRepository) ReleaseSyncLease(ctx context.Context, holder string) error {
+	if _, err := m.state().DeleteOne(ctx, bson.M{"_id": syncLeaseID, "holder": holder}); err != nil {
+		return fmt.Errorf("failed to release sync lease: %w", err)
+	}
+	return nil
+}
+
+func (m *MirrorRepository) users() *mongo.Collection {
+	return m.db.Database(m.cfg.Mongo.DBName).Collection(m.cfg.Mongo.DirUsersCollName)
+}
+
+func (m *MirrorRepository) groups() *mongo.Collection {
+	return m.db.Database(m.cfg.Mongo.DBName).Collection(m.cfg.Mongo.DirGroupsCollName)
+}
+
+func (m *MirrorRepository) state() *mongo.Collection {
+	return m.db.Database(m.cfg.Mongo.DBName).Collection(m.cfg.Mongo.SyncCollName)
+}
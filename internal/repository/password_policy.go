@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Error values of the password policy response control.
+const (
+	ppolicyPasswordExpired  = 0
+	ppolicyAccountLocked    = 1
+	ppolicyChangeAfterReset = 2
+)
+
+type passwordPolicyResult struct {
+	expire     int64
+	grace      int64
+	errorCode  int8
+	mustChange bool
+}
+
+func parsePasswordPolicy(result *ldap.SimpleBindResult) passwordPolicyResult {
+	p := passwordPolicyResult{expire: -1, grace: -1, errorCode: -1}
+	if result == nil {
+		return p
+	}
+
+	for _, control := range result.Controls {
+		switch c := control.(type) {
+		case *ldap.ControlBeheraPasswordPolicy:
+			p.expire = c.Expire
+			p.grace = c.Grace
+			p.errorCode = c.Error
+		case *ldap.ControlVChuPasswordWarning:
+			if p.expire < 0 {
+				p.expire = c.Expire
+			}
+		case *ldap.ControlVChuPasswordMustChange:
+			p.mustChange = c.MustChange
+		}
+	}
+
+	return p
+}
+
+// policyError maps a policy control to a typed rejection. It returns nil when
+// the control does not prevent the sign-in.
+func (p passwordPolicyResult) policyError() *domain.PasswordPolicyError {
+	switch {
+	case p.errorCode == ppolicyAccountLocked:
+		return &domain.PasswordPolicyError{Code: domain.AccountLocked, Message: "account is locked"}
+	case p.errorCode == ppolicyPasswordExpired && p.grace < 0:
+		return &domain.PasswordPolicyError{Code: domain.PasswordExpired, Message: "password has expired"}
+	case p.errorCode == ppolicyChangeAfterReset || p.mustChange:
+		return &domain.PasswordPolicyError{Code: domain.PasswordMustChange, Message: "password must be changed"}
+	}
+	return nil
+}
+
+func (p passwordPolicyResult) status() *domain.PasswordPolicyStatus {
+	if p.grace < 0 && p.expire < 0 {
+		return nil
+	}
+
+	status := &domain.PasswordPolicyStatus{}
+
+	if p.grace >= 0 {
+		grace := int(p.grace)
+		status.GraceLoginsRemaining = &grace
+	}
+
+	if p.expire >= 0 {
+		expire := p.expire
+		days := int(expire / 86400)
+		status.ExpiresInSeconds = &expire
+		status.ExpiresInDays = &days
+	}
+
+	return status
+}
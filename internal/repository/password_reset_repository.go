@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type PasswordResetRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewPasswordResetRepository(cfg *config.Config, db *mongo.Client) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+func (p *PasswordResetRepository) Create(ctx context.Context, reset *domain.PasswordReset) error {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.ResetCollName)
+
+	if _, err := coll.InsertOne(ctx, reset); err != nil {
+		logger.Error(fmt.Errorf("failed to save password reset for user %s: %w", reset.UserID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (p *PasswordResetRepository) GetActive(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.ResetCollName)
+
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+
+	var reset domain.PasswordReset
+	if err := coll.FindOne(ctx, filter).Decode(&reset); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("reset token not found or expired")
+		}
+		return nil, fmt.Errorf("failed to get password reset: %w", err)
+	}
+
+	return &reset, nil
+}
+
+// Consume marks an active token as used. Only one concurrent caller succeeds.
+func (p *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.ResetCollName)
+
+	now := time.Now()
+	filter := bson.M{
+		"token_hash": tokenHash,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+
+	var reset domain.PasswordReset
+	err := coll.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&reset)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("reset token not found or expired")
+		}
+		return nil, fmt.Errorf("failed to consume password reset: %w", err)
+	}
+
+	return &reset, nil
+}
+
+// Release makes a consumed token usable again after the reset itself failed.
+func (p *PasswordResetRepository) Release(ctx context.Context, tokenHash string) error {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.ResetCollName)
+
+	if _, err := coll.UpdateOne(ctx, bson.M{"token_hash": tokenHash}, bson.M{"$unset": bson.M{"used_at": ""}}); err != nil {
+		return fmt.Errorf("failed to release password reset: %w", err)
+	}
+
+	return nil
+}
+
+func (p *PasswordResetRepository) DeleteForUser(ctx context.Context, userID string) error {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.ResetCollName)
+
+	result, err := coll.DeleteMany(ctx, bson.M{"userid": userID})
+	if err != nil {
+		return err
+	}
+
+	logger.Debug(fmt.Sprintf("deleted %d pending password resets for user %s", result.DeletedCount, userID))
+	return nil
+}
+
+// CountAttempt records a reset request under key and returns the number of
+// requests made under it in the current fixed window, this one included.
+func (p *PasswordResetRepository) CountAttempt(ctx context.Context, key string, window time.Duration) (int, error) {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.ResetAttemptsCollName)
+
+	start := time.Now().Truncate(window)
+	filter := bson.M{"_id": fmt.Sprintf("%s@%d", key, start.Unix())}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expires_at": start.Add(window)},
+	}
+
+	var attempt struct {
+		Count int `bson:"count"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&attempt); err != nil {
+		return 0, fmt.Errorf("failed to count password reset attempt: %w", err)
+	}
+
+	return attempt.Count, nil
+}
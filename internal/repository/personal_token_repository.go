@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrPersonalTokenNotFound = errors.New("personal access token not found")
+
+type PersonalTokenRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewPersonalTokenRepository(cfg *config.Config, db *mongo.Client) *PersonalTokenRepository {
+	return &PersonalTokenRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+func (r *PersonalTokenRepository) coll() *mongo.Collection {
+	return r.db.Database(r.cfg.Mongo.DBName).Collection(r.cfg.Mongo.TokensCollName)
+}
+
+func (r *PersonalTokenRepository) Create(ctx context.Context, token *domain.PersonalToken) error {
+	if _, err := r.coll().InsertOne(ctx, token); err != nil {
+		logger.Error(fmt.Errorf("failed to save personal access token for user %s: %w", token.UserID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *PersonalTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.PersonalToken, error) {
+	var token domain.PersonalToken
+	if err := r.coll().FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrPersonalTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get personal access token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *PersonalTokenRepository) ListForUser(ctx context.Context, userID string) ([]domain.PersonalToken, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.coll().Find(ctx, bson.M{"userid": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+
+	tokens := []domain.PersonalToken{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode personal access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Delete removes a token of the given user; tokens of other users are
+// reported as not found.
+func (r *PersonalTokenRepository) Delete(ctx context.Context, userID, id string) error {
+	result, err := r.coll().DeleteOne(ctx, bson.M{"_id": id, "userid": userID})
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to delete personal access token %s: %w", id, err))
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrPersonalTokenNotFound
+	}
+
+	return nil
+}
+
+// DeleteForUser revokes every token of a user, for example after a password
+// change.
+func (r *PersonalTokenRepository) DeleteForUser(ctx context.Context, userID string) error {
+	if _, err := r.coll().DeleteMany(ctx, bson.M{"userid": userID}); err != nil {
+		logger.Error(fmt.Errorf("failed to delete personal access tokens of %s: %w", userID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *PersonalTokenRepository) Touch(ctx context.Context, id string, usedAt time.Time) error {
+	if _, err := r.coll().UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_used_at": usedAt}}); err != nil {
+		return fmt.Errorf("failed to record token use: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrProfileNotFound = errors.New("user profile not found")
+
+type ProfileRepository struct {
+	cfg *config.Config
+	db  *mongo.Client
+}
+
+func NewProfileRepository(cfg *config.Config, db *mongo.Client) *ProfileRepository {
+	return &ProfileRepository{
+		cfg: cfg,
+		db:  db,
+	}
+}
+
+// RecordSignIn creates or refreshes the profile and stamps the login time.
+// Groups are only overwritten when the caller provides them, and flags are
+// never touched here.
+func (p *ProfileRepository) RecordSignIn(ctx context.Context, profile *domain.UserProfile) error {
+	return p.save(ctx, profile, true)
+}
+
+// UpdateFromSource stores data reloaded from the directory without counting
+// it as a sign-in.
+func (p *ProfileRepository) UpdateFromSource(ctx context.Context, profile *domain.UserProfile) error {
+	return p.save(ctx, profile, false)
+}
+
+func (p *ProfileRepository) save(ctx context.Context, profile *domain.UserProfile, signIn bool) error {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.UsersCollName)
+
+	now := time.Now()
+	set := bson.M{
+		"username":   profile.Username,
+		"role":       profile.Role,
+		"roles":      profile.Roles,
+		"source":     profile.Source,
+		"updated_at": now,
+		"synced_at":  now,
+	}
+	if profile.Groups != nil {
+		set["groups"] = profile.Groups
+	}
+	if signIn {
+		set["last_login_at"] = now
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"created_at": now,
+		},
+	}
+
+	if _, err := coll.UpdateByID(ctx, profile.ID, update, options.UpdateOne().SetUpsert(true)); err != nil {
+		logger.Error(fmt.Errorf("failed to save profile for user %s: %w", profile.ID, err))
+		return err
+	}
+
+	return nil
+}
+
+func (p *ProfileRepository) GetByID(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.UsersCollName)
+
+	var profile domain.UserProfile
+	if err := coll.FindOne(ctx, bson.M{"_id": userID}).Decode(&profile); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, fmt.Errorf("failed to get user profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
+func (p *ProfileRepository) SetFlags(ctx context.Context, userID string, flags []string) error {
+	coll := p.db.Database(p.cfg.Mongo.DBName).Collection(p.cfg.Mongo.UsersCollName)
+
+	update := bson.M{"$set": bson.M{"flags": flags, "updated_at": time.Now()}}
+
+	result, err := coll.UpdateByID(ctx, userID, update)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to update profile flags for user %s: %w", userID, err))
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrProfileNotFound
+	}
+
+	return nil
+}
@@ -2,15 +2,22 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/anton1ks96/college-auth-svc/internal/domain"
 )
 
 // UserLDAPRepository handles user authentication and data retrieval from LDAP
 type UserLDAPRepository interface {
-	Authentication(ctx context.Context, userID, userPass string) error
+	Authentication(ctx context.Context, userID, userPass string) (*domain.PasswordPolicyStatus, error)
 	GetByID(ctx context.Context, userID, userPass string) (*domain.User, error)
 	GetUserGroups(ctx context.Context, userID, userPass string) (*domain.UserGroups, error)
+	ExplainRole(ctx context.Context, userID string) (*domain.RoleDecision, error)
+	Reload(ctx context.Context, userID string) (*domain.UserProfile, error)
+	ReloadFromMirror(ctx context.Context, userID string) (*domain.UserProfile, error)
+	ChangePassword(ctx context.Context, userID, oldPass, newPass string) error
+	ResetPassword(ctx context.Context, userID, newPass string) error
+	GetMail(ctx context.Context, userID string) (string, error)
 }
 
 // SessionMongoRepository manages refresh tokens and user sessions in MongoDB
@@ -18,8 +25,94 @@ type SessionMongoRepository interface {
 	SaveRefreshToken(ctx context.Context, session *domain.RefreshSession) error
 	RevokeRefreshToken(ctx context.Context, jti string) error
 	RevokeAllUserSessions(ctx context.Context, userID string) error
+	RevokeOtherUserSessions(ctx context.Context, userID, keepJTI string) error
 	TokenExists(ctx context.Context, jti string) (bool, error)
 	ReplaceRefreshToken(ctx context.Context, oldJTI string, newSession *domain.RefreshSession) error
-	GetUserByID(ctx context.Context, userID string) (*domain.User, error)
-	GetExtendedUserByID(ctx context.Context, userID string) (*domain.UserExtended, error)
+}
+
+// ProfileMongoRepository stores user profiles that outlive refresh sessions
+type ProfileMongoRepository interface {
+	RecordSignIn(ctx context.Context, profile *domain.UserProfile) error
+	UpdateFromSource(ctx context.Context, profile *domain.UserProfile) error
+	GetByID(ctx context.Context, userID string) (*domain.UserProfile, error)
+	SetFlags(ctx context.Context, userID string, flags []string) error
+}
+
+// LocalAccountMongoRepository stores users that exist outside LDAP
+type LocalAccountMongoRepository interface {
+	Create(ctx context.Context, account *domain.LocalAccount) error
+	Get(ctx context.Context, userID string) (*domain.LocalAccount, error)
+	List(ctx context.Context) ([]domain.LocalAccount, error)
+	Update(ctx context.Context, account *domain.LocalAccount) error
+	SetPassword(ctx context.Context, userID, passwordHash string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+// FixtureUserRepository checks the credentials of test mode fixture users
+type FixtureUserRepository interface {
+	Authenticate(ctx context.Context, userID, password string) (*domain.UserExtended, error)
+}
+
+// AuditMongoRepository appends to the audit log
+type AuditMongoRepository interface {
+	Record(ctx context.Context, event *domain.AuditEvent) error
+}
+
+// AccessMongoRepository stores suspensions, the sign-in access policy and
+// the used one-time code steps
+type AccessMongoRepository interface {
+	Suspend(ctx context.Context, suspension *domain.Suspension) error
+	GetSuspension(ctx context.Context, userID string) (*domain.Suspension, error)
+	ListSuspensions(ctx context.Context) ([]domain.Suspension, error)
+	Unsuspend(ctx context.Context, userID string) error
+	GetPolicy(ctx context.Context) (*domain.AccessPolicy, error)
+	SavePolicy(ctx context.Context, policy *domain.AccessPolicy) error
+	ClaimOTPStep(ctx context.Context, userID string, step int64) error
+}
+
+// PersonalTokenMongoRepository stores hashed personal access tokens
+type PersonalTokenMongoRepository interface {
+	Create(ctx context.Context, token *domain.PersonalToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*domain.PersonalToken, error)
+	ListForUser(ctx context.Context, userID string) ([]domain.PersonalToken, error)
+	Delete(ctx context.Context, userID, id string) error
+	DeleteForUser(ctx context.Context, userID string) error
+	Touch(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// PasswordResetMongoRepository stores hashed one-time password reset tokens
+type PasswordResetMongoRepository interface {
+	Create(ctx context.Context, reset *domain.PasswordReset) error
+	GetActive(ctx context.Context, tokenHash string) (*domain.PasswordReset, error)
+	Consume(ctx context.Context, tokenHash string) (*domain.PasswordReset, error)
+	Release(ctx context.Context, tokenHash string) error
+	DeleteForUser(ctx context.Context, userID string) error
+	CountAttempt(ctx context.Context, key string, window time.Duration) (int, error)
+}
+
+// DirectoryLDAPRepository reads whole directory branches with the service account
+type DirectoryLDAPRepository interface {
+	ExportUsers(ctx context.Context, since string) ([]domain.DirectoryUser, error)
+	ExportGroups(ctx context.Context, since string) ([]domain.DirectoryGroup, error)
+	SearchPeople(ctx context.Context, query domain.PersonQuery) (*domain.PersonPage, error)
+	SuggestPeople(ctx context.Context, query domain.PersonQuery) ([]domain.Person, error)
+	GetPeople(ctx context.Context, ids []string) ([]domain.Person, error)
+	ListGroups(ctx context.Context, category string, offset, limit int) (*domain.GroupPage, error)
+	GroupMembers(ctx context.Context, name, category string, query domain.PersonQuery) (*domain.PersonPage, error)
+}
+
+// DirectoryMirrorRepository stores the MongoDB copy of the directory and its sync status
+type DirectoryMirrorRepository interface {
+	UpsertUsers(ctx context.Context, users []domain.DirectoryUser) error
+	UpsertGroups(ctx context.Context, groups []domain.DirectoryGroup) error
+	PruneUsers(ctx context.Context, before time.Time) (int64, error)
+	PruneGroups(ctx context.Context, before time.Time) (int64, error)
+	SearchUsers(ctx context.Context, branch, query string, limit int) ([]domain.DirectoryUser, error)
+	ListNames(ctx context.Context) ([]domain.DirectoryUser, error)
+	GetUser(ctx context.Context, userID string) (*domain.DirectoryUser, error)
+	GetUserGroups(ctx context.Context, userDN, userID string) ([]domain.DirectoryGroup, error)
+	GetSyncStatus(ctx context.Context) (*domain.SyncStatus, error)
+	SaveSyncStatus(ctx context.Context, status *domain.SyncStatus) error
+	AcquireSyncLease(ctx context.Context, holder string, ttl time.Duration) error
+	ReleaseSyncLease(ctx context.Context, holder string) error
 }
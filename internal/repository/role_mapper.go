@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// defaultRoleRules reproduce the historical admin > teacher > student precedence
+// and are used when no rules are configured.
+var defaultRoleRules = []config.RoleRule{
+	{Name: "admin-group", Role: "admin", Group: `^cn=admin,(.+,)?ou=current,dc=it-college,dc=ru$`, Stop: true},
+	{Name: "teachers-group", Role: "teacher", Group: `^cn=teachers,(.+,)?ou=current,dc=it-college,dc=ru$`, Stop: true},
+	{Name: "students", Role: "student", OU: "People", Group: `^cn=(students|ИТ[^,]*),(.+,)?ou=current,dc=it-college,dc=ru$`, Stop: true},
+	{Name: "teachers-ou", Role: "teacher", OU: "Teachers", Stop: true},
+}
+
+type roleRule struct {
+	name      string
+	role      string
+	group     *regexp.Regexp
+	ou        string
+	attribute string
+	value     *regexp.Regexp
+	stop      bool
+}
+
+// RoleSubject is the directory data a role decision is made from.
+type RoleSubject struct {
+	DN         string
+	MemberOf   []string
+	Attributes map[string][]string
+}
+
+type RoleMapper struct {
+	rules       []roleRule
+	defaultRole string
+}
+
+func NewRoleMapper(cfg config.RolesConfig) (*RoleMapper, error) {
+	rules := cfg.Rules
+	if len(rules) == 0 {
+		rules = defaultRoleRules
+	}
+
+	m := &RoleMapper{defaultRole: cfg.Default}
+
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule-%d", i+1)
+		}
+
+		if r.Role == "" {
+			return nil, fmt.Errorf("role rule %s: role is required", name)
+		}
+
+		if r.Group == "" && r.OU == "" && r.Attribute == "" {
+			return nil, fmt.Errorf("role rule %s: at least one of group, ou or attribute is required", name)
+		}
+
+		if (r.Attribute == "") != (r.Value == "") {
+			return nil, fmt.Errorf("role rule %s: attribute and value must be set together", name)
+		}
+
+		rule := roleRule{
+			name:      name,
+			role:      r.Role,
+			ou:        r.OU,
+			attribute: r.Attribute,
+			stop:      r.Stop,
+		}
+
+		if r.Group != "" {
+			re, err := regexp.Compile("(?i)" + r.Group)
+			if err != nil {
+				return nil, fmt.Errorf("role rule %s: invalid group pattern: %w", name, err)
+			}
+			rule.group = re
+		}
+
+		if r.Value != "" {
+			re, err := regexp.Compile("(?i)" + r.Value)
+			if err != nil {
+				return nil, fmt.Errorf("role rule %s: invalid value pattern: %w", name, err)
+			}
+			rule.value = re
+		}
+
+		m.rules = append(m.rules, rule)
+	}
+
+	return m, nil
+}
+
+// Attributes returns the extra LDAP attributes referenced by the rules.
+func (m *RoleMapper) Attributes() []string {
+	var attrs []string
+	seen := make(map[string]bool)
+	for _, r := range m.rules {
+		key := strings.ToLower(r.attribute)
+		if r.attribute == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		attrs = append(attrs, r.attribute)
+	}
+	return attrs
+}
+
+func (m *RoleMapper) Evaluate(subject RoleSubject) *domain.RoleDecision {
+	decision := &domain.RoleDecision{
+		DN:      subject.DN,
+		Roles:   []string{},
+		Matches: []domain.RuleMatch{},
+	}
+
+	for _, r := range m.rules {
+		reasons, ok := r.match(subject)
+		if !ok {
+			continue
+		}
+
+		decision.Matches = append(decision.Matches, domain.RuleMatch{
+			Rule:    r.name,
+			Role:    r.role,
+			Reasons: reasons,
+			Stop:    r.stop,
+		})
+
+		if !containsFold(decision.Roles, r.role) {
+			decision.Roles = append(decision.Roles, r.role)
+		}
+
+		if r.stop {
+			break
+		}
+	}
+
+	if len(decision.Roles) == 0 && m.defaultRole != "" {
+		decision.Roles = append(decision.Roles, m.defaultRole)
+		decision.DefaultApplied = true
+	}
+
+	if len(decision.Roles) > 0 {
+		decision.Role = decision.Roles[0]
+	}
+
+	return decision
+}
+
+func (r roleRule) match(subject RoleSubject) ([]string, bool) {
+	var reasons []string
+
+	if r.ou != "" {
+		if !dnHasOU(subject.DN, r.ou) {
+			return nil, false
+		}
+		reasons = append(reasons, "ou="+r.ou)
+	}
+
+	if r.group != nil {
+		matched := ""
+		for _, memberOf := range subject.MemberOf {
+			if r.group.MatchString(memberOf) {
+				matched = memberOf
+				break
+			}
+		}
+		if matched == "" {
+			return nil, false
+		}
+		reasons = append(reasons, "memberOf "+matched)
+	}
+
+	if r.value != nil {
+		matched := ""
+		for name, values := range subject.Attributes {
+			if !strings.EqualFold(name, r.attribute) {
+				continue
+			}
+			for _, v := range values {
+				if r.value.MatchString(v) {
+					matched = v
+					break
+				}
+			}
+		}
+		if matched == "" {
+			return nil, false
+		}
+		reasons = append(reasons, r.attribute+"="+matched)
+	}
+
+	return reasons, true
+}
+
+func dnHasOU(dn, ou string) bool {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return false
+	}
+
+	for _, rdn := range parsed.RDNs {
+		for _, attr := range rdn.Attributes {
+			if strings.EqualFold(attr.Type, "ou") && strings.EqualFold(attr.Value, ou) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
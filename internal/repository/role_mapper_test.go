@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+)
+
+func TestRoleMapperDefaultRules(t *testing.T) {
+	mapper, err := NewRoleMapper(config.RolesConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		subject  RoleSubject
+		wantRole string
+	}{
+		{
+			name: "admin wins over teachers",
+			subject: RoleSubject{
+				DN: "uid=t001,ou=Teachers,dc=it-college,dc=ru",
+				MemberOf: []string{
+					"cn=teachers,ou=Current,dc=it-college,dc=ru",
+					"cn=admin,ou=Current,dc=it-college,dc=ru",
+				},
+			},
+			wantRole: "admin",
+		},
+		{
+			name: "student by academic group",
+			subject: RoleSubject{
+				DN:       "uid=i24s0291,ou=People,dc=it-college,dc=ru",
+				MemberOf: []string{"cn=ИТ24-11,ou=Current,dc=it-college,dc=ru"},
+			},
+			wantRole: "student",
+		},
+		{
+			name: "teacher by OU without groups",
+			subject: RoleSubject{
+				DN: "uid=t002,ou=Teachers,dc=it-college,dc=ru",
+			},
+			wantRole: "teacher",
+		},
+		{
+			name: "academic group outside ou=People",
+			subject: RoleSubject{
+				DN:       "uid=x1,ou=Guests,dc=it-college,dc=ru",
+				MemberOf: []string{"cn=ИТ24-11,ou=Current,dc=it-college,dc=ru"},
+			},
+			wantRole: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := mapper.Evaluate(tt.subject)
+			if decision.Role != tt.wantRole {
+				t.Errorf("expected role %q, got %q", tt.wantRole, decision.Role)
+			}
+		})
+	}
+}
+
+func TestRoleMapperMultipleRolesAndDefault(t *testing.T) {
+	mapper, err := NewRoleMapper(config.RolesConfig{
+		Default: "guest",
+		Rules: []config.RoleRule{
+			{Name: "teachers", Role: "teacher", OU: "Teachers"},
+			{Name: "curators", Role: "curator", Attribute: "employeeType", Value: "^curator$"},
+			{Name: "methodists", Role: "methodist", Group: "^cn=methodists,", Stop: true},
+			{Name: "unreachable", Role: "admin", OU: "Teachers"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	decision := mapper.Evaluate(RoleSubject{
+		DN:         "uid=t010,ou=Teachers,dc=it-college,dc=ru",
+		MemberOf:   []string{"cn=Methodists,ou=Current,dc=it-college,dc=ru"},
+		Attributes: map[string][]string{"employeeType": {"Curator"}},
+	})
+
+	wantRoles := []string{"teacher", "curator", "methodist"}
+	if !reflect.DeepEqual(decision.Roles, wantRoles) {
+		t.Errorf("expected roles %v, got %v", wantRoles, decision.Roles)
+	}
+	if decision.Role != "teacher" {
+		t.Errorf("expected primary role %q, got %q", "teacher", decision.Role)
+	}
+
+	decision = mapper.Evaluate(RoleSubject{DN: "uid=x1,ou=Guests,dc=it-college,dc=ru"})
+	if decision.Role != "guest" || !decision.DefaultApplied {
+		t.Errorf("expected default role guest, got %q (default applied: %v)", decision.Role, decision.DefaultApplied)
+	}
+}
+
+func TestRoleMapperRejectsInvalidRules(t *testing.T) {
+	invalid := []config.RoleRule{
+		{Name: "no-role", OU: "People"},
+		{Name: "no-condition", Role: "student"},
+		{Name: "bad-regex", Role: "student", Group: "("},
+		{Name: "attribute-without-value", Role: "student", Attribute: "employeeType"},
+	}
+
+	for _, rule := range invalid {
+		if _, err := NewRoleMapper(config.RolesConfig{Rules: []config.RoleRule{rule}}); err == nil {
+			t.Errorf("expected error for rule %s", rule.Name)
+		}
+	}
+}
@@ -69,6 +69,19 @@ func (s *SessionsRepository) RevokeAllUserSessions(ctx context.Context, userID s
 	return nil
 }
 
+func (s *SessionsRepository) RevokeOtherUserSessions(ctx context.Context, userID, keepJTI string) error {
+	coll := s.db.Database(s.cfg.Mongo.DBName).Collection(s.cfg.Mongo.CollName)
+	filter := bson.M{"userid": userID, "jti": bson.M{"$ne": keepJTI}}
+
+	result, err := coll.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug(fmt.Sprintf("revoked %d other sessions for user %s", result.DeletedCount, userID))
+	return nil
+}
+
 func (s *SessionsRepository) RevokeRefreshToken(ctx context.Context, jti string) error {
 	coll := s.db.Database(s.cfg.Mongo.DBName).Collection(s.cfg.Mongo.CollName)
 	filter := bson.M{"jti": jti}
@@ -108,49 +121,3 @@ func (s *SessionsRepository) ReplaceRefreshToken(ctx context.Context, oldJTI str
 
 	return nil
 }
-
-func (s *SessionsRepository) GetUserByID(ctx context.Context, userID string) (*domain.User, error) {
-	coll := s.db.Database(s.cfg.Mongo.DBName).Collection(s.cfg.Mongo.CollName)
-
-	var session domain.RefreshSession
-	filter := bson.M{"userid": userID}
-
-	err := coll.FindOne(ctx, filter).Decode(&session)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, fmt.Errorf("user session not found")
-		}
-		return nil, fmt.Errorf("failed to get user session: %w", err)
-	}
-
-	return &domain.User{
-		ID:       session.UserID,
-		Username: session.Username,
-		Role:     session.Role,
-	}, nil
-}
-
-func (s *SessionsRepository) GetExtendedUserByID(ctx context.Context, userID string) (*domain.UserExtended, error) {
-	coll := s.db.Database(s.cfg.Mongo.DBName).Collection(s.cfg.Mongo.CollName)
-
-	var session domain.RefreshSession
-	filter := bson.M{"userid": userID}
-
-	err := coll.FindOne(ctx, filter).Decode(&session)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, fmt.Errorf("user session not found")
-		}
-		return nil, fmt.Errorf("failed to get user session: %w", err)
-	}
-
-	return &domain.UserExtended{
-		ID:            session.UserID,
-		Username:      session.Username,
-		Role:          session.Role,
-		AcademicGroup: session.AcademicGroup,
-		Profile:       session.Profile,
-		Subgroup:      session.Subgroup,
-		EnglishGroup:  session.EnglishGroup,
-	}, nil
-}
@@ -2,50 +2,100 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/anton1ks96/college-auth-svc/internal/config"
 	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
 	"github.com/anton1ks96/college-auth-svc/pkg/logger"
 	"github.com/go-ldap/ldap/v3"
 )
 
 type UserRepository struct {
-	cfg *config.Config
+	cfg      *config.Config
+	pool     *ldappool.Pool
+	roles    *RoleMapper
+	groups   *GroupClassifier
+	resolver *GroupResolver
+	mirror   DirectoryMirrorRepository
 }
 
-func NewUserRepository(cfg *config.Config) *UserRepository {
-	return &UserRepository{cfg: cfg}
+func NewUserRepository(cfg *config.Config, pool *ldappool.Pool) *UserRepository {
+	roles, err := NewRoleMapper(cfg.Roles)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid role mapping rules: %w", err))
+	}
+
+	groups, err := NewGroupClassifier(cfg.Groups)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid group classification: %w", err))
+	}
+
+	resolver, err := NewGroupResolver(cfg.Groups.Nested)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid nested group settings: %w", err))
+	}
+
+	return &UserRepository{
+		cfg:      cfg,
+		pool:     pool,
+		roles:    roles,
+		groups:   groups,
+		resolver: resolver,
+	}
 }
 
-func (u *UserRepository) Authentication(ctx context.Context, userID, userPass string) error {
+func (u *UserRepository) Authentication(ctx context.Context, userID, userPass string) (*domain.PasswordPolicyStatus, error) {
 	if ctx.Err() != nil {
 		logger.Error(fmt.Errorf("context cancelled during authentication for user %s: %w", userID, ctx.Err()))
-		return ctx.Err()
+		return nil, ctx.Err()
 	}
 
-	l, err := ldap.DialURL(u.cfg.LDAP.URL)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to connect to LDAP server %s for user %s: %w", u.cfg.LDAP.URL, userID, err))
-		return fmt.Errorf("LDAP connection failed")
-	}
-	defer l.Close()
+	var policy passwordPolicyResult
 
-	userDN, err := u.findUserDN(l, userID)
+	err := u.pool.Do(ctx, func(l *ldap.Conn) error {
+		userDN, err := u.findUserDN(l, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to find DN for user %s: %w", userID, err))
+			return opError("user not found", err)
+		}
+
+		logger.Debug(fmt.Sprintf("Found DN for user %s: %s", userID, userDN))
+
+		result, err := l.SimpleBind(&ldap.SimpleBindRequest{
+			Username: userDN,
+			Password: userPass,
+			Controls: []ldap.Control{ldap.NewControlBeheraPasswordPolicy()},
+		})
+		policy = parsePasswordPolicy(result)
+
+		if ppErr := policy.policyError(); ppErr != nil {
+			logger.Warn(fmt.Sprintf("LDAP password policy rejected user %s: %s", userID, ppErr.Code))
+			return ppErr
+		}
+
+		if err != nil {
+			logger.Warn(fmt.Sprintf("LDAP authentication failed for user %s with DN %s", userID, userDN))
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+				return domain.ErrInvalidCredentials
+			}
+			return opError(fmt.Sprintf("authentication failed: %s", err.Error()), err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to find DN for user %s: %w", userID, err))
-		return fmt.Errorf("user not found")
+		return nil, u.connError(err, fmt.Sprintf("authentication of %s", userID))
 	}
 
-	logger.Debug(fmt.Sprintf("Found DN for user %s: %s", userID, userDN))
-
-	if err := l.Bind(userDN, userPass); err != nil {
-		logger.Warn(fmt.Sprintf("LDAP authentication failed for user %s with DN %s", userID, userDN))
-		return fmt.Errorf("authentication failed: %s", err.Error())
+	status := policy.status()
+	if status != nil {
+		logger.Debug(fmt.Sprintf("password policy warning for user %s: %+v", userID, policy))
 	}
 
-	return nil
+	return status, nil
 }
 
 func (u *UserRepository) GetByID(ctx context.Context, userID, userPass string) (*domain.User, error) {
@@ -54,188 +104,272 @@ func (u *UserRepository) GetByID(ctx context.Context, userID, userPass string) (
 		return nil, ctx.Err()
 	}
 
-	l, err := ldap.DialURL(u.cfg.LDAP.URL)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to connect to LDAP server %s during user lookup for %s: %w", u.cfg.LDAP.URL, userID, err))
-		return nil, fmt.Errorf("LDAP connection failed")
-	}
-	defer l.Close()
+	var user *domain.User
 
-	dn, err := u.findUserDN(l, userID)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to find DN for user %s: %w", userID, err))
-		return nil, fmt.Errorf("user not found")
-	}
+	err := u.pool.Do(ctx, func(l *ldap.Conn) error {
+		dn, err := u.findUserDN(l, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to find DN for user %s: %w", userID, err))
+			return opError("user not found", err)
+		}
 
-	logger.Debug(fmt.Sprintf("Found DN for user %s: %s", userID, dn))
+		logger.Debug(fmt.Sprintf("Found DN for user %s: %s", userID, dn))
 
-	var baseDN string
-	if !strings.HasPrefix(userID, "t") {
-		baseDN = "ou=People,dc=it-college,dc=ru"
-	} else {
-		baseDN = "ou=Teachers,dc=it-college,dc=ru"
-	}
+		baseDN := userBaseDN(userID)
 
-	if err := l.Bind(dn, userPass); err != nil {
-		logger.Error(fmt.Errorf("failed to bind account %s to LDAP during user lookup for %s: %w", userID, userID, err))
-		return nil, fmt.Errorf("service account bind failed")
-	}
+		if err := l.Bind(dn, userPass); err != nil {
+			logger.Error(fmt.Errorf("failed to bind account %s to LDAP during user lookup for %s: %w", userID, userID, err))
+			return opError("service account bind failed", err)
+		}
 
-	searchFilter := fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(userID))
+		searchFilter := fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(userID))
+
+		logger.Debug(fmt.Sprintf("Search filter: %s in baseDN: %s", searchFilter, baseDN))
+
+		userReq := ldap.NewSearchRequest(
+			baseDN,
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			0,
+			5,
+			false,
+			searchFilter,
+			append([]string{"uid", "cn", "memberOf"}, u.roles.Attributes()...),
+			nil,
+		)
+
+		sr, err := l.Search(userReq)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP search failed for user %s with filter %s in baseDN %s: %w", userID, searchFilter, baseDN, err))
+			return opError("user search failed", err)
+		}
 
-	logger.Debug(fmt.Sprintf("Search filter: %s in baseDN: %s", searchFilter, baseDN))
+		if ctx.Err() != nil {
+			logger.Error(fmt.Errorf("context cancelled after LDAP search for user %s: %w", userID, ctx.Err()))
+			return ctx.Err()
+		}
 
-	userReq := ldap.NewSearchRequest(
-		baseDN,
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		0,
-		5,
-		false,
-		searchFilter,
-		[]string{"uid", "cn", "memberOf"},
-		nil,
-	)
+		if len(sr.Entries) == 0 {
+			logger.Warn(fmt.Sprintf("user %s not found in LDAP directory", userID))
+			return fmt.Errorf("user not found")
+		}
+
+		if len(sr.Entries) > 1 {
+			logger.Error(fmt.Errorf("multiple LDAP entries (%d) found for user %s - this should not happen", len(sr.Entries), userID))
+			return fmt.Errorf("multiple users found")
+		}
+
+		entry := sr.Entries[0]
+
+		uid := entry.GetAttributeValue("uid")
+		cn := entry.GetAttributeValue("cn")
+		memberOfValues := entry.GetAttributeValues("memberOf")
+
+		logger.Debug(fmt.Sprintf("User %s memberOf: %v", userID, memberOfValues))
+
+		groups, err := u.resolver.Resolve(l, dn, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP group search failed for user %s: %w", userID, err))
+			return opError("group search failed", err)
+		}
+		if len(groups) == 0 {
+			logger.Warn(fmt.Sprintf("user %s has no group under ou=Current", userID))
+			return domain.ErrAccountInactive
+		}
+
+		subject := roleSubject(entry, dn)
+		u.addMemberOf(&subject, groups)
 
-	sr, err := l.Search(userReq)
+		decision := u.roles.Evaluate(subject)
+		if decision.Role == "" {
+			logger.Warn(fmt.Sprintf("role not determined from groups and DN for user %s", userID))
+		}
+
+		logger.Debug(fmt.Sprintf("User %s role determined as: %s (all roles: %v)", userID, decision.Role, decision.Roles))
+
+		user = &domain.User{
+			ID:       uid,
+			Username: cn,
+			Role:     decision.Role,
+			Roles:    decision.Roles,
+		}
+
+		return nil
+	})
 	if err != nil {
-		logger.Error(fmt.Errorf("LDAP search failed for user %s with filter %s in baseDN %s: %w", userID, searchFilter, baseDN, err))
-		return nil, fmt.Errorf("user search failed")
+		return nil, u.connError(err, fmt.Sprintf("user lookup for %s", userID))
 	}
 
+	return user, nil
+}
+
+func (u *UserRepository) GetUserGroups(ctx context.Context, userID, userPass string) (*domain.UserGroups, error) {
 	if ctx.Err() != nil {
-		logger.Error(fmt.Errorf("context cancelled after LDAP search for user %s: %w", userID, ctx.Err()))
+		logger.Error(fmt.Errorf("context cancelled during group retrieval for user %s: %w", userID, ctx.Err()))
 		return nil, ctx.Err()
 	}
 
-	if len(sr.Entries) == 0 {
-		logger.Warn(fmt.Sprintf("user %s not found in LDAP directory", userID))
-		return nil, fmt.Errorf("user not found")
-	}
+	userDN := fmt.Sprintf("uid=%s,%s", ldap.EscapeDN(userID), userBaseDN(userID))
 
-	if len(sr.Entries) > 1 {
-		logger.Error(fmt.Errorf("multiple LDAP entries (%d) found for user %s - this should not happen", len(sr.Entries), userID))
-		return nil, fmt.Errorf("multiple users found")
-	}
+	var userGroups *domain.UserGroups
 
-	entry := sr.Entries[0]
+	err := u.pool.Do(ctx, func(l *ldap.Conn) error {
+		if err := l.Bind(userDN, userPass); err != nil {
+			logger.Error(fmt.Errorf("failed to bind for group lookup with DN %s: %w", userDN, err))
+			return opError("authentication failed", err)
+		}
 
-	uid := entry.GetAttributeValue("uid")
-	cn := entry.GetAttributeValue("cn")
-	memberOfValues := entry.GetAttributeValues("memberOf")
+		entries, err := u.resolver.Resolve(l, userDN, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP group search failed for user %s: %w", userID, err))
+			return opError("group search failed", err)
+		}
 
-	logger.Debug(fmt.Sprintf("User %s memberOf: %v", userID, memberOfValues))
+		userGroups = &domain.UserGroups{}
 
-	role := u.determineRole(memberOfValues, dn)
+		for _, entry := range entries {
+			cn := entry.GetAttributeValue("cn")
+			description := entry.GetAttributeValue("description")
 
-	logger.Debug(fmt.Sprintf("User %s role determined as: %s", userID, role))
+			if category := u.groups.Apply(userGroups, cn, description); category != "" {
+				logger.Debug(fmt.Sprintf("found %s for user %s: %s", category, userID, cn))
+			}
+		}
 
-	user := &domain.User{
-		ID:       uid,
-		Username: cn,
-		Role:     role,
+		if !strings.HasPrefix(userID, "t") && userGroups.AcademicGroup == "" {
+			logger.Warn(fmt.Sprintf("no academic group found for student %s", userID))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, u.connError(err, fmt.Sprintf("group lookup for %s", userID))
 	}
 
-	return user, nil
+	return userGroups, nil
 }
 
-func (u *UserRepository) GetUserGroups(ctx context.Context, userID, userPass string) (*domain.UserGroups, error) {
+func (u *UserRepository) ChangePassword(ctx context.Context, userID, oldPass, newPass string) error {
 	if ctx.Err() != nil {
-		logger.Error(fmt.Errorf("context cancelled during group retrieval for user %s: %w", userID, ctx.Err()))
-		return nil, ctx.Err()
+		return ctx.Err()
 	}
 
-	l, err := ldap.DialURL(u.cfg.LDAP.URL)
+	err := u.pool.DoOnce(ctx, func(l *ldap.Conn) error {
+		userDN, err := u.findUserDN(l, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to find DN for user %s: %w", userID, err))
+			return opError("user not found", err)
+		}
+
+		if err := l.Bind(userDN, oldPass); err != nil {
+			if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+				logger.Warn(fmt.Sprintf("password change for user %s rejected: wrong current password", userID))
+				return domain.ErrInvalidCredentials
+			}
+			logger.Error(fmt.Errorf("failed to bind user %s for password change: %w", userID, err))
+			return opError("authentication failed", err)
+		}
+
+		if _, err := l.PasswordModify(ldap.NewPasswordModifyRequest(userDN, oldPass, newPass)); err != nil {
+			logger.Warn(fmt.Sprintf("LDAP rejected password change for user %s: %v", userID, err))
+			return opError(fmt.Sprintf("password change rejected: %s", ldapErrorMessage(err)), err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to connect to LDAP server %s for group lookup: %w", u.cfg.LDAP.URL, err))
-		return nil, fmt.Errorf("LDAP connection failed")
+		return u.connError(err, fmt.Sprintf("password change for %s", userID))
 	}
-	defer l.Close()
 
-	var userDN string
-	if !strings.HasPrefix(userID, "t") {
-		userDN = fmt.Sprintf("uid=%s,ou=People,dc=it-college,dc=ru", userID)
-	} else {
-		userDN = fmt.Sprintf("uid=%s,ou=Teachers,dc=it-college,dc=ru", userID)
+	logger.Info(fmt.Sprintf("password changed for user %s", userID))
+	return nil
+}
+
+// ResetPassword sets a new password through the service account, without the
+// current one. The account needs write access to userPassword.
+func (u *UserRepository) ResetPassword(ctx context.Context, userID, newPass string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
 
-	if err := l.Bind(userDN, userPass); err != nil {
-		logger.Error(fmt.Errorf("failed to bind for group lookup with DN %s: %w", userDN, err))
-		return nil, fmt.Errorf("authentication failed")
+	if u.cfg.LDAP.BindDN == "" {
+		return fmt.Errorf("password reset requires a service account")
 	}
 
-	searchFilter := fmt.Sprintf(
-		"(&(|(objectClass=groupOfNames)(objectClass=posixGroup)(objectClass=group))"+
-			"(|(member=%s)(memberUid=%s)))",
-		ldap.EscapeFilter(userDN),
-		ldap.EscapeFilter(userID),
-	)
+	err := u.pool.DoOnce(ctx, func(l *ldap.Conn) error {
+		userDN, err := u.findUserDN(l, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to find DN for user %s: %w", userID, err))
+			return opError("user not found", err)
+		}
 
-	searchRequest := ldap.NewSearchRequest(
-		"ou=Current,dc=it-college,dc=ru",
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		0,
-		0,
-		false,
-		searchFilter,
-		[]string{"cn", "description"},
-		nil,
-	)
+		if err := serviceBind(l, u.cfg.LDAP); err != nil {
+			logger.Error(fmt.Errorf("service bind failed for password reset of %s: %w", userID, err))
+			return opError("service account bind failed", err)
+		}
 
-	sr, err := l.Search(searchRequest)
+		if _, err := l.PasswordModify(ldap.NewPasswordModifyRequest(userDN, "", newPass)); err != nil {
+			logger.Warn(fmt.Sprintf("LDAP rejected password reset for user %s: %v", userID, err))
+			return opError(fmt.Sprintf("password reset rejected: %s", ldapErrorMessage(err)), err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		logger.Error(fmt.Errorf("LDAP group search failed for user %s: %w", userID, err))
-		return nil, fmt.Errorf("group search failed")
+		return u.connError(err, fmt.Sprintf("password reset for %s", userID))
 	}
 
-	userGroups := &domain.UserGroups{}
+	logger.Info(fmt.Sprintf("password reset for user %s", userID))
+	return nil
+}
 
-	for _, entry := range sr.Entries {
-		cn := entry.GetAttributeValue("cn")
-		description := entry.GetAttributeValue("description")
+func (u *UserRepository) GetMail(ctx context.Context, userID string) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
 
-		if description == "Группа" && strings.HasPrefix(cn, "ИТ") {
-			userGroups.AcademicGroup = cn
-			logger.Debug(fmt.Sprintf("found academic group for user %s: %s", userID, cn))
-		}
+	var mail string
 
-		if description == "Профиль" {
-			validProfiles := map[string]bool{
-				"BE": true, "FE": true, "PM": true,
-				"CD": true, "GD": true, "SA": true,
-			}
-			if validProfiles[cn] {
-				userGroups.Profile = cn
-				logger.Debug(fmt.Sprintf("found profile for user %s: %s", userID, cn))
-			}
+	err := u.pool.Do(ctx, func(l *ldap.Conn) error {
+		if err := serviceBind(l, u.cfg.LDAP); err != nil {
+			logger.Error(fmt.Errorf("service bind failed for mail lookup of %s: %w", userID, err))
+			return opError("service account bind failed", err)
 		}
 
-		if description == "Подгруппа" && (cn == "Подгр1" || cn == "Подгр2") {
-			userGroups.Subgroup = cn
-			logger.Debug(fmt.Sprintf("found subgroup for user %s: %s", userID, cn))
+		searchRequest := ldap.NewSearchRequest(
+			userBaseDN(userID),
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			2,
+			5,
+			false,
+			fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(userID)),
+			[]string{"mail"},
+			nil,
+		)
+
+		sr, err := l.Search(searchRequest)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP mail lookup failed for user %s: %w", userID, err))
+			return opError("user search failed", err)
 		}
 
-		if description == "Английский язык подгруппа" {
-			userGroups.EnglishGroup = cn
-			logger.Debug(fmt.Sprintf("found english group for user %s: %s", userID, cn))
+		if len(sr.Entries) != 1 {
+			return fmt.Errorf("user not found")
 		}
-	}
 
-	if !strings.HasPrefix(userID, "t") && userGroups.AcademicGroup == "" {
-		logger.Warn(fmt.Sprintf("no academic group found for student %s", userID))
+		mail = sr.Entries[0].GetAttributeValue("mail")
+		return nil
+	})
+	if err != nil {
+		return "", u.connError(err, fmt.Sprintf("mail lookup for %s", userID))
 	}
 
-	return userGroups, nil
+	return mail, nil
 }
 
 func (u *UserRepository) findUserDN(l *ldap.Conn, userID string) (string, error) {
-	var baseDN string
-	if !strings.HasPrefix(userID, "t") {
-		baseDN = "ou=People,dc=it-college,dc=ru"
-	} else {
-		baseDN = "ou=Teachers,dc=it-college,dc=ru"
-	}
+	baseDN := userBaseDN(userID)
 
 	err := l.UnauthenticatedBind("")
 	if err != nil {
@@ -263,7 +397,7 @@ func (u *UserRepository) findUserDN(l *ldap.Conn, userID string) (string, error)
 
 	if len(sr.Entries) == 0 {
 		logger.Warn(fmt.Sprintf("user %s not found in LDAP", userID))
-		return "", fmt.Errorf("user not found")
+		return "", domain.ErrAccountNotFound
 	}
 
 	if len(sr.Entries) > 1 {
@@ -274,55 +408,331 @@ func (u *UserRepository) findUserDN(l *ldap.Conn, userID string) (string, error)
 	return sr.Entries[0].DN, nil
 }
 
-func (u *UserRepository) determineRole(memberOfValues []string, userDN string) string {
-	userDNLower := strings.ToLower(userDN)
+func (u *UserRepository) ExplainRole(ctx context.Context, userID string) (*domain.RoleDecision, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var decision *domain.RoleDecision
 
-	isTeacherOU := strings.Contains(userDNLower, "ou=teachers")
-	isPeopleOU := strings.Contains(userDNLower, "ou=people,dc=it-college,dc=ru") && !isTeacherOU
+	err := u.pool.Do(ctx, func(l *ldap.Conn) error {
+		if err := serviceBind(l, u.cfg.LDAP); err != nil {
+			logger.Error(fmt.Errorf("service bind failed for role dry-run of %s: %w", userID, err))
+			return opError("service account bind failed", err)
+		}
 
-	logger.Debug(fmt.Sprintf("User DN: %s, isTeacherOU: %v, isPeopleOU: %v", userDN, isTeacherOU, isPeopleOU))
+		searchRequest := ldap.NewSearchRequest(
+			userBaseDN(userID),
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			2,
+			5,
+			false,
+			fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(userID)),
+			append([]string{"uid", "memberOf"}, u.roles.Attributes()...),
+			nil,
+		)
+
+		sr, err := l.Search(searchRequest)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP search failed for role dry-run of %s: %w", userID, err))
+			return opError("user search failed", err)
+		}
 
-	for _, memberOf := range memberOfValues {
-		memberOfLower := strings.ToLower(memberOf)
-		if !strings.Contains(memberOfLower, "ou=current,dc=it-college,dc=ru") {
-			continue
+		if len(sr.Entries) == 0 {
+			return fmt.Errorf("user not found")
 		}
 
-		parts := strings.Split(memberOf, ",")
-		if len(parts) == 0 {
-			continue
+		if len(sr.Entries) > 1 {
+			return fmt.Errorf("multiple users found")
 		}
 
-		cnPart := parts[0]
-		if !strings.HasPrefix(cnPart, "cn=") {
-			continue
+		entry := sr.Entries[0]
+		subject := roleSubject(entry, entry.DN)
+		if err := u.expandMemberOf(l, &subject, userID); err != nil {
+			logger.Error(fmt.Errorf("nested group lookup failed for role dry-run of %s: %w", userID, err))
+			return opError("group search failed", err)
 		}
 
-		cn := strings.TrimPrefix(cnPart, "cn=")
+		decision = u.roles.Evaluate(subject)
+		decision.UserID = userID
+
+		return nil
+	})
+	if err != nil {
+		return nil, u.connError(err, fmt.Sprintf("role dry-run of %s", userID))
+	}
+
+	return decision, nil
+}
+
+// Reload reads the profile of an already signed-in user through the service
+// account. Locked accounts and accounts that lost every group under
+// ou=Current (e.g. students of a past year) are reported as domain errors,
+// as they are at sign-in. Membership decides access on its own: role rules
+// such as teachers-ou still match users who have left ou=Current.
+func (u *UserRepository) Reload(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var profile *domain.UserProfile
+
+	err := u.pool.Do(ctx, func(l *ldap.Conn) error {
+		if err := serviceBind(l, u.cfg.LDAP); err != nil {
+			logger.Error(fmt.Errorf("service bind failed for profile reload of %s: %w", userID, err))
+			return opError("service account bind failed", err)
+		}
+
+		searchRequest := ldap.NewSearchRequest(
+			userBaseDN(userID),
+			ldap.ScopeWholeSubtree,
+			ldap.NeverDerefAliases,
+			2,
+			5,
+			false,
+			fmt.Sprintf("(uid=%s)", ldap.EscapeFilter(userID)),
+			append([]string{"uid", "cn", "memberOf", "pwdAccountLockedTime"}, u.roles.Attributes()...),
+			nil,
+		)
+
+		sr, err := l.Search(searchRequest)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP search failed for profile reload of %s: %w", userID, err))
+			return opError("user search failed", err)
+		}
+
+		if len(sr.Entries) == 0 {
+			return domain.ErrAccountNotFound
+		}
+
+		if len(sr.Entries) > 1 {
+			return fmt.Errorf("multiple users found")
+		}
+
+		entry := sr.Entries[0]
+		if entry.GetAttributeValue("pwdAccountLockedTime") != "" {
+			return domain.ErrAccountLocked
+		}
+
+		groups, err := u.resolver.Resolve(l, entry.DN, userID)
+		if err != nil {
+			logger.Error(fmt.Errorf("LDAP group search failed for profile reload of %s: %w", userID, err))
+			return opError("group search failed", err)
+		}
+
+		if len(groups) == 0 {
+			return domain.ErrAccountInactive
+		}
+
+		subject := roleSubject(entry, entry.DN)
+		u.addMemberOf(&subject, groups)
+		decision := u.roles.Evaluate(subject)
+
+		userGroups := &domain.UserGroups{}
+		for _, g := range groups {
+			u.groups.Apply(userGroups, g.GetAttributeValue("cn"), g.GetAttributeValue("description"))
+		}
+
+		profile = &domain.UserProfile{
+			ID:       entry.GetAttributeValue("uid"),
+			Username: entry.GetAttributeValue("cn"),
+			Role:     decision.Role,
+			Roles:    decision.Roles,
+			Groups:   userGroups,
+			Source:   domain.ProfileSourceLDAP,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, u.connError(err, fmt.Sprintf("profile reload of %s", userID))
+	}
+
+	return profile, nil
+}
+
+// UseMirror lets ReloadFromMirror read profiles from the directory mirror.
+func (u *UserRepository) UseMirror(mirror DirectoryMirrorRepository) {
+	u.mirror = mirror
+}
+
+// ReloadFromMirror builds a profile from the directory mirror the way Reload
+// does from LDAP, for when LDAP is unreachable. The mirror keeps no lock
+// state and only the groups under ou=Current, so role rules see those as
+// memberOf.
+func (u *UserRepository) ReloadFromMirror(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	if u.mirror == nil {
+		return nil, errors.New("directory mirror fallback is disabled")
+	}
 
-		logger.Debug(fmt.Sprintf("Checking group cn=%s from memberOf", cn))
+	user, err := u.mirror.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 
-		if cn == "admin" {
-			logger.Debug("User is member of admin group, role: admin")
-			return "admin"
+	groups, err := u.mirror.GetUserGroups(ctx, user.DN, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parents list nested groups by DN, so the walk up reuses the member
+	// lookup one level at a time.
+	seen := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		seen[strings.ToLower(g.DN)] = true
+	}
+	level := groups
+	for depth := 0; u.resolver.Nested() && depth < u.resolver.maxDepth && len(level) > 0; depth++ {
+		var next []domain.DirectoryGroup
+		for _, g := range level {
+			parents, err := u.mirror.GetUserGroups(ctx, g.DN, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range parents {
+				if key := strings.ToLower(p.DN); !seen[key] {
+					seen[key] = true
+					next = append(next, p)
+				}
+			}
 		}
+		groups = append(groups, next...)
+		level = next
+	}
+
+	subject := RoleSubject{
+		DN: user.DN,
+		Attributes: map[string][]string{
+			"uid": {user.UserID},
+			"cn":  {user.Username},
+		},
+	}
+	if user.Mail != "" {
+		subject.Attributes["mail"] = []string{user.Mail}
+	}
+	for _, g := range groups {
+		subject.MemberOf = append(subject.MemberOf, g.DN)
+	}
 
-		if cn == "teachers" {
-			logger.Debug("User is member of teachers group, role: teacher")
-			return "teacher"
+	if len(groups) == 0 {
+		return nil, domain.ErrAccountInactive
+	}
+	decision := u.roles.Evaluate(subject)
+
+	userGroups := &domain.UserGroups{}
+	for _, g := range groups {
+		u.groups.Apply(userGroups, g.Name, g.Description)
+	}
+
+	return &domain.UserProfile{
+		ID:       user.UserID,
+		Username: user.Username,
+		Role:     decision.Role,
+		Roles:    decision.Roles,
+		Groups:   userGroups,
+		Source:   domain.ProfileSourceLDAP,
+	}, nil
+}
+
+// expandMemberOf adds the groups reached through nesting to the direct
+// memberOf values, so role rules see the full membership.
+func (u *UserRepository) expandMemberOf(l *ldap.Conn, subject *RoleSubject, userID string) error {
+	if !u.resolver.Nested() {
+		return nil
+	}
+
+	groups, err := u.resolver.Resolve(l, subject.DN, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		if !containsFold(subject.MemberOf, g.DN) {
+			subject.MemberOf = append(subject.MemberOf, g.DN)
 		}
+	}
 
-		if isPeopleOU && (cn == "students" || strings.HasPrefix(cn, "ИТ")) {
-			logger.Debug(fmt.Sprintf("User is in ou=people and member of group %s, role: student", cn))
-			return "student"
+	return nil
+}
+
+// addMemberOf adds groups already resolved for the user to the direct
+// memberOf values when nesting is on, like expandMemberOf without the
+// extra lookup.
+func (u *UserRepository) addMemberOf(subject *RoleSubject, groups []*ldap.Entry) {
+	if !u.resolver.Nested() {
+		return
+	}
+
+	for _, g := range groups {
+		if !containsFold(subject.MemberOf, g.DN) {
+			subject.MemberOf = append(subject.MemberOf, g.DN)
 		}
 	}
+}
+
+// connError maps pool exhaustion to the generic connection error returned to
+// callers and passes every other error through.
+func (u *UserRepository) connError(err error, operation string) error {
+	if errors.Is(err, ldappool.ErrUnavailable) {
+		logger.Error(fmt.Errorf("LDAP unavailable during %s: %w", operation, err))
+		return opError("LDAP connection failed", err)
+	}
+	return err
+}
 
-	if isTeacherOU {
-		logger.Debug("User is in ou=Teachers with no group membership, assigning teacher role by OU location")
-		return "teacher"
+// serviceBind binds with the configured service account, falling back to an
+// anonymous bind when none is set.
+func serviceBind(l *ldap.Conn, cfg config.LDAPConfig) error {
+	if cfg.BindDN == "" {
+		return l.UnauthenticatedBind("")
 	}
+	return l.Bind(cfg.BindDN, cfg.BindPassword)
+}
 
-	logger.Warn("Role not determined from groups and DN")
-	return ""
+func userBaseDN(userID string) string {
+	if !strings.HasPrefix(userID, "t") {
+		return "ou=People,dc=it-college,dc=ru"
+	}
+	return "ou=Teachers,dc=it-college,dc=ru"
+}
+
+func roleSubject(entry *ldap.Entry, dn string) RoleSubject {
+	attrs := make(map[string][]string, len(entry.Attributes))
+	for _, attr := range entry.Attributes {
+		attrs[attr.Name] = attr.Values
+	}
+
+	return RoleSubject{
+		DN:         dn,
+		MemberOf:   entry.GetAttributeValues("memberOf"),
+		Attributes: attrs,
+	}
+}
+
+// ldapOpError keeps the caller-facing message while exposing the underlying
+// LDAP error, so the pool can still recognise connection failures.
+type ldapOpError struct {
+	msg string
+	err error
+}
+
+func (e *ldapOpError) Error() string {
+	return e.msg
+}
+
+func (e *ldapOpError) Unwrap() error {
+	return e.err
+}
+
+func opError(msg string, err error) error {
+	return &ldapOpError{msg: msg, err: err}
+}
+
+func ldapErrorMessage(err error) string {
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) && ldapErr.Err != nil {
+		return ldapErr.Err.Error()
+	}
+	return err.Error()
 }
@@ -2,10 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
 )
 
 func TestGetUserGroups(t *testing.T) {
@@ -27,7 +33,7 @@ func TestGetUserGroups(t *testing.T) {
 		},
 	}
 
-	repo := NewUserRepository(cfg)
+	repo := NewUserRepository(cfg, ldappool.New(cfg.LDAP))
 
 	userGroups, err := repo.GetUserGroups(context.Background(), userID, userPass)
 	if err != nil {
@@ -53,3 +59,402 @@ func TestGetUserGroups(t *testing.T) {
 		t.Errorf("expected group %q, got %q", wantEnglishGroup, userGroups.EnglishGroup)
 	}
 }
+
+func newDirectoryRepository(t *testing.T, cfg *config.Config, opts ...ldaptest.Option) (*UserRepository, *ldaptest.Server) {
+	t.Helper()
+
+	srv := ldaptest.Start(t, ldaptest.ITCollege, append(opts, ldaptest.WithManager(ldaptest.ServiceDN))...)
+
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	if cfg.LDAP.URL == "" {
+		cfg.LDAP.URL = srv.URL()
+	}
+	cfg.LDAP.BindDN = ldaptest.ServiceDN
+	cfg.LDAP.BindPassword = ldaptest.ServicePassword
+
+	return NewUserRepository(cfg, ldappool.New(cfg.LDAP)), srv
+}
+
+func TestAuthenticationAgainstDirectory(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+
+	tests := []struct {
+		name       string
+		userID     string
+		password   string
+		wantErr    string
+		wantPolicy string
+	}{
+		{name: "student", userID: "i24s0001", password: "i24s0001-pass"},
+		{name: "teacher", userID: "t001", password: "t001-pass"},
+		{name: "wrong password", userID: "i24s0001", password: "nope", wantErr: domain.ErrInvalidCredentials.Error()},
+		{name: "unknown user", userID: "i99s9999", password: "x", wantErr: "user not found"},
+		{name: "filter injection", userID: "*", password: "x", wantErr: "user not found"},
+		{name: "locked account", userID: "i19s0500", password: "i19s0500-pass", wantPolicy: domain.AccountLocked},
+		{name: "password reset by admin", userID: "i24s0777", password: "i24s0777-pass", wantPolicy: domain.PasswordMustChange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.Authentication(context.Background(), tt.userID, tt.password)
+
+			switch {
+			case tt.wantPolicy != "":
+				var policyErr *domain.PasswordPolicyError
+				if !errors.As(err, &policyErr) {
+					t.Fatalf("expected password policy error, got %v", err)
+				}
+				if policyErr.Code != tt.wantPolicy {
+					t.Errorf("expected policy code %q, got %q", tt.wantPolicy, policyErr.Code)
+				}
+			case tt.wantErr != "":
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetByIDRolePrecedence(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+
+	tests := []struct {
+		userID       string
+		wantUsername string
+		wantRole     string
+	}{
+		{userID: "t003", wantUsername: "Волков Дмитрий Юрьевич", wantRole: "admin"},
+		{userID: "t001", wantUsername: "Смирнов Павел Андреевич", wantRole: "teacher"},
+		{userID: "t002", wantUsername: "Орлова Елена Николаевна", wantRole: "teacher"},
+		{userID: "i22s0042", wantUsername: "Морозова Анна Викторовна", wantRole: "teacher"},
+		{userID: "i24s0001", wantUsername: "Иванов Иван Иванович", wantRole: "student"},
+		{userID: "i23s0101", wantUsername: "Сидоров Алексей Петрович", wantRole: "student"},
+		{userID: "i25s0003", wantUsername: "Кузнецов Фёдор Олегович", wantRole: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			user, err := repo.GetByID(context.Background(), tt.userID, tt.userID+"-pass")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if user.ID != tt.userID {
+				t.Errorf("expected id %q, got %q", tt.userID, user.ID)
+			}
+			if user.Username != tt.wantUsername {
+				t.Errorf("expected username %q, got %q", tt.wantUsername, user.Username)
+			}
+			if user.Role != tt.wantRole {
+				t.Errorf("expected role %q, got %q", tt.wantRole, user.Role)
+			}
+		})
+	}
+}
+
+func TestExplainRoleWithConfiguredRules(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, &config.Config{
+		Roles: config.RolesConfig{
+			Default: "guest",
+			Rules: []config.RoleRule{
+				{Name: "admins", Role: "admin", Group: `^cn=admin,ou=current,`},
+				{Name: "teachers", Role: "teacher", Group: `^cn=teachers,ou=current,`},
+				{Name: "mailbox", Role: "mail-user", Attribute: "mail", Value: `@it-college\.ru$`, Stop: true},
+				{Name: "students", Role: "student", OU: "People"},
+			},
+		},
+	})
+
+	tests := []struct {
+		userID      string
+		wantRoles   []string
+		wantDefault bool
+	}{
+		{userID: "t003", wantRoles: []string{"admin", "teacher"}},
+		{userID: "t001", wantRoles: []string{"teacher", "mail-user"}},
+		{userID: "i24s0001", wantRoles: []string{"mail-user"}},
+		{userID: "i23s0101", wantRoles: []string{"student"}},
+		{userID: "t002", wantRoles: []string{"guest"}, wantDefault: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			decision, err := repo.ExplainRole(context.Background(), tt.userID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(decision.Roles, tt.wantRoles) {
+				t.Errorf("expected roles %v, got %v", tt.wantRoles, decision.Roles)
+			}
+			if decision.Role != tt.wantRoles[0] {
+				t.Errorf("expected primary role %q, got %q", tt.wantRoles[0], decision.Role)
+			}
+			if decision.DefaultApplied != tt.wantDefault {
+				t.Errorf("expected default applied %v, got %v", tt.wantDefault, decision.DefaultApplied)
+			}
+		})
+	}
+}
+
+func TestGetUserGroupsFromDirectory(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+
+	tests := []struct {
+		userID string
+		want   domain.UserGroups
+	}{
+		{
+			userID: "i24s0001",
+			want:   domain.UserGroups{AcademicGroup: "ИТ24-11", Profile: "BE", Subgroup: "Подгр1", EnglishGroup: "B1.21"},
+		},
+		{
+			userID: "i24s0002",
+			want:   domain.UserGroups{AcademicGroup: "ИТ24-11", Profile: "FE", EnglishGroup: "A2.11"},
+		},
+		{
+			// QA is not an allowed profile.
+			userID: "i23s0101",
+			want:   domain.UserGroups{AcademicGroup: "ИТ23-21"},
+		},
+		{
+			// Membership through a posixGroup memberUid.
+			userID: "i25s0003",
+			want:   domain.UserGroups{AcademicGroup: "ИТ25-01"},
+		},
+		{
+			userID: "t001",
+			want:   domain.UserGroups{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			groups, err := repo.GetUserGroups(context.Background(), tt.userID, tt.userID+"-pass")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(*groups, tt.want) {
+				t.Errorf("expected groups %+v, got %+v", tt.want, *groups)
+			}
+		})
+	}
+}
+
+func TestGetUserGroupsExtraCategories(t *testing.T) {
+	categories := append([]config.GroupCategory{}, defaultGroupCategories...)
+	categories = append(categories, config.GroupCategory{Name: "club", Description: "Кружок"})
+
+	repo, _ := newDirectoryRepository(t, &config.Config{
+		Groups: config.GroupsConfig{Categories: categories},
+	})
+
+	groups, err := repo.GetUserGroups(context.Background(), "i24s0001", "i24s0001-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if groups.AcademicGroup != "ИТ24-11" {
+		t.Errorf("expected group %q, got %q", "ИТ24-11", groups.AcademicGroup)
+	}
+
+	want := map[string]string{"club": "Робототехника"}
+	if !reflect.DeepEqual(groups.ExtraGroups, want) {
+		t.Errorf("expected extra groups %v, got %v", want, groups.ExtraGroups)
+	}
+}
+
+func TestGetUserGroupsWrongPassword(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+
+	if _, err := repo.GetUserGroups(context.Background(), "i24s0001", "nope"); err == nil || err.Error() != "authentication failed" {
+		t.Errorf("expected error %q, got %v", "authentication failed", err)
+	}
+}
+
+func TestChangePasswordAgainstDirectory(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+	ctx := context.Background()
+
+	if err := repo.ChangePassword(ctx, "i24s0002", "wrong", "n3w-Secret"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+
+	if err := repo.ChangePassword(ctx, "i24s0002", "i24s0002-pass", "n3w-Secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.Authentication(ctx, "i24s0002", "n3w-Secret"); err != nil {
+		t.Errorf("expected new password to work, got %v", err)
+	}
+	if _, err := repo.Authentication(ctx, "i24s0002", "i24s0002-pass"); err == nil {
+		t.Error("expected old password to be rejected")
+	}
+}
+
+func TestChangePasswordClearsResetFlag(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+	ctx := context.Background()
+
+	if err := repo.ChangePassword(ctx, "i24s0777", "i24s0777-pass", "n3w-Secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.Authentication(ctx, "i24s0777", "n3w-Secret"); err != nil {
+		t.Errorf("expected sign-in after changing the password, got %v", err)
+	}
+}
+
+func TestResetPasswordAgainstDirectory(t *testing.T) {
+	repo, srv := newDirectoryRepository(t, nil)
+	ctx := context.Background()
+
+	if err := repo.ResetPassword(ctx, "t002", "r3set-Secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.Authentication(ctx, "t002", "r3set-Secret"); err != nil {
+		t.Errorf("expected reset password to work, got %v", err)
+	}
+
+	if changed := srv.Attribute("uid=t002,ou=Teachers,dc=it-college,dc=ru", "pwdChangedTime"); len(changed) != 1 {
+		t.Errorf("expected pwdChangedTime to be set, got %v", changed)
+	}
+}
+
+func TestGetMailAgainstDirectory(t *testing.T) {
+	repo, _ := newDirectoryRepository(t, nil)
+
+	tests := []struct {
+		userID   string
+		wantMail string
+		wantErr  bool
+	}{
+		{userID: "i24s0001", wantMail: "i24s0001@it-college.ru"},
+		{userID: "t001", wantMail: "t001@it-college.ru"},
+		{userID: "i23s0101", wantMail: ""},
+		{userID: "i99s9999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			mail, err := repo.GetMail(context.Background(), tt.userID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if mail != tt.wantMail {
+				t.Errorf("expected mail %q, got %q", tt.wantMail, mail)
+			}
+		})
+	}
+}
+
+func TestUserRepositoryFailsOverToHealthyEndpoint(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+
+	repo, _ := newDirectoryRepository(t, &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:         "ldap://127.0.0.1:1, " + srv.URL(),
+			DialTimeout: 200 * time.Millisecond,
+		},
+	})
+
+	user, err := repo.GetByID(context.Background(), "t001", "t001-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if user.Role != "teacher" {
+		t.Errorf("expected role %q, got %q", "teacher", user.Role)
+	}
+}
+
+func TestUserRepositoryUnavailable(t *testing.T) {
+	repo := NewUserRepository(&config.Config{}, ldappool.New(config.LDAPConfig{
+		URL:         "ldap://127.0.0.1:1",
+		DialTimeout: 200 * time.Millisecond,
+	}))
+
+	if _, err := repo.GetByID(context.Background(), "t001", "t001-pass"); err == nil || err.Error() != "LDAP connection failed" {
+		t.Errorf("expected error %q, got %v", "LDAP connection failed", err)
+	}
+}
+
+func TestReloadAgainstDirectory(t *testing.T) {
+	repo, srv := newDirectoryRepository(t, nil)
+
+	profile, err := repo.Reload(context.Background(), "i24s0001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if profile.Username != "Иванов Иван Иванович" || profile.Role != "student" || profile.Source != domain.ProfileSourceLDAP {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+	wantGroups := domain.UserGroups{AcademicGroup: "ИТ24-11", Profile: "BE", Subgroup: "Подгр1", EnglishGroup: "B1.21"}
+	if !reflect.DeepEqual(*profile.Groups, wantGroups) {
+		t.Errorf("expected groups %+v, got %+v", wantGroups, *profile.Groups)
+	}
+
+	if err := srv.Modify("cn=ИТ25-01,ou=Groups,ou=Current,dc=it-college,dc=ru", "memberUid"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		userID string
+		want   error
+	}{
+		{userID: "i19s0500", want: domain.ErrAccountLocked},
+		{userID: "i99s9999", want: domain.ErrAccountNotFound},
+		{userID: "i25s0003", want: domain.ErrAccountInactive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			if _, err := repo.Reload(context.Background(), tt.userID); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestTeacherMatchedOnlyByOU(t *testing.T) {
+	repo, srv := newDirectoryRepository(t, nil)
+	ctx := context.Background()
+
+	// t002 is a teacher through the teachers-ou rule and belongs to a club
+	// under ou=Current, so sign-in and reload both keep access.
+	user, err := repo.GetByID(ctx, "t002", "t002-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Role != "teacher" {
+		t.Errorf("expected role %q, got %q", "teacher", user.Role)
+	}
+	if _, err := repo.Reload(ctx, "t002"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Leaving ou=Current ends access in both paths, whatever role the OU gives.
+	if err := srv.Modify("cn=Робототехника,ou=Groups,ou=Current,dc=it-college,dc=ru", "member", "uid=i24s0001,ou=People,dc=it-college,dc=ru"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, "t002", "t002-pass"); !errors.Is(err, domain.ErrAccountInactive) {
+		t.Errorf("expected %v at sign-in, got %v", domain.ErrAccountInactive, err)
+	}
+	if _, err := repo.Reload(ctx, "t002"); !errors.Is(err, domain.ErrAccountInactive) {
+		t.Errorf("expected %v on reload, got %v", domain.ErrAccountInactive, err)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"net"
 	"net/http"
 
 	"github.com/anton1ks96/college-auth-svc/internal/config"
@@ -14,7 +15,7 @@ type Server struct {
 func NewServer(cfg *config.Config, handler http.Handler) *Server {
 	return &Server{
 		httpServer: &http.Server{
-			Addr:           ":" + cfg.Server.Port,
+			Addr:           net.JoinHostPort(cfg.Server.Host, cfg.Server.Port),
 			Handler:        handler,
 			ReadTimeout:    cfg.Server.ReadTimeout,
 			WriteTimeout:   cfg.Server.WriteTimeout,
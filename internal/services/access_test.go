@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+)
+
+type memoryAccess struct {
+	suspensions map[string]domain.Suspension
+	policy      domain.AccessPolicy
+	otpSteps    map[string]int64
+}
+
+func (m *memoryAccess) Suspend(_ context.Context, suspension *domain.Suspension) error {
+	m.suspensions[suspension.UserID] = *suspension
+	return nil
+}
+
+func (m *memoryAccess) GetSuspension(_ context.Context, userID string) (*domain.Suspension, error) {
+	suspension, ok := m.suspensions[userID]
+	if !ok {
+		return nil, repository.ErrSuspensionNotFound
+	}
+	return &suspension, nil
+}
+
+func (m *memoryAccess) ListSuspensions(context.Context) ([]domain.Suspension, error) {
+	suspensions := []domain.Suspension{}
+	for _, s := range m.suspensions {
+		suspensions = append(suspensions, s)
+	}
+	return suspensions, nil
+}
+
+func (m *memoryAccess) Unsuspend(_ context.Context, userID string) error {
+	if _, ok := m.suspensions[userID]; !ok {
+		return repository.ErrSuspensionNotFound
+	}
+	delete(m.suspensions, userID)
+	return nil
+}
+
+func (m *memoryAccess) GetPolicy(context.Context) (*domain.AccessPolicy, error) {
+	policy := m.policy
+	return &policy, nil
+}
+
+func (m *memoryAccess) SavePolicy(_ context.Context, policy *domain.AccessPolicy) error {
+	m.policy = *policy
+	return nil
+}
+
+func (m *memoryAccess) ClaimOTPStep(_ context.Context, userID string, step int64) error {
+	if step <= m.otpSteps[userID] {
+		return repository.ErrOTPStepUsed
+	}
+	if m.otpSteps == nil {
+		m.otpSteps = map[string]int64{}
+	}
+	m.otpSteps[userID] = step
+	return nil
+}
+
+func TestSuspension(t *testing.T) {
+	access := &memoryAccess{suspensions: map[string]domain.Suspension{}}
+	sessions := &memorySessions{}
+	audit := &memoryAudit{}
+	repos := Repositories{AccessRepo: access, SessionRepo: sessions, AuditRepo: audit}
+	svc := NewAccessService(repos)
+	gate := accessGate{repos: repos}
+	ctx := context.Background()
+	student := &domain.UserExtended{ID: "i24s0001", Role: RoleStudent}
+
+	if err := gate.check(ctx, student, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	invalid := []SuspendInput{
+		{ActorID: "admin", UserID: "i24s0001"},
+		{ActorID: "admin", UserID: "admin", Reason: "test"},
+		{ActorID: "admin", UserID: "i24s0001", Reason: "expelled", Until: &past},
+	}
+	for _, input := range invalid {
+		if _, err := svc.Suspend(ctx, input); !errors.Is(err, ErrInvalidSuspension) {
+			t.Errorf("expected %v for %+v, got %v", ErrInvalidSuspension, input, err)
+		}
+	}
+
+	if _, err := svc.Suspend(ctx, SuspendInput{ActorID: "admin", UserID: "i24s0001", Reason: "expelled"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != "i24s0001" {
+		t.Errorf("expected sessions of i24s0001 to be revoked, got %v", sessions.revoked)
+	}
+	if err := gate.check(ctx, student, nil); !errors.Is(err, domain.ErrAccountSuspended) {
+		t.Errorf("expected %v, got %v", domain.ErrAccountSuspended, err)
+	}
+
+	// A suspension that has run out no longer blocks the user.
+	access.suspensions["i24s0001"] = domain.Suspension{UserID: "i24s0001", Reason: "expelled", Until: &past}
+	if err := gate.check(ctx, student, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := svc.Unsuspend(ctx, "admin", "i24s0001", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := svc.Unsuspend(ctx, "admin", "i24s0001", ""); !errors.Is(err, repository.ErrSuspensionNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrSuspensionNotFound, err)
+	}
+
+	if len(audit.events) != 2 || audit.events[0].Action != domain.AuditSuspend || audit.events[1].Action != domain.AuditUnsuspend {
+		t.Errorf("unexpected audit events %+v", audit.events)
+	}
+}
+
+func TestAccessPolicy(t *testing.T) {
+	access := &memoryAccess{suspensions: map[string]domain.Suspension{}}
+	repos := Repositories{AccessRepo: access, AuditRepo: &memoryAudit{}}
+	svc := NewAccessService(repos)
+	gate := accessGate{repos: repos}
+	ctx := context.Background()
+
+	err := svc.SetPolicy(ctx, "admin", "", &domain.AccessPolicy{
+		AllowRoles:  []string{RoleTeacher},
+		AllowGroups: []string{"ИТ24-11"},
+		Message:     "exam in progress",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policy, err := gate.policy(ctx)
+	if err != nil || policy == nil {
+		t.Fatalf("expected an active policy, got %v, %v", policy, err)
+	}
+
+	tests := []struct {
+		name    string
+		user    domain.UserExtended
+		allowed bool
+	}{
+		{name: "listed role", user: domain.UserExtended{ID: "t001", Role: RoleTeacher}, allowed: true},
+		{name: "admin", user: domain.UserExtended{ID: "t003", Role: RoleTeacher, Roles: []string{RoleTeacher, RoleAdmin}}, allowed: true},
+		{name: "admin in another case", user: domain.UserExtended{ID: "t004", Role: "Admin"}, allowed: true},
+		{name: "listed group", user: domain.UserExtended{ID: "i24s0001", Role: RoleStudent, AcademicGroup: "ИТ24-11"}, allowed: true},
+		{name: "other group", user: domain.UserExtended{ID: "i25s0003", Role: RoleStudent, AcademicGroup: "ИТ25-01"}},
+		{name: "groups not loaded", user: domain.UserExtended{ID: "i24s0002", Role: RoleStudent}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gate.check(ctx, &tt.user, policy)
+			if tt.allowed && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, domain.ErrAccessRestricted) {
+				t.Errorf("expected %v, got %v", domain.ErrAccessRestricted, err)
+			}
+		})
+	}
+
+	past := time.Now().Add(-time.Minute)
+	access.policy.Until = &past
+	if policy, err := gate.policy(ctx); err != nil || policy != nil {
+		t.Errorf("expected an expired policy to open sign-in, got %v, %v", policy, err)
+	}
+
+	if err := svc.SetPolicy(ctx, "admin", "", &domain.AccessPolicy{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policy, err := gate.policy(ctx); err != nil || policy != nil {
+		t.Errorf("expected an empty policy to open sign-in, got %v, %v", policy, err)
+	}
+}
@@ -24,16 +24,22 @@ type AppUserService struct {
 	repos           Repositories
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
-	cfg             *config.App
+	profiles        profileLoader
+	gate            accessGate
+	personal        personalTokens
+	auth            authChain
 }
 
-func NewAppUserService(tm auth.Manager, repos Repositories, accessTTL time.Duration, refreshTTL time.Duration, appCfg *config.App) *AppUserService {
+func NewAppUserService(tm auth.Manager, repos Repositories, accessTTL time.Duration, refreshTTL time.Duration, profileCfg *config.ProfileConfig, chain authChain) *AppUserService {
 	return &AppUserService{
 		tokenManager:    &tm,
 		repos:           repos,
 		accessTokenTTL:  accessTTL,
 		refreshTokenTTL: refreshTTL,
-		cfg:             appCfg,
+		profiles:        newProfileLoader(repos, profileCfg),
+		gate:            accessGate{repos: repos},
+		personal:        newPersonalTokens(&tm, repos, profileCfg),
+		auth:            chain,
 	}
 }
 
@@ -47,57 +53,18 @@ func (a *AppUserService) SignIn(ctx context.Context, input SignInInput) (Tokens,
 		return Tokens{}, nil, fmt.Errorf("empty login credentials")
 	}
 
-	var user *domain.User
-	var userExtended *domain.UserExtended
-	var err error
-
-	if a.cfg.Test {
-		userExtended = &domain.UserExtended{
-			ID:            input.UserID,
-			Username:      "i24s0291",
-			Role:          "student",
-			AcademicGroup: "ИТ25-11",
-			Profile:       "BE",
-			Subgroup:      "Подгр1",
-			EnglishGroup:  "B1.21",
-		}
-		user = &domain.User{
-			ID:       userExtended.ID,
-			Username: userExtended.Username,
-			Role:     userExtended.Role,
-		}
-	} else {
-		if err := a.repos.UserRepo.Authentication(ctx, input.UserID, input.Password); err != nil {
-			logger.Error(fmt.Errorf("authentication failed for user %s: %w", input.UserID, err))
-			return Tokens{}, nil, fmt.Errorf("authentication failed: %w", err)
-		}
-
-		user, err = a.repos.UserRepo.GetByID(ctx, input.UserID, input.Password)
-		if err != nil {
-			logger.Error(fmt.Errorf("find user data failed for user %s: %w", input.UserID, err))
-			return Tokens{}, nil, fmt.Errorf("find user data failed: %w", err)
-		}
+	policy, err := a.gate.policy(ctx)
+	if err != nil {
+		return Tokens{}, nil, err
+	}
 
-		var userGroups *domain.UserGroups
-		if user.Role != "teacher" && user.Role != "admin" {
-			userGroups, err = a.repos.UserRepo.GetUserGroups(ctx, input.UserID, input.Password)
-			if err != nil {
-				logger.Warn(fmt.Sprintf("failed to get groups for user %s: %v", input.UserID, err))
-				userGroups = &domain.UserGroups{}
-			}
-		} else {
-			userGroups = &domain.UserGroups{}
-		}
+	userExtended, source, err := a.auth.Authenticate(ctx, input, true)
+	if err != nil {
+		return Tokens{}, nil, err
+	}
 
-		userExtended = &domain.UserExtended{
-			ID:            user.ID,
-			Username:      user.Username,
-			Role:          user.Role,
-			AcademicGroup: userGroups.AcademicGroup,
-			Profile:       userGroups.Profile,
-			Subgroup:      userGroups.Subgroup,
-			EnglishGroup:  userGroups.EnglishGroup,
-		}
+	if err := a.gate.check(ctx, userExtended, policy); err != nil {
+		return Tokens{}, nil, err
 	}
 
 	tokens, err := a.generateTokens(userExtended)
@@ -112,17 +79,29 @@ func (a *AppUserService) SignIn(ctx context.Context, input SignInInput) (Tokens,
 		return Tokens{}, nil, fmt.Errorf("failed to extract jti: %w", err)
 	}
 
+	if err := a.repos.ProfileRepo.RecordSignIn(ctx, &domain.UserProfile{
+		ID:       userExtended.ID,
+		Username: userExtended.Username,
+		Role:     userExtended.Role,
+		Roles:    userExtended.Roles,
+		Groups: &domain.UserGroups{
+			AcademicGroup: userExtended.AcademicGroup,
+			Profile:       userExtended.Profile,
+			Subgroup:      userExtended.Subgroup,
+			EnglishGroup:  userExtended.EnglishGroup,
+			ExtraGroups:   userExtended.ExtraGroups,
+		},
+		Source: source,
+	}); err != nil {
+		logger.Error(fmt.Errorf("failed to save profile for user %s: %w", input.UserID, err))
+		return Tokens{}, nil, fmt.Errorf("failed to save user profile: %w", err)
+	}
+
 	session := domain.RefreshSession{
-		JTI:           jti,
-		UserID:        input.UserID,
-		Username:      userExtended.Username,
-		Role:          userExtended.Role,
-		AcademicGroup: userExtended.AcademicGroup,
-		Profile:       userExtended.Profile,
-		Subgroup:      userExtended.Subgroup,
-		EnglishGroup:  userExtended.EnglishGroup,
-		ExpiresAt:     time.Now().Add(a.refreshTokenTTL),
-		CreatedAt:     time.Now(),
+		JTI:       jti,
+		UserID:    userExtended.ID,
+		ExpiresAt: time.Now().Add(a.refreshTokenTTL),
+		CreatedAt: time.Now(),
 	}
 
 	if err := a.repos.SessionRepo.SaveRefreshToken(ctx, &session); err != nil {
@@ -196,10 +175,13 @@ func (a *AppUserService) RefreshToken(ctx context.Context, refreshToken string)
 		return "", fmt.Errorf("token not found or already used")
 	}
 
-	userExtended, err := a.repos.SessionRepo.GetExtendedUserByID(ctx, userID)
+	profile, err := a.profiles.load(ctx, userID, true)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to get extended user data from session for ID %s: %w", userID, err))
-		return "", fmt.Errorf("failed to get user data: %w", err)
+		return "", err
+	}
+
+	if err := a.gate.checkProfile(ctx, profile); err != nil {
+		return "", err
 	}
 
 	newRefreshToken, err := a.tokenManager.NewRefreshToken(userID)
@@ -215,16 +197,10 @@ func (a *AppUserService) RefreshToken(ctx context.Context, refreshToken string)
 	}
 
 	newSession := domain.RefreshSession{
-		JTI:           newJti,
-		UserID:        userID,
-		Username:      userExtended.Username,
-		Role:          userExtended.Role,
-		AcademicGroup: userExtended.AcademicGroup,
-		Profile:       userExtended.Profile,
-		Subgroup:      userExtended.Subgroup,
-		EnglishGroup:  userExtended.EnglishGroup,
-		ExpiresAt:     time.Now().Add(a.refreshTokenTTL),
-		CreatedAt:     time.Now(),
+		JTI:       newJti,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(a.refreshTokenTTL),
+		CreatedAt: time.Now(),
 	}
 
 	if err := a.repos.SessionRepo.ReplaceRefreshToken(ctx, oldJti, &newSession); err != nil {
@@ -245,16 +221,18 @@ func (a *AppUserService) ValidateAccessToken(ctx context.Context, accessToken st
 		return nil, fmt.Errorf("empty access token")
 	}
 
-	err := a.tokenManager.Validate(accessToken)
-	if err != nil {
-		logger.Error(fmt.Errorf("token validation failed: %w", err))
-		return nil, fmt.Errorf("invalid token")
+	if auth.IsPersonalToken(accessToken) {
+		user, err := a.personal.authenticate(ctx, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
 	}
 
-	claims, err := a.tokenManager.GetAllClaims(accessToken)
+	claims, err := a.tokenManager.ValidateAccessToken(accessToken)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to extract claims from token: %w", err))
-		return nil, fmt.Errorf("invalid token claims")
+		logger.Error(fmt.Errorf("token validation failed: %w", err))
+		return nil, fmt.Errorf("invalid token")
 	}
 
 	userID, ok := claims["user_id"].(string)
@@ -273,10 +251,13 @@ func (a *AppUserService) ValidateAccessToken(ctx context.Context, accessToken st
 		ID:            userID,
 		Username:      username,
 		Role:          role,
+		Roles:         auth.StringSliceClaim(claims, "roles"),
 		AcademicGroup: academicGroup,
 		Profile:       profile,
 		Subgroup:      subgroup,
 		EnglishGroup:  englishGroup,
+		ExtraGroups:   auth.StringMapClaim(claims, "extra_groups"),
+		Impersonator:  auth.ActorClaim(claims),
 	}
 
 	return userExtended, nil
@@ -320,21 +301,17 @@ func (a *AppUserService) GetAccessToken(ctx context.Context, refreshToken string
 		return "", nil, fmt.Errorf("token not found or already used")
 	}
 
-	userExtended, err := a.repos.SessionRepo.GetExtendedUserByID(ctx, userID)
+	profile, err := a.profiles.load(ctx, userID, true)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to get extended user data from session for ID %s: %w", userID, err))
-		return "", nil, fmt.Errorf("failed to get user data: %w", err)
-	}
-
-	accessToken, err := a.tokenManager.NewAccessToken(
-		userExtended.ID,
-		userExtended.Username,
-		userExtended.Role,
-		userExtended.AcademicGroup,
-		userExtended.Profile,
-		userExtended.Subgroup,
-		userExtended.EnglishGroup,
-	)
+		return "", nil, err
+	}
+
+	if err := a.gate.checkProfile(ctx, profile); err != nil {
+		return "", nil, err
+	}
+	userExtended := profile.Extended()
+
+	accessToken, err := a.tokenManager.NewAccessToken(accessClaims(userExtended))
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to generate access token for user %s: %w", userExtended.ID, err))
 		return "", nil, fmt.Errorf("failed to generate access token: %w", err)
@@ -344,15 +321,7 @@ func (a *AppUserService) GetAccessToken(ctx context.Context, refreshToken string
 }
 
 func (a *AppUserService) generateTokens(userExtended *domain.UserExtended) (Tokens, error) {
-	newAccess, err := a.tokenManager.NewAccessToken(
-		userExtended.ID,
-		userExtended.Username,
-		userExtended.Role,
-		userExtended.AcademicGroup,
-		userExtended.Profile,
-		userExtended.Subgroup,
-		userExtended.EnglishGroup,
-	)
+	newAccess, err := a.tokenManager.NewAccessToken(accessClaims(userExtended))
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to generate access token for user %s: %w", userExtended.ID, err))
 		return Tokens{}, fmt.Errorf("failed to generate access token: %w", err)
@@ -369,3 +338,17 @@ func (a *AppUserService) generateTokens(userExtended *domain.UserExtended) (Toke
 		RefreshToken: newRefresh,
 	}, nil
 }
+
+func accessClaims(user *domain.UserExtended) auth.AccessTokenClaims {
+	return auth.AccessTokenClaims{
+		UserID:        user.ID,
+		Username:      user.Username,
+		Role:          user.Role,
+		Roles:         user.Roles,
+		AcademicGroup: user.AcademicGroup,
+		Profile:       user.Profile,
+		Subgroup:      user.Subgroup,
+		EnglishGroup:  user.EnglishGroup,
+		ExtraGroups:   user.ExtraGroups,
+	}
+}
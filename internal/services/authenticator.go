@@ -0,0 +1,398 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/passhash"
+	"github.com/anton1ks96/college-auth-svc/pkg/totp"
+)
+
+// Identity providers that can be listed in the auth.providers setting.
+const (
+	AuthSourceBuiltin = "builtin"
+	AuthSourceFixture = "fixture"
+	AuthSourceLDAP    = "ldap"
+	AuthSourceLocal   = "local"
+)
+
+// ErrUnknownUser means a source has no such user, so the next one is asked.
+var ErrUnknownUser = errors.New("unknown user")
+
+// Authenticator checks credentials against one identity source. withGroups
+// asks for the classified groups of students as well, which may cost the
+// source another lookup.
+type Authenticator interface {
+	Source() string
+	Authenticate(ctx context.Context, input SignInInput, withGroups bool) (*domain.UserExtended, error)
+}
+
+// authProviderFactory builds a provider. A nil Authenticator means the
+// provider is switched off and is left out of the chain.
+type authProviderFactory func(repos Repositories, cfg *config.Config) (Authenticator, error)
+
+// authProviders maps provider types to their constructors. A new identity
+// source only needs an Authenticator and an entry here.
+var authProviders = map[string]authProviderFactory{
+	AuthSourceBuiltin: newBuiltinAuthenticator,
+	AuthSourceFixture: newFixtureAuthenticator,
+	AuthSourceLDAP: func(repos Repositories, _ *config.Config) (Authenticator, error) {
+		return ldapAuthenticator{repos: repos}, nil
+	},
+	AuthSourceLocal: func(repos Repositories, _ *config.Config) (Authenticator, error) {
+		return localAuthenticator{repos: repos}, nil
+	},
+}
+
+// authChain asks each authenticator in turn until one knows the user.
+type authChain []Authenticator
+
+// defaultAuthProviders is the chain used when auth.providers is empty.
+// LDAP comes before local accounts so a local ID can never shadow a directory
+// user added after it; while LDAP is down the chain skips it.
+var defaultAuthProviders = []config.AuthProvider{{Type: AuthSourceBuiltin}, {Type: AuthSourceLDAP}, {Type: AuthSourceLocal}}
+
+// newAuthChain builds the configured providers in order. In test mode the
+// fixtures file stands in for LDAP, so ldap providers become fixture ones.
+func newAuthChain(repos Repositories, cfg *config.Config) (authChain, error) {
+	providers := cfg.Auth.Providers
+	if len(providers) == 0 {
+		providers = defaultAuthProviders
+	}
+
+	var chain authChain
+	for _, provider := range providers {
+		if cfg.App.Test && provider.Type == AuthSourceLDAP {
+			provider.Type = AuthSourceFixture
+		}
+
+		factory, ok := authProviders[provider.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown identity provider %q", provider.Type)
+		}
+
+		a, err := factory(repos, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("identity provider %s: %w", provider.Type, err)
+		}
+		if a == nil {
+			continue
+		}
+
+		if provider.Pattern != "" {
+			pattern, err := regexp.Compile("^(?:" + provider.Pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("identity provider %s: invalid pattern: %w", provider.Type, err)
+			}
+			a = matchingAuthenticator{Authenticator: a, pattern: pattern}
+		}
+
+		chain = append(chain, a)
+	}
+
+	// Only a builtin provider at the end of the chain knows that a wrong
+	// password will not be accepted by anyone else; the others say in the
+	// audit log that they passed the attempt on.
+	if len(chain) > 0 {
+		last := chain[len(chain)-1]
+		if m, ok := last.(matchingAuthenticator); ok {
+			last = m.Authenticator
+		}
+		if b, ok := last.(*builtinAuthenticator); ok {
+			b.last = true
+		}
+	}
+
+	return chain, nil
+}
+
+// Authenticate returns the user and the source that recognised them.
+// ErrUnknownUser moves on to the next source, and so does an unreachable
+// LDAP, so local accounts keep working through an outage; if no later source
+// knows the user, the outage is reported rather than invalid credentials. A
+// wrong password ends the sign-in.
+func (c authChain) Authenticate(ctx context.Context, input SignInInput, withGroups bool) (*domain.UserExtended, string, error) {
+	var unavailable error
+	for _, a := range c {
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+
+		user, err := a.Authenticate(ctx, input, withGroups)
+		if errors.Is(err, ErrUnknownUser) {
+			continue
+		}
+		if errors.Is(err, ldappool.ErrUnavailable) {
+			logger.Warn(fmt.Sprintf("%s unavailable for user %s, asking the next identity source", a.Source(), input.UserID))
+			if unavailable == nil {
+				unavailable = err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		return user, a.Source(), nil
+	}
+
+	if unavailable != nil {
+		return nil, "", unavailable
+	}
+
+	logger.Warn(fmt.Sprintf("no identity source knows user %s", input.UserID))
+	return nil, "", fmt.Errorf("authentication failed: %w", domain.ErrInvalidCredentials)
+}
+
+// matchingAuthenticator limits a provider to the user IDs matching its
+// configured pattern.
+type matchingAuthenticator struct {
+	Authenticator
+	pattern *regexp.Regexp
+}
+
+func (m matchingAuthenticator) Authenticate(ctx context.Context, input SignInInput, withGroups bool) (*domain.UserExtended, error) {
+	if !m.pattern.MatchString(input.UserID) {
+		return nil, ErrUnknownUser
+	}
+	return m.Authenticator.Authenticate(ctx, input, withGroups)
+}
+
+// builtinAuthenticator signs in the bootstrap "admin" with the password hash
+// and optional TOTP secret from the environment. Every attempt is audited.
+type builtinAuthenticator struct {
+	repos        Repositories
+	passwordHash string
+	totpKey      []byte
+	last         bool // No provider follows, so a wrong password ends here
+}
+
+func newBuiltinAuthenticator(repos Repositories, cfg *config.Config) (Authenticator, error) {
+	if !cfg.Bootstrap.Enabled {
+		logger.Info("Bootstrap admin is disabled")
+		return nil, nil
+	}
+	if cfg.Bootstrap.PasswordHash == "" {
+		logger.Warn("Bootstrap admin is enabled but BOOTSTRAP_ADMIN_HASH is not set, skipping it")
+		return nil, nil
+	}
+
+	// Verifying any password checks the hash format up front.
+	if _, err := passhash.Verify("", cfg.Bootstrap.PasswordHash); err != nil {
+		return nil, fmt.Errorf("invalid BOOTSTRAP_ADMIN_HASH: %w", err)
+	}
+
+	b := &builtinAuthenticator{
+		repos:        repos,
+		passwordHash: cfg.Bootstrap.PasswordHash,
+	}
+	if cfg.Bootstrap.TOTPSecret != "" {
+		key, err := totp.DecodeSecret(cfg.Bootstrap.TOTPSecret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOTSTRAP_ADMIN_TOTP: %w", err)
+		}
+		b.totpKey = key
+	}
+
+	logger.Warn(fmt.Sprintf("Bootstrap admin is enabled (TOTP: %t); disable it once LDAP admins exist", b.totpKey != nil))
+	return b, nil
+}
+
+func (*builtinAuthenticator) Source() string {
+	return domain.ProfileSourceBuiltin
+}
+
+// Authenticate leaves a wrong password to the next provider, so an LDAP
+// account named admin keeps working. A wrong one-time code ends the sign-in.
+func (b *builtinAuthenticator) Authenticate(ctx context.Context, input SignInInput, _ bool) (*domain.UserExtended, error) {
+	if input.UserID != "admin" {
+		return nil, ErrUnknownUser
+	}
+
+	ok, err := passhash.Verify(input.Password, b.passwordHash)
+	if err != nil || !ok {
+		details := "wrong password, passed on to the next provider"
+		if b.last {
+			details = "wrong password"
+		}
+		b.audit(ctx, domain.AuditBootstrapSignInFailed, input, details)
+		return nil, ErrUnknownUser
+	}
+
+	if b.totpKey != nil {
+		ok, err := b.checkOTP(ctx, input.OTP)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			b.audit(ctx, domain.AuditBootstrapSignInFailed, input, "wrong or reused one-time code")
+			return nil, fmt.Errorf("authentication failed: %w", domain.ErrInvalidCredentials)
+		}
+	}
+
+	b.audit(ctx, domain.AuditBootstrapSignIn, input, "")
+	return &domain.UserExtended{
+		ID:       "admin",
+		Username: "Администратор",
+		Role:     "admin",
+		Roles:    []string{"admin"},
+	}, nil
+}
+
+// checkOTP accepts a valid code whose step is newer than any used before.
+// The step is claimed in Mongo, so a code works only once on every replica.
+func (b *builtinAuthenticator) checkOTP(ctx context.Context, code string) (bool, error) {
+	step, ok := totp.Validate(b.totpKey, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	if err := b.repos.AccessRepo.ClaimOTPStep(ctx, "admin", step); err != nil {
+		if errors.Is(err, repository.ErrOTPStepUsed) {
+			return false, nil
+		}
+		logger.Error(fmt.Errorf("failed to check one-time code of admin: %w", err))
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *builtinAuthenticator) audit(ctx context.Context, action string, input SignInInput, details string) {
+	logger.Warn(fmt.Sprintf("%s by admin from %s %s", action, input.ClientIP, details))
+
+	event := &domain.AuditEvent{
+		Action:    action,
+		Actor:     "admin",
+		ClientIP:  input.ClientIP,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if err := b.repos.AuditRepo.Record(ctx, event); err != nil {
+		logger.Error(fmt.Errorf("failed to audit bootstrap sign-in: %w", err))
+	}
+}
+
+// fixtureAuthenticator signs in the users of the test mode fixtures file.
+type fixtureAuthenticator struct {
+	repos Repositories
+}
+
+func newFixtureAuthenticator(repos Repositories, cfg *config.Config) (Authenticator, error) {
+	if !cfg.App.Test || repos.FixtureRepo == nil {
+		return nil, errors.New("only available in test mode")
+	}
+	return fixtureAuthenticator{repos: repos}, nil
+}
+
+func (fixtureAuthenticator) Source() string {
+	return domain.ProfileSourceFixture
+}
+
+func (f fixtureAuthenticator) Authenticate(ctx context.Context, input SignInInput, _ bool) (*domain.UserExtended, error) {
+	user, err := f.repos.FixtureRepo.Authenticate(ctx, input.UserID, input.Password)
+	if errors.Is(err, repository.ErrFixtureUserNotFound) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		logger.Warn(fmt.Sprintf("fixture authentication failed for user %s: %v", input.UserID, err))
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	return user, nil
+}
+
+type ldapAuthenticator struct {
+	repos Repositories
+}
+
+func (ldapAuthenticator) Source() string {
+	return domain.ProfileSourceLDAP
+}
+
+func (l ldapAuthenticator) Authenticate(ctx context.Context, input SignInInput, withGroups bool) (*domain.UserExtended, error) {
+	passwordPolicy, err := l.repos.UserRepo.Authentication(ctx, input.UserID, input.Password)
+	if errors.Is(err, domain.ErrAccountNotFound) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("authentication failed for user %s: %w", input.UserID, err))
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	user, err := l.repos.UserRepo.GetByID(ctx, input.UserID, input.Password)
+	if err != nil {
+		logger.Error(fmt.Errorf("find user data failed for user %s: %w", input.UserID, err))
+		return nil, fmt.Errorf("find user data failed: %w", err)
+	}
+
+	userGroups := &domain.UserGroups{}
+	if withGroups && user.Role != "teacher" && user.Role != "admin" {
+		userGroups, err = l.repos.UserRepo.GetUserGroups(ctx, input.UserID, input.Password)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("failed to get groups for user %s: %v", input.UserID, err))
+			userGroups = &domain.UserGroups{}
+		}
+	}
+
+	return &domain.UserExtended{
+		ID:            user.ID,
+		Username:      user.Username,
+		Role:          user.Role,
+		Roles:         user.Roles,
+		AcademicGroup: userGroups.AcademicGroup,
+		Profile:       userGroups.Profile,
+		Subgroup:      userGroups.Subgroup,
+		EnglishGroup:  userGroups.EnglishGroup,
+		ExtraGroups:   userGroups.ExtraGroups,
+
+		PasswordPolicy: passwordPolicy,
+	}, nil
+}
+
+type localAuthenticator struct {
+	repos Repositories
+}
+
+func (localAuthenticator) Source() string {
+	return domain.ProfileSourceLocal
+}
+
+func (l localAuthenticator) Authenticate(ctx context.Context, input SignInInput, _ bool) (*domain.UserExtended, error) {
+	account, err := l.repos.LocalAccountRepo.Get(ctx, input.UserID)
+	if errors.Is(err, repository.ErrLocalAccountNotFound) {
+		return nil, ErrUnknownUser
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get local account %s: %w", input.UserID, err))
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	ok, err := passhash.Verify(input.Password, account.PasswordHash)
+	if err != nil {
+		logger.Error(fmt.Errorf("unusable password hash for local account %s: %w", input.UserID, err))
+		return nil, fmt.Errorf("authentication failed: %w", domain.ErrInvalidCredentials)
+	}
+	if !ok {
+		logger.Warn(fmt.Sprintf("local authentication failed for user %s", input.UserID))
+		return nil, fmt.Errorf("authentication failed: %w", domain.ErrInvalidCredentials)
+	}
+
+	if account.Expired(time.Now()) {
+		logger.Warn(fmt.Sprintf("sign-in attempt by expired local account %s", input.UserID))
+		return nil, fmt.Errorf("authentication failed: %w", domain.ErrAccountExpired)
+	}
+
+	return account.Extended(), nil
+}
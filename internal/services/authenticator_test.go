@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+	"github.com/anton1ks96/college-auth-svc/pkg/passhash"
+	"github.com/anton1ks96/college-auth-svc/pkg/totp"
+)
+
+func TestAuthChain(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	repos, accounts, _ := newLocalAccountRepos(t, srv.URL())
+
+	hash, err := passhash.Hash("guest-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	accounts["guest.lecturer"] = domain.LocalAccount{ID: "guest.lecturer", Username: "Гость", PasswordHash: hash, Role: RoleTeacher}
+	accounts["proctor"] = domain.LocalAccount{ID: "proctor", Username: "Проктор", PasswordHash: hash, Role: RoleTeacher, ExpiresAt: &past}
+
+	chain, err := newAuthChain(repos, &config.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		input      SignInInput
+		wantSource string
+		wantErr    error
+		wantFail   bool
+	}{
+		{name: "local account", input: SignInInput{UserID: "guest.lecturer", Password: "guest-pass"}, wantSource: AuthSourceLocal},
+		{name: "directory user", input: SignInInput{UserID: "i24s0001", Password: "i24s0001-pass"}, wantSource: AuthSourceLDAP},
+		{name: "wrong local password", input: SignInInput{UserID: "guest.lecturer", Password: "i24s0001-pass"}, wantErr: domain.ErrInvalidCredentials},
+		{name: "expired account", input: SignInInput{UserID: "proctor", Password: "guest-pass"}, wantErr: domain.ErrAccountExpired},
+		{name: "unknown everywhere", input: SignInInput{UserID: "nobody", Password: "guest-pass"}, wantErr: domain.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, source, err := chain.Authenticate(context.Background(), tt.input, true)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatal("expected sign-in to fail")
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if source != tt.wantSource {
+				t.Errorf("expected source %q, got %q", tt.wantSource, source)
+			}
+			if user.ID != tt.input.UserID {
+				t.Errorf("expected user %q, got %q", tt.input.UserID, user.ID)
+			}
+		})
+	}
+
+	// Users missing from LDAP fall through to the providers after it.
+	ldapFirst, err := newAuthChain(repos, &config.Config{Auth: config.AuthConfig{Providers: []config.AuthProvider{{Type: AuthSourceLDAP}, {Type: AuthSourceLocal}}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, source, err := ldapFirst.Authenticate(context.Background(), SignInInput{UserID: "guest.lecturer", Password: "guest-pass"}, true); err != nil || source != AuthSourceLocal {
+		t.Errorf("expected a local sign-in, got %q and %v", source, err)
+	}
+
+	if _, err := newAuthChain(repos, &config.Config{Auth: config.AuthConfig{Providers: []config.AuthProvider{{Type: "kerberos"}}}}); err == nil {
+		t.Error("expected an error for an unknown identity source")
+	}
+}
+
+func TestAuthChainSkipsUnavailableLDAP(t *testing.T) {
+	repos, accounts, _ := newLocalAccountRepos(t, "ldap://127.0.0.1:1")
+
+	hash, err := passhash.Hash("guest-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	accounts["guest.lecturer"] = domain.LocalAccount{ID: "guest.lecturer", Username: "Гость", PasswordHash: hash, Role: RoleTeacher}
+
+	chain, err := newAuthChain(repos, &config.Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	_, source, err := chain.Authenticate(ctx, SignInInput{UserID: "guest.lecturer", Password: "guest-pass"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != AuthSourceLocal {
+		t.Errorf("expected source %q, got %q", AuthSourceLocal, source)
+	}
+
+	// Directory users hear about the outage, not about a wrong password.
+	if _, _, err := chain.Authenticate(ctx, SignInInput{UserID: "i24s0001", Password: "i24s0001-pass"}, false); !errors.Is(err, ldappool.ErrUnavailable) {
+		t.Errorf("expected %v, got %v", ldappool.ErrUnavailable, err)
+	}
+}
+
+func TestAuthChainProviders(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	repos, _, _ := newLocalAccountRepos(t, srv.URL())
+	repos.AuditRepo = &memoryAudit{}
+
+	hash, err := passhash.Hash("bootstrap-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &config.Config{
+		Auth: config.AuthConfig{Providers: []config.AuthProvider{
+			{Type: AuthSourceBuiltin, Pattern: "admin"},
+			{Type: AuthSourceLDAP, Pattern: `t\d+`},
+		}},
+		Bootstrap: config.BootstrapConfig{Enabled: true, PasswordHash: hash},
+	}
+	chain, err := newAuthChain(repos, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	user, source, err := chain.Authenticate(context.Background(), SignInInput{UserID: "admin", Password: "bootstrap-pass"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != AuthSourceBuiltin || user.Role != RoleAdmin {
+		t.Errorf("expected builtin admin, got %q from %q", user.Role, source)
+	}
+
+	// i24s0001 exists in LDAP but does not match the LDAP provider pattern.
+	if _, _, err := chain.Authenticate(context.Background(), SignInInput{UserID: "i24s0001", Password: "i24s0001-pass"}, false); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+
+	fixture := &config.Config{Auth: config.AuthConfig{Providers: []config.AuthProvider{{Type: AuthSourceFixture}}}}
+	if _, err := newAuthChain(repos, fixture); err == nil {
+		t.Error("expected the fixture provider to be refused outside test mode")
+	}
+
+	// In test mode the ldap provider signs in fixture users instead.
+	repos.FixtureRepo, err = repository.NewFixtureRepository(&config.Config{App: config.App{Fixtures: "../../configs/fixtures.yml"}})
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	cfg.App.Test = true
+	chain, err = newAuthChain(repos, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	user, source, err = chain.Authenticate(context.Background(), SignInInput{UserID: "t002", Password: "teacher"}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if source != AuthSourceFixture || user.Role != RoleTeacher {
+		t.Errorf("expected fixture teacher, got %q from %q", user.Role, source)
+	}
+	if _, _, err := chain.Authenticate(context.Background(), SignInInput{UserID: "t002", Password: "anything"}, true); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+
+	invalid := &config.Config{Auth: config.AuthConfig{Providers: []config.AuthProvider{{Type: AuthSourceLDAP, Pattern: "("}}}}
+	if _, err := newAuthChain(repos, invalid); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+type memoryAudit struct {
+	events []domain.AuditEvent
+}
+
+func (m *memoryAudit) Record(_ context.Context, event *domain.AuditEvent) error {
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	audit := &memoryAudit{}
+	repos := Repositories{AuditRepo: audit, AccessRepo: &memoryAccess{}}
+
+	hash, err := passhash.Hash("bootstrap-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	secret, err := totp.NewSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key, _ := totp.DecodeSecret(secret)
+
+	providers := config.AuthConfig{Providers: []config.AuthProvider{{Type: AuthSourceBuiltin}}}
+	chain, err := newAuthChain(repos, &config.Config{
+		Auth:      providers,
+		Bootstrap: config.BootstrapConfig{Enabled: true, PasswordHash: hash, TOTPSecret: secret},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	input := SignInInput{UserID: "admin", Password: "wrong-pass", ClientIP: "10.0.0.7"}
+	if _, _, err := chain.Authenticate(ctx, input, false); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+
+	input.Password = "bootstrap-pass"
+	if _, _, err := chain.Authenticate(ctx, input, false); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected the missing one-time code to fail, got %v", err)
+	}
+
+	input.OTP = totp.Code(key, time.Now())
+	if _, _, err := chain.Authenticate(ctx, input, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := chain.Authenticate(ctx, input, false); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected a reused one-time code to fail, got %v", err)
+	}
+
+	// Another replica shares the used steps.
+	other, err := newAuthChain(repos, &config.Config{
+		Auth:      providers,
+		Bootstrap: config.BootstrapConfig{Enabled: true, PasswordHash: hash, TOTPSecret: secret},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := other.Authenticate(ctx, input, false); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected a code used on another replica to fail, got %v", err)
+	}
+
+	var actions []string
+	for _, e := range audit.events {
+		actions = append(actions, e.Action)
+		if e.ClientIP != "10.0.0.7" {
+			t.Errorf("expected client ip %q, got %q", "10.0.0.7", e.ClientIP)
+		}
+	}
+	want := []string{
+		domain.AuditBootstrapSignInFailed,
+		domain.AuditBootstrapSignInFailed,
+		domain.AuditBootstrapSignIn,
+		domain.AuditBootstrapSignInFailed,
+		domain.AuditBootstrapSignInFailed,
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("expected audit %v, got %v", want, actions)
+	}
+
+	// A provider after builtin may still know admin, so a wrong password is
+	// audited as passed on.
+	audit.events = nil
+	repos.LocalAccountRepo = memoryLocalAccounts{}
+	chain, err = newAuthChain(repos, &config.Config{
+		Auth:      config.AuthConfig{Providers: []config.AuthProvider{{Type: AuthSourceBuiltin}, {Type: AuthSourceLocal}}},
+		Bootstrap: config.BootstrapConfig{Enabled: true, PasswordHash: hash},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := chain.Authenticate(ctx, SignInInput{UserID: "admin", Password: "wrong-pass"}, false); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+	if len(audit.events) != 1 || audit.events[0].Details != "wrong password, passed on to the next provider" {
+		t.Errorf("unexpected audit events %+v", audit.events)
+	}
+
+	for name, bootstrap := range map[string]config.BootstrapConfig{
+		"disabled":     {Enabled: false, PasswordHash: hash},
+		"without hash": {Enabled: true},
+	} {
+		chain, err := newAuthChain(repos, &config.Config{Auth: providers, Bootstrap: bootstrap})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if len(chain) != 0 {
+			t.Errorf("%s: expected the builtin provider to be left out", name)
+		}
+	}
+
+	if _, err := newAuthChain(repos, &config.Config{Auth: providers, Bootstrap: config.BootstrapConfig{Enabled: true, PasswordHash: "plain"}}); err == nil {
+		t.Error("expected an error for a malformed hash")
+	}
+}
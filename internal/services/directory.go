@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/translit"
+)
+
+const (
+	defaultPeoplePageSize = 20
+	maxPeoplePageSize     = 200
+	defaultBatchLimit     = 500
+	// maxFuzzyCandidates bounds the best-scored names looked up in LDAP for
+	// one fuzzy search.
+	maxFuzzyCandidates = 1000
+)
+
+var (
+	ErrBatchTooLarge = errors.New("too many user ids")
+	ErrInvalidPaging = errors.New("invalid paging")
+)
+
+type DirectoryService interface {
+	SearchPeople(ctx context.Context, query domain.PersonQuery) (*domain.PersonPage, error)
+	ListGroups(ctx context.Context, category string, offset, limit int) (*domain.GroupPage, error)
+	GroupMembers(ctx context.Context, name, category string, query domain.PersonQuery) (*domain.PersonPage, error)
+	BatchGet(ctx context.Context, ids []string) (*domain.UserBatch, error)
+}
+
+type DirectoryServiceImpl struct {
+	repos      Repositories
+	batchLimit int
+	names      *nameIndex
+}
+
+// NewDirectoryService creates the directory service. mirror may be nil; when
+// set, fuzzy searches score the names mirrored by the sync instead of
+// reading the directory.
+func NewDirectoryService(repos Repositories, cfg *config.DirectoryConfig, mirror repository.DirectoryMirrorRepository) *DirectoryServiceImpl {
+	batchLimit := cfg.BatchLimit
+	if batchLimit <= 0 {
+		batchLimit = defaultBatchLimit
+	}
+
+	d := &DirectoryServiceImpl{
+		repos:      repos,
+		batchLimit: batchLimit,
+	}
+	if mirror != nil {
+		d.names = &nameIndex{mirror: mirror}
+	}
+
+	return d
+}
+
+func (d *DirectoryServiceImpl) SearchPeople(ctx context.Context, query domain.PersonQuery) (*domain.PersonPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	limit, err := pageLimit(query.Offset, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	query.Limit = limit
+
+	if query.Fuzzy && query.Query != "" && d.names != nil {
+		page, err := d.searchFuzzy(ctx, query)
+		if err != nil {
+			logger.Error(fmt.Errorf("fuzzy people search failed: %w", err))
+			return nil, err
+		}
+		if page != nil {
+			return page, nil
+		}
+	}
+
+	page, err := d.repos.DirectoryRepo.SearchPeople(ctx, query)
+	if err != nil {
+		logger.Error(fmt.Errorf("people search failed: %w", err))
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// searchFuzzy scores the mirrored names against the query in process and
+// looks up only the matches in LDAP for their roles and groups. It returns
+// nil when the mirror has not been synced yet.
+func (d *DirectoryServiceImpl) searchFuzzy(ctx context.Context, query domain.PersonQuery) (*domain.PersonPage, error) {
+	users, err := d.names.load(ctx)
+	if err != nil || users == nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		id    string
+		name  string
+		score int
+	}
+	var candidates []candidate
+	for _, u := range users {
+		if score, ok := translit.Score(query.Query, u.Username+" "+u.UserID); ok {
+			candidates = append(candidates, candidate{id: u.UserID, name: strings.ToLower(u.Username), score: score})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		if candidates[i].name != candidates[j].name {
+			return candidates[i].name < candidates[j].name
+		}
+		return candidates[i].id < candidates[j].id
+	})
+	if len(candidates) > maxFuzzyCandidates {
+		candidates = candidates[:maxFuzzyCandidates]
+	}
+
+	page := &domain.PersonPage{
+		Items:  []domain.Person{},
+		Offset: query.Offset,
+		Limit:  query.Limit,
+	}
+	if len(candidates) == 0 {
+		return page, nil
+	}
+
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.id
+	}
+	people, err := d.repos.DirectoryRepo.GetPeople(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]domain.Person, len(people))
+	for _, p := range people {
+		byID[p.ID] = p
+	}
+
+	var matched []domain.Person
+	for _, c := range candidates {
+		if p, ok := byID[c.id]; ok && query.Matches(p) {
+			matched = append(matched, p)
+		}
+	}
+
+	page.Total = len(matched)
+	if query.Offset < len(matched) {
+		page.Items = matched[query.Offset:min(query.Offset+query.Limit, len(matched))]
+	}
+
+	return page, nil
+}
+
+func (d *DirectoryServiceImpl) ListGroups(ctx context.Context, category string, offset, limit int) (*domain.GroupPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	limit, err := pageLimit(offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := d.repos.DirectoryRepo.ListGroups(ctx, category, offset, limit)
+	if err != nil {
+		logger.Error(fmt.Errorf("group listing failed: %w", err))
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (d *DirectoryServiceImpl) GroupMembers(ctx context.Context, name, category string, query domain.PersonQuery) (*domain.PersonPage, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("empty group name")
+	}
+
+	limit, err := pageLimit(query.Offset, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	query.Limit = limit
+
+	page, err := d.repos.DirectoryRepo.GroupMembers(ctx, name, category, query)
+	if err != nil {
+		if !errors.Is(err, repository.ErrGroupNotFound) {
+			logger.Error(fmt.Errorf("roster of group %s failed: %w", name, err))
+		}
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// BatchGet returns the users with the given IDs in request order. Duplicate
+// and empty IDs are ignored; IDs without an account are listed in NotFound.
+func (d *DirectoryServiceImpl) BatchGet(ctx context.Context, ids []string) (*domain.UserBatch, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		key := strings.ToLower(id)
+		if id == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, id)
+	}
+
+	if len(unique) > d.batchLimit {
+		return nil, fmt.Errorf("%w: at most %d are allowed", ErrBatchTooLarge, d.batchLimit)
+	}
+
+	people, err := d.repos.DirectoryRepo.GetPeople(ctx, unique)
+	if err != nil {
+		logger.Error(fmt.Errorf("batch lookup of %d users failed: %w", len(unique), err))
+		return nil, err
+	}
+
+	byID := make(map[string]*domain.Person, len(people))
+	for i := range people {
+		byID[strings.ToLower(people[i].ID)] = &people[i]
+	}
+
+	batch := &domain.UserBatch{
+		Users:    []domain.UserExtended{},
+		NotFound: []string{},
+	}
+	for _, id := range unique {
+		if p, ok := byID[strings.ToLower(id)]; ok {
+			batch.Users = append(batch.Users, p.Extended())
+		} else {
+			batch.NotFound = append(batch.NotFound, id)
+		}
+	}
+
+	return batch, nil
+}
+
+// nameIndex keeps the uids and names of the mirror in process for fuzzy
+// search. It is read again once a sync, on any replica, has finished since.
+type nameIndex struct {
+	mirror repository.DirectoryMirrorRepository
+
+	mu       sync.Mutex
+	syncedAt time.Time
+	users    []domain.DirectoryUser
+}
+
+// load returns the mirrored names, or nil when the mirror was never synced.
+func (n *nameIndex) load(ctx context.Context) ([]domain.DirectoryUser, error) {
+	status, err := n.mirror.GetSyncStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status.LastSuccessAt == nil {
+		return nil, nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.users != nil && n.syncedAt.Equal(*status.LastSuccessAt) {
+		return n.users, nil
+	}
+
+	users, err := n.mirror.ListNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []domain.DirectoryUser{}
+	}
+
+	n.users = users
+	n.syncedAt = *status.LastSuccessAt
+	return users, nil
+}
+
+// pageLimit validates paging input and applies the default and maximum
+// page size.
+func pageLimit(offset, limit int) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("%w: offset must not be negative", ErrInvalidPaging)
+	}
+
+	switch {
+	case limit <= 0:
+		return defaultPeoplePageSize, nil
+	case limit > maxPeoplePageSize:
+		return maxPeoplePageSize, nil
+	}
+
+	return limit, nil
+}
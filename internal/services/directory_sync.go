@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSyncInterval     = 5 * time.Minute
+	defaultFullSyncInterval = 24 * time.Hour
+	syncRunTimeout          = 10 * time.Minute
+
+	// watermarkLayout is the UTC GeneralizedTime form the watermark is stored
+	// and filtered with.
+	watermarkLayout = "20060102150405Z"
+)
+
+var ErrSyncRunning = errors.New("directory sync is already running")
+
+type DirectorySyncService interface {
+	Start(ctx context.Context)
+	Trigger(full bool) error
+	Sync(ctx context.Context, full bool) (*domain.SyncStatus, error)
+	Status(ctx context.Context) (*domain.SyncStatus, error)
+}
+
+type DirectorySyncServiceImpl struct {
+	repos        Repositories
+	enabled      bool
+	interval     time.Duration
+	fullInterval time.Duration
+	running      atomic.Bool
+	// holder names this replica on the Mongo lease that keeps two replicas
+	// from syncing at once.
+	holder string
+}
+
+func NewDirectorySyncService(repos Repositories, cfg *config.DirectorySyncConfig) *DirectorySyncServiceImpl {
+	s := &DirectorySyncServiceImpl{
+		repos:        repos,
+		enabled:      cfg.Enabled,
+		interval:     cfg.Interval,
+		fullInterval: cfg.FullInterval,
+		holder:       leaseHolder(),
+	}
+
+	if s.interval <= 0 {
+		s.interval = defaultSyncInterval
+	}
+	if s.fullInterval <= 0 {
+		s.fullInterval = defaultFullSyncInterval
+	}
+
+	return s
+}
+
+// Start runs the sync loop in the background until ctx is done.
+func (s *DirectorySyncServiceImpl) Start(ctx context.Context) {
+	if !s.enabled {
+		logger.Info("directory mirror sync is disabled")
+		return
+	}
+
+	logger.Info(fmt.Sprintf("directory mirror sync started, interval %s", s.interval))
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.runScheduled(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Trigger starts a run in the background unless one is in progress.
+func (s *DirectorySyncServiceImpl) Trigger(full bool) error {
+	if s.running.Load() {
+		return ErrSyncRunning
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), syncRunTimeout)
+		defer cancel()
+
+		if _, err := s.Sync(ctx, full); err != nil && !errors.Is(err, ErrSyncRunning) {
+			logger.Error(fmt.Errorf("triggered directory sync failed: %w", err))
+		}
+	}()
+
+	return nil
+}
+
+// Sync mirrors the directory once. It is incremental from the stored
+// watermark unless full is set or the last full run is older than the full
+// interval; only full runs remove entries that disappeared from LDAP.
+func (s *DirectorySyncServiceImpl) Sync(ctx context.Context, full bool) (*domain.SyncStatus, error) {
+	if !s.running.CompareAndSwap(false, true) {
+		return nil, ErrSyncRunning
+	}
+	defer s.running.Store(false)
+
+	// A run is cut off at syncRunTimeout, so a lease of that length outlives
+	// it, and a crashed replica frees it by then.
+	if err := s.repos.MirrorRepo.AcquireSyncLease(ctx, s.holder, syncRunTimeout); err != nil {
+		if errors.Is(err, repository.ErrSyncLeaseHeld) {
+			return nil, ErrSyncRunning
+		}
+		logger.Error(fmt.Errorf("failed to acquire directory sync lease: %w", err))
+		return nil, err
+	}
+	defer func() {
+		if err := s.repos.MirrorRepo.ReleaseSyncLease(context.WithoutCancel(ctx), s.holder); err != nil {
+			logger.Warn(fmt.Sprintf("failed to release directory sync lease: %v", err))
+		}
+	}()
+
+	status, err := s.repos.MirrorRepo.GetSyncStatus(ctx)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to load directory sync status: %w", err))
+		return nil, fmt.Errorf("failed to load sync status: %w", err)
+	}
+
+	// Mongo keeps milliseconds; a finer start time would make pruning drop
+	// the entries written by this very run.
+	started := time.Now().Truncate(time.Millisecond)
+	if status.LastFullSyncAt == nil || status.Watermark == "" || started.Sub(*status.LastFullSyncAt) >= s.fullInterval {
+		full = true
+	}
+
+	since := status.Watermark
+	status.Mode = domain.SyncModeIncremental
+	if full {
+		since = ""
+		status.Mode = domain.SyncModeFull
+	}
+	status.LastRunAt = &started
+
+	runErr := s.run(ctx, status, since, started)
+
+	status.Duration = time.Since(started).Round(time.Millisecond).String()
+	if runErr != nil {
+		status.LastError = runErr.Error()
+		logger.Error(fmt.Errorf("directory %s sync failed: %w", status.Mode, runErr))
+	} else {
+		status.LastError = ""
+		status.LastSuccessAt = &started
+		if full {
+			status.LastFullSyncAt = &started
+		}
+		logger.Info(fmt.Sprintf("directory %s sync finished in %s: %d users, %d groups, %d/%d removed",
+			status.Mode, status.Duration, status.UsersSynced, status.GroupsSynced, status.UsersRemoved, status.GroupsRemoved))
+	}
+
+	if err := s.repos.MirrorRepo.SaveSyncStatus(ctx, status); err != nil {
+		return status, fmt.Errorf("failed to save sync status: %w", err)
+	}
+
+	return status, runErr
+}
+
+func (s *DirectorySyncServiceImpl) Status(ctx context.Context) (*domain.SyncStatus, error) {
+	status, err := s.repos.MirrorRepo.GetSyncStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Running = s.running.Load()
+	return status, nil
+}
+
+func (s *DirectorySyncServiceImpl) run(ctx context.Context, status *domain.SyncStatus, since string, started time.Time) error {
+	status.UsersSynced = 0
+	status.GroupsSynced = 0
+	status.UsersRemoved = 0
+	status.GroupsRemoved = 0
+
+	users, err := s.repos.DirectoryRepo.ExportUsers(ctx, since)
+	if err != nil {
+		return fmt.Errorf("failed to export users: %w", err)
+	}
+
+	groups, err := s.repos.DirectoryRepo.ExportGroups(ctx, since)
+	if err != nil {
+		return fmt.Errorf("failed to export groups: %w", err)
+	}
+
+	watermark := newerTimestamp(time.Time{}, status.Watermark)
+	for i := range users {
+		users[i].SyncedAt = started
+		watermark = newerTimestamp(watermark, users[i].ModifyTimestamp)
+	}
+	for i := range groups {
+		groups[i].SyncedAt = started
+		watermark = newerTimestamp(watermark, groups[i].ModifyTimestamp)
+	}
+
+	if err := s.repos.MirrorRepo.UpsertUsers(ctx, users); err != nil {
+		return fmt.Errorf("failed to store users: %w", err)
+	}
+	if err := s.repos.MirrorRepo.UpsertGroups(ctx, groups); err != nil {
+		return fmt.Errorf("failed to store groups: %w", err)
+	}
+
+	status.UsersSynced = len(users)
+	status.GroupsSynced = len(groups)
+
+	if since == "" {
+		if status.UsersRemoved, err = s.repos.MirrorRepo.PruneUsers(ctx, started); err != nil {
+			return fmt.Errorf("failed to prune users: %w", err)
+		}
+		if status.GroupsRemoved, err = s.repos.MirrorRepo.PruneGroups(ctx, started); err != nil {
+			return fmt.Errorf("failed to prune groups: %w", err)
+		}
+	}
+
+	// Entries changed within the same second as the watermark are fetched
+	// again next time (>=), which is harmless since writes are upserts.
+	if !watermark.IsZero() {
+		status.Watermark = watermark.Format(watermarkLayout)
+	}
+	return nil
+}
+
+// newerTimestamp returns the later of t and a modifyTimestamp value. Values
+// are compared as times, since servers may send fractions or offsets that
+// do not sort as strings.
+func newerTimestamp(t time.Time, value string) time.Time {
+	if value == "" {
+		return t
+	}
+
+	parsed, err := ber.ParseGeneralizedTime([]byte(value))
+	if err != nil {
+		logger.Warn(fmt.Sprintf("ignoring unparsable modifyTimestamp %q: %v", value, err))
+		return t
+	}
+
+	parsed = parsed.UTC().Truncate(time.Second)
+	if parsed.After(t) {
+		return parsed
+	}
+	return t
+}
+
+// leaseHolder identifies this process among the replicas.
+func leaseHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+func (s *DirectorySyncServiceImpl) runScheduled(ctx context.Context) {
+	runCtx, cancel := context.WithTimeout(ctx, syncRunTimeout)
+	defer cancel()
+
+	if _, err := s.Sync(runCtx, false); err != nil && errors.Is(err, ErrSyncRunning) {
+		logger.Debug("scheduled directory sync skipped, a run is in progress")
+	}
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+type memoryMirror struct {
+	mu     sync.Mutex
+	users  map[string]domain.DirectoryUser
+	groups map[string]domain.DirectoryGroup
+	status domain.SyncStatus
+
+	leaseHolder string
+	leaseUntil  time.Time
+}
+
+func newMemoryMirror() *memoryMirror {
+	return &memoryMirror{
+		users:  make(map[string]domain.DirectoryUser),
+		groups: make(map[string]domain.DirectoryGroup),
+	}
+}
+
+func (m *memoryMirror) UpsertUsers(_ context.Context, users []domain.DirectoryUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range users {
+		m.users[u.UserID] = u
+	}
+	return nil
+}
+
+func (m *memoryMirror) UpsertGroups(_ context.Context, groups []domain.DirectoryGroup) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, g := range groups {
+		m.groups[g.DN] = g
+	}
+	return nil
+}
+
+func (m *memoryMirror) PruneUsers(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for id, u := range m.users {
+		if u.SyncedAt.Before(before) {
+			delete(m.users, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryMirror) PruneGroups(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for dn, g := range m.groups {
+		if g.SyncedAt.Before(before) {
+			delete(m.groups, dn)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memoryMirror) SearchUsers(_ context.Context, branch, query string, limit int) ([]domain.DirectoryUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	query = strings.ToLower(query)
+	var users []domain.DirectoryUser
+	for _, u := range m.users {
+		if u.Branch == branch && (strings.Contains(strings.ToLower(u.UserID), query) || strings.Contains(strings.ToLower(u.Username), query)) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].UserID < users[j].UserID })
+	if limit > 0 && len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *memoryMirror) ListNames(context.Context) ([]domain.DirectoryUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []domain.DirectoryUser
+	for _, u := range m.users {
+		users = append(users, domain.DirectoryUser{UserID: u.UserID, Username: u.Username})
+	}
+	return users, nil
+}
+
+func (m *memoryMirror) GetUser(_ context.Context, userID string) (*domain.DirectoryUser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[userID]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &u, nil
+}
+
+func (m *memoryMirror) GetUserGroups(_ context.Context, userDN, userID string) ([]domain.DirectoryGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var groups []domain.DirectoryGroup
+	for _, g := range m.groups {
+		if slices.Contains(g.Members, strings.ToLower(userDN)) || (userID != "" && slices.Contains(g.MemberUIDs, userID)) {
+			groups = append(groups, g)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].DN < groups[j].DN })
+	return groups, nil
+}
+
+func (m *memoryMirror) GetSyncStatus(_ context.Context) (*domain.SyncStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := m.status
+	return &status, nil
+}
+
+func (m *memoryMirror) SaveSyncStatus(_ context.Context, status *domain.SyncStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = *status
+	return nil
+}
+
+func (m *memoryMirror) AcquireSyncLease(_ context.Context, holder string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leaseHolder != "" && m.leaseHolder != holder && time.Now().Before(m.leaseUntil) {
+		return repository.ErrSyncLeaseHeld
+	}
+	m.leaseHolder = holder
+	m.leaseUntil = time.Now().Add(ttl)
+	return nil
+}
+
+func (m *memoryMirror) ReleaseSyncLease(_ context.Context, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leaseHolder == holder {
+		m.leaseHolder = ""
+	}
+	return nil
+}
+
+func newDirectorySync(t *testing.T) (*DirectorySyncServiceImpl, *ldaptest.Server, *memoryMirror) {
+	t.Helper()
+
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          srv.URL(),
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+		},
+		Sync: config.DirectorySyncConfig{Enabled: true, PageSize: 3},
+	}
+
+	mirror := newMemoryMirror()
+	repos := Repositories{
+		DirectoryRepo: repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP)),
+		MirrorRepo:    mirror,
+	}
+
+	return NewDirectorySyncService(repos, &cfg.Sync), srv, mirror
+}
+
+func TestDirectorySyncFull(t *testing.T) {
+	svc, _, mirror := newDirectorySync(t)
+
+	status, err := svc.Sync(context.Background(), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if status.Mode != domain.SyncModeFull {
+		t.Errorf("expected the first run to be %q, got %q", domain.SyncModeFull, status.Mode)
+	}
+	if status.UsersSynced != 11 {
+		t.Errorf("expected 11 users, got %d", status.UsersSynced)
+	}
+	if status.GroupsSynced != 17 {
+		t.Errorf("expected 17 groups, got %d", status.GroupsSynced)
+	}
+	if status.Watermark != ldaptest.LoadTimestamp {
+		t.Errorf("expected watermark %q, got %q", ldaptest.LoadTimestamp, status.Watermark)
+	}
+
+	if u, err := mirror.GetUser(context.Background(), "t001"); err != nil || u.Branch != domain.DirectoryBranchTeachers || u.Mail != "t001@it-college.ru" {
+		t.Errorf("expected t001 mirrored from Teachers, got %+v (%v)", u, err)
+	}
+	if _, ok := mirror.groups["cn=ИТ19-11,ou=Archive,dc=it-college,dc=ru"]; ok {
+		t.Error("expected archived groups not to be mirrored")
+	}
+}
+
+func TestDirectorySyncIncremental(t *testing.T) {
+	svc, srv, mirror := newDirectorySync(t)
+	ctx := context.Background()
+
+	if _, err := svc.Sync(ctx, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := srv.Modify("uid=t002,ou=Teachers,dc=it-college,dc=ru", "cn", "Орлова-Белова Елена Николаевна"); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := svc.Sync(ctx, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Mode != domain.SyncModeIncremental {
+		t.Errorf("expected %q, got %q", domain.SyncModeIncremental, status.Mode)
+	}
+	if u, _ := mirror.GetUser(ctx, "t002"); u == nil || u.Username != "Орлова-Белова Елена Николаевна" {
+		t.Errorf("expected renamed t002 in the mirror, got %+v", u)
+	}
+
+	// The watermark has moved past the loaded entries, so only t002 is
+	// fetched again.
+	status, err = svc.Sync(ctx, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.UsersSynced != 1 || status.GroupsSynced != 0 {
+		t.Errorf("expected 1 user and 0 groups, got %d and %d", status.UsersSynced, status.GroupsSynced)
+	}
+}
+
+func TestDirectorySyncPrunesOnFullRunOnly(t *testing.T) {
+	svc, _, mirror := newDirectorySync(t)
+	ctx := context.Background()
+
+	if _, err := svc.Sync(ctx, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	gone := domain.DirectoryUser{UserID: "i18s0001", Branch: domain.DirectoryBranchPeople, SyncedAt: time.Now().Add(-time.Hour)}
+	_ = mirror.UpsertUsers(ctx, []domain.DirectoryUser{gone})
+
+	status, err := svc.Sync(ctx, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.UsersRemoved != 0 {
+		t.Errorf("expected an incremental run to keep entries, removed %d", status.UsersRemoved)
+	}
+
+	status, err = svc.Sync(ctx, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.UsersRemoved != 1 {
+		t.Errorf("expected 1 removed user, got %d", status.UsersRemoved)
+	}
+	if _, err := mirror.GetUser(ctx, "i18s0001"); err == nil {
+		t.Error("expected i18s0001 to be pruned")
+	}
+}
+
+func TestDirectorySyncRunning(t *testing.T) {
+	svc, _, _ := newDirectorySync(t)
+	svc.running.Store(true)
+
+	if _, err := svc.Sync(context.Background(), false); !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("expected %v, got %v", ErrSyncRunning, err)
+	}
+	if err := svc.Trigger(true); !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("expected %v, got %v", ErrSyncRunning, err)
+	}
+}
+
+func TestDirectorySyncLease(t *testing.T) {
+	svc, _, mirror := newDirectorySync(t)
+	ctx := context.Background()
+
+	// Another replica is syncing.
+	if err := mirror.AcquireSyncLease(ctx, "other-replica", time.Minute); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Sync(ctx, false); !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("expected %v, got %v", ErrSyncRunning, err)
+	}
+
+	// Its lease expired without being released.
+	mirror.leaseUntil = time.Now().Add(-time.Second)
+	if _, err := svc.Sync(ctx, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mirror.leaseHolder != "" {
+		t.Errorf("expected the lease to be released, held by %q", mirror.leaseHolder)
+	}
+}
+
+func TestNewerTimestamp(t *testing.T) {
+	base := newerTimestamp(time.Time{}, "20240901000000Z")
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "20240901000000.5Z", want: base},
+		// Sorts after the base as a string, but is three hours earlier.
+		{value: "20240901000000+0300", want: base},
+		{value: "20240901030000+0300", want: base},
+		{value: "20240901000001Z", want: base.Add(time.Second)},
+		{value: "garbage", want: base},
+		{value: "", want: base},
+	}
+
+	for _, tt := range tests {
+		if got := newerTimestamp(base, tt.value); !got.Equal(tt.want) {
+			t.Errorf("%q: expected %s, got %s", tt.value, tt.want, got)
+		}
+	}
+	if got := base.Format(watermarkLayout); got != "20240901000000Z" {
+		t.Errorf("expected watermark %q, got %q", "20240901000000Z", got)
+	}
+}
+
+func TestSearchStudentsFallsBackToMirror(t *testing.T) {
+	mirror := newMemoryMirror()
+	_ = mirror.UpsertUsers(context.Background(), []domain.DirectoryUser{
+		{UserID: "i24s0001", Username: "Иванов Иван Иванович", Branch: domain.DirectoryBranchPeople},
+		{UserID: "t001", Username: "Смирнов Павел Андреевич", Branch: domain.DirectoryBranchTeachers},
+	})
+
+	cfg := &config.Config{LDAP: config.LDAPConfig{URL: "ldap://127.0.0.1:1", DialTimeout: 200 * time.Millisecond}}
+	svc := NewStudentService(repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP)), mirror)
+
+	got, err := svc.SearchStudents(context.Background(), "иван")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "i24s0001" {
+		t.Errorf("expected i24s0001 from the mirror, got %v", got)
+	}
+}
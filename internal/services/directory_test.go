@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+func TestBatchGet(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          srv.URL(),
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+		},
+		Directory: config.DirectoryConfig{BatchLimit: 4},
+	}
+
+	svc := NewDirectoryService(Repositories{
+		DirectoryRepo: repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP)),
+	}, &cfg.Directory, nil)
+
+	batch, err := svc.BatchGet(context.Background(), []string{"t002", "ghost", "i24s0001", "T002", " ", "missing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := []string{}
+	for _, u := range batch.Users {
+		ids = append(ids, u.ID)
+	}
+	if want := []string{"t002", "i24s0001"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected users %v, got %v", want, ids)
+	}
+	if want := []string{"ghost", "missing"}; !reflect.DeepEqual(batch.NotFound, want) {
+		t.Errorf("expected not found %v, got %v", want, batch.NotFound)
+	}
+	if batch.Users[1].AcademicGroup != "ИТ24-11" {
+		t.Errorf("expected academic group %q, got %q", "ИТ24-11", batch.Users[1].AcademicGroup)
+	}
+
+	batch, err = svc.BatchGet(context.Background(), []string{"I24S0001"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batch.Users) != 1 || len(batch.NotFound) != 0 {
+		t.Errorf("expected the upper-cased id to be found, got %+v", batch)
+	}
+
+	if _, err := svc.BatchGet(context.Background(), []string{"a", "b", "c", "d", "e"}); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("expected %v, got %v", ErrBatchTooLarge, err)
+	}
+}
+
+func TestFuzzySearchOverMirror(t *testing.T) {
+	directorySync, _, mirror := newDirectorySync(t)
+	svc := NewDirectoryService(directorySync.repos, &config.DirectoryConfig{}, mirror)
+	ctx := context.Background()
+
+	// Until the first sync the LDAP substring search answers.
+	page, err := svc.SearchPeople(ctx, domain.PersonQuery{Query: "petrov", Fuzzy: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Total != 0 {
+		t.Errorf("expected no substring matches, got %v", page.Items)
+	}
+
+	if _, err := directorySync.Sync(ctx, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		query   domain.PersonQuery
+		wantIDs []string
+	}{
+		{
+			name:    "in latin",
+			query:   domain.PersonQuery{Query: "petrov", Fuzzy: true},
+			wantIDs: []string{"i24s0002", "i23s0101"},
+		},
+		{
+			name:    "with typos and ё",
+			query:   domain.PersonQuery{Query: "Федр Кузнецв", Fuzzy: true},
+			wantIDs: []string{"i25s0003"},
+		},
+		{
+			name:    "closest match first",
+			query:   domain.PersonQuery{Query: "петрович", Fuzzy: true},
+			wantIDs: []string{"i23s0101", "i24s0002"},
+		},
+		{
+			name:    "with a group filter",
+			query:   domain.PersonQuery{Query: "petrov", Fuzzy: true, AcademicGroup: "ИТ24-11"},
+			wantIDs: []string{"i24s0002"},
+		},
+		{
+			name:    "paged",
+			query:   domain.PersonQuery{Query: "petrov", Fuzzy: true, Offset: 1, Limit: 1},
+			wantIDs: []string{"i23s0101"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := svc.SearchPeople(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids := []string{}
+			for _, p := range page.Items {
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("expected %v, got %v", tt.wantIDs, ids)
+			}
+		})
+	}
+}
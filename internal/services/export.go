@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/xlsx"
+)
+
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+var (
+	ErrUnknownExportFormat = errors.New("unknown export format")
+	ErrUnknownExportColumn = errors.New("unknown export column")
+)
+
+type exportColumn struct {
+	title string
+	value func(p *domain.Person) string
+}
+
+var exportColumns = map[string]exportColumn{
+	"id":             {"ID", func(p *domain.Person) string { return p.ID }},
+	"name":           {"ФИО", func(p *domain.Person) string { return p.Username }},
+	"academic_group": {"Группа", func(p *domain.Person) string { return p.AcademicGroup }},
+	"profile":        {"Профиль", func(p *domain.Person) string { return p.Profile }},
+	"subgroup":       {"Подгруппа", func(p *domain.Person) string { return p.Subgroup }},
+	"english_group":  {"Английский", func(p *domain.Person) string { return p.EnglishGroup }},
+}
+
+var defaultExportColumns = []string{"id", "name", "academic_group", "profile", "subgroup", "english_group"}
+
+// ExportRequest selects people either from a group roster, when Group is
+// set, or by search like SearchPeople. Columns default to all of them.
+type ExportRequest struct {
+	Format   string
+	Columns  []string
+	Role     string
+	Query    string
+	Group    string
+	Category string
+	Subgroup string
+}
+
+type ExportService interface {
+	Prepare(ctx context.Context, req ExportRequest) (*Export, error)
+}
+
+// Export is a loaded list of people ready to be written in one format.
+type Export struct {
+	format  string
+	name    string
+	columns []exportColumn
+	people  []domain.Person
+}
+
+type ExportServiceImpl struct {
+	repos  Repositories
+	search StudentService
+}
+
+func NewExportService(repos Repositories, search StudentService) *ExportServiceImpl {
+	return &ExportServiceImpl{
+		repos:  repos,
+		search: search,
+	}
+}
+
+// Prepare validates req and loads the people to export. Rosters are exported
+// whole; searches are limited like SearchPeople.
+func (e *ExportServiceImpl) Prepare(ctx context.Context, req ExportRequest) (*Export, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if req.Format != ExportFormatCSV && req.Format != ExportFormatXLSX {
+		return nil, fmt.Errorf("%w %q", ErrUnknownExportFormat, req.Format)
+	}
+
+	names := req.Columns
+	if len(names) == 0 {
+		names = defaultExportColumns
+	}
+	columns := make([]exportColumn, 0, len(names))
+	for _, name := range names {
+		column, ok := exportColumns[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownExportColumn, name)
+		}
+		columns = append(columns, column)
+	}
+
+	export := &Export{
+		format:  req.Format,
+		columns: columns,
+	}
+
+	if req.Group != "" {
+		role, err := normalizeRole(req.Role)
+		if err != nil {
+			return nil, err
+		}
+
+		page, err := e.repos.DirectoryRepo.GroupMembers(ctx, req.Group, req.Category, domain.PersonQuery{
+			Role:     role,
+			Subgroup: req.Subgroup,
+		})
+		if err != nil {
+			logger.Error(fmt.Errorf("roster export of %s failed: %w", req.Group, err))
+			return nil, err
+		}
+
+		export.name = req.Group
+		export.people = page.Items
+		return export, nil
+	}
+
+	people, err := e.search.SearchPeople(ctx, req.Role, req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	export.name = "search"
+	export.people = people
+	return export, nil
+}
+
+func (x *Export) ContentType() string {
+	if x.format == ExportFormatXLSX {
+		return xlsx.ContentType
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FileName is the suggested download name, e.g. "ИТ24-11.xlsx".
+func (x *Export) FileName() string {
+	return x.name + "." + x.format
+}
+
+// Write streams a header row and one row per person to w.
+func (x *Export) Write(w io.Writer) error {
+	header := make([]string, len(x.columns))
+	for i, c := range x.columns {
+		header[i] = c.title
+	}
+
+	row := func(p *domain.Person) []string {
+		cells := make([]string, len(x.columns))
+		for i, c := range x.columns {
+			cells[i] = c.value(p)
+		}
+		return cells
+	}
+
+	if x.format == ExportFormatXLSX {
+		xw, err := xlsx.NewWriter(w, x.name)
+		if err != nil {
+			return err
+		}
+		if err := xw.WriteRow(header); err != nil {
+			return err
+		}
+		for i := range x.people {
+			if err := xw.WriteRow(row(&x.people[i])); err != nil {
+				return err
+			}
+		}
+		return xw.Close()
+	}
+
+	// The byte order mark makes Excel read the file as UTF-8.
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for i := range x.people {
+		if err := cw.Write(row(&x.people[i])); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
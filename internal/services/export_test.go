@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+func newTestExportService(t *testing.T) *ExportServiceImpl {
+	t.Helper()
+
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          srv.URL(),
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+		},
+	}
+
+	directory := repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP))
+	return NewExportService(Repositories{DirectoryRepo: directory}, NewStudentService(directory, nil))
+}
+
+func TestExportRosterCSV(t *testing.T) {
+	svc := newTestExportService(t)
+
+	export, err := svc.Prepare(context.Background(), ExportRequest{
+		Format:  ExportFormatCSV,
+		Columns: []string{"id", "name", "subgroup"},
+		Group:   "ИТ24-11",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if export.FileName() != "ИТ24-11.csv" {
+		t.Errorf("expected file name %q, got %q", "ИТ24-11.csv", export.FileName())
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "\ufeffID,ФИО,Подгруппа\n" +
+		"i24s0001,Иванов Иван Иванович,Подгр1\n" +
+		"i24s0777,Новиков Егор Андреевич,\n" +
+		"i24s0002,Петрова Мария Сергеевна,\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestExportSearchXLSX(t *testing.T) {
+	svc := newTestExportService(t)
+
+	export, err := svc.Prepare(context.Background(), ExportRequest{
+		Format: ExportFormatXLSX,
+		Role:   RoleTeacher,
+		Query:  "t00",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "PK") {
+		t.Error("expected a zip archive")
+	}
+}
+
+func TestExportRejectsBadRequests(t *testing.T) {
+	svc := newTestExportService(t)
+	ctx := context.Background()
+
+	if _, err := svc.Prepare(ctx, ExportRequest{Format: "pdf"}); !errors.Is(err, ErrUnknownExportFormat) {
+		t.Errorf("expected %v, got %v", ErrUnknownExportFormat, err)
+	}
+	if _, err := svc.Prepare(ctx, ExportRequest{Format: ExportFormatCSV, Columns: []string{"id", "password"}}); !errors.Is(err, ErrUnknownExportColumn) {
+		t.Errorf("expected %v, got %v", ErrUnknownExportColumn, err)
+	}
+	if _, err := svc.Prepare(ctx, ExportRequest{Format: ExportFormatCSV, Group: "ИТ99-99"}); !errors.Is(err, repository.ErrGroupNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrGroupNotFound, err)
+	}
+}
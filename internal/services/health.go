@@ -0,0 +1,19 @@
+package service
+
+import "github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+
+type HealthService interface {
+	LDAPEndpoints() []ldappool.EndpointStatus
+}
+
+type HealthServiceImpl struct {
+	pool *ldappool.Pool
+}
+
+func NewHealthService(pool *ldappool.Pool) *HealthServiceImpl {
+	return &HealthServiceImpl{pool: pool}
+}
+
+func (h *HealthServiceImpl) LDAPEndpoints() []ldappool.EndpointStatus {
+	return h.pool.Status()
+}
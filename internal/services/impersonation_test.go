@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+)
+
+func TestImpersonate(t *testing.T) {
+	profiles := memoryProfiles{
+		"i24s0001": {
+			ID:       "i24s0001",
+			Username: "Иванов Иван Иванович",
+			Role:     RoleStudent,
+			Groups:   &domain.UserGroups{AcademicGroup: "ИТ24-11", Subgroup: "Подгр1"},
+		},
+		"t003":     {ID: "t003", Username: "Админов", Role: RoleTeacher, Roles: []string{RoleTeacher, RoleAdmin}},
+		"i23s0101": {ID: "i23s0101", Username: "Сидоров Алексей Петрович", Role: RoleStudent},
+		"guest":    {ID: "guest", Role: RoleTeacher, Source: domain.ProfileSourceLocal},
+	}
+	audit := &memoryAudit{}
+	access := &memoryAccess{suspensions: map[string]domain.Suspension{
+		"i23s0101": {UserID: "i23s0101", Reason: "expelled"},
+	}}
+	sessions := &memorySessions{}
+	repos := Repositories{
+		ProfileRepo:      profiles,
+		AuditRepo:        audit,
+		AccessRepo:       access,
+		SessionRepo:      sessions,
+		LocalAccountRepo: memoryLocalAccounts{},
+	}
+
+	tm := auth.NewManager(&config.Config{JWT: config.JWTConfig{AccessTokenTTL: "60m", SigningKey: "test-key"}})
+	svc := NewImpersonationService(*tm, repos, &config.ImpersonationConfig{}, &config.ProfileConfig{})
+	users := NewUserService(*tm, repos, time.Hour, time.Hour, &config.ProfileConfig{}, nil)
+	ctx := context.Background()
+
+	result, err := svc.Impersonate(ctx, ImpersonateInput{ActorID: "admin", TargetID: "i24s0001", Reason: "schedule looks empty", ClientIP: "10.0.0.7"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ExpiresIn != defaultImpersonationTTL {
+		t.Errorf("expected ttl %s, got %s", defaultImpersonationTTL, result.ExpiresIn)
+	}
+	if result.User.AcademicGroup != "ИТ24-11" {
+		t.Errorf("expected group %q, got %q", "ИТ24-11", result.User.AcademicGroup)
+	}
+
+	claims, err := tm.GetAllClaims(result.AccessToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp, _ := claims["exp"].(float64)
+	if remaining := time.Until(time.Unix(int64(exp), 0)); remaining > defaultImpersonationTTL {
+		t.Errorf("expected the token to expire within %s, got %s", defaultImpersonationTTL, remaining)
+	}
+
+	user, err := users.ValidateAccessToken(ctx, result.AccessToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.ID != "i24s0001" || user.Impersonator != "admin" {
+		t.Errorf("expected i24s0001 impersonated by admin, got %q by %q", user.ID, user.Impersonator)
+	}
+
+	if len(audit.events) != 1 {
+		t.Fatalf("expected 1 audit event, got %d", len(audit.events))
+	}
+	if e := audit.events[0]; e.Action != domain.AuditImpersonation || e.Subject != "i24s0001" || e.Details != "schedule looks empty" {
+		t.Errorf("unexpected audit event %+v", e)
+	}
+
+	tests := []struct {
+		name    string
+		target  string
+		wantErr error
+	}{
+		{name: "self", target: "admin", wantErr: ErrImpersonationForbidden},
+		{name: "admin by additional role", target: "t003", wantErr: ErrImpersonationForbidden},
+		{name: "never signed in", target: "i24s0002", wantErr: repository.ErrProfileNotFound},
+		{name: "suspended", target: "i23s0101", wantErr: ErrImpersonationForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Impersonate(ctx, ImpersonateInput{ActorID: "admin", TargetID: tt.target})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// An admins-only window keeps everyone else out, impersonated or not.
+	access.policy = domain.AccessPolicy{AllowRoles: []string{RoleAdmin}}
+	if _, err := svc.Impersonate(ctx, ImpersonateInput{ActorID: "admin", TargetID: "i24s0001"}); !errors.Is(err, ErrImpersonationForbidden) {
+		t.Errorf("expected %v, got %v", ErrImpersonationForbidden, err)
+	}
+	access.policy = domain.AccessPolicy{}
+
+	// A stale local profile whose account is gone fails the request but
+	// leaves the target's sessions alone.
+	stale := NewImpersonationService(*tm, repos, &config.ImpersonationConfig{}, &config.ProfileConfig{StaleAfter: time.Hour})
+	if _, err := stale.Impersonate(ctx, ImpersonateInput{ActorID: "admin", TargetID: "guest"}); !errors.Is(err, ErrImpersonationForbidden) {
+		t.Errorf("expected %v, got %v", ErrImpersonationForbidden, err)
+	}
+	if len(sessions.revoked) != 0 {
+		t.Errorf("expected no revoked sessions, got %v", sessions.revoked)
+	}
+
+	if len(audit.events) != 1 {
+		t.Errorf("expected refused attempts not to be audited as impersonation, got %d events", len(audit.events))
+	}
+}
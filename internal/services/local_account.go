@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/passhash"
+)
+
+var (
+	ErrInvalidLocalAccount = errors.New("invalid local account")
+	ErrDirectoryUserExists = errors.New("user id is taken by a directory user")
+)
+
+// Local IDs use the same alphabet as LDAP uids so they fit in tokens and URLs.
+var localAccountIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,63}$`)
+
+type LocalAccountInput struct {
+	ID        string
+	Username  string
+	Password  string
+	Role      string
+	Groups    *domain.UserGroups
+	ExpiresAt *time.Time
+}
+
+type LocalAccountService interface {
+	List(ctx context.Context) ([]domain.LocalAccount, error)
+	Get(ctx context.Context, userID string) (*domain.LocalAccount, error)
+	Create(ctx context.Context, input LocalAccountInput) (*domain.LocalAccount, error)
+	Update(ctx context.Context, input LocalAccountInput) (*domain.LocalAccount, error)
+	SetPassword(ctx context.Context, userID, password string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type LocalAccountServiceImpl struct {
+	repos Repositories
+	cfg   *config.PasswordConfig
+}
+
+func NewLocalAccountService(repos Repositories, cfg *config.PasswordConfig) *LocalAccountServiceImpl {
+	return &LocalAccountServiceImpl{
+		repos: repos,
+		cfg:   cfg,
+	}
+}
+
+func (l *LocalAccountServiceImpl) List(ctx context.Context) ([]domain.LocalAccount, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return l.repos.LocalAccountRepo.List(ctx)
+}
+
+func (l *LocalAccountServiceImpl) Get(ctx context.Context, userID string) (*domain.LocalAccount, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return l.repos.LocalAccountRepo.Get(ctx, localAccountID(userID))
+}
+
+// Create stores a new account. IDs already used in LDAP are refused, since
+// the two users would share sessions and profiles.
+func (l *LocalAccountServiceImpl) Create(ctx context.Context, input LocalAccountInput) (*domain.LocalAccount, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	input.ID = localAccountID(input.ID)
+	if !localAccountIDPattern.MatchString(input.ID) {
+		return nil, fmt.Errorf("%w: id must be 3-64 lowercase letters, digits, '.', '_' or '-'", ErrInvalidLocalAccount)
+	}
+	if err := validateLocalAccount(input); err != nil {
+		return nil, err
+	}
+	if err := CheckPasswordStrength(l.cfg, input.ID, input.Password); err != nil {
+		return nil, err
+	}
+
+	people, err := l.repos.DirectoryRepo.GetPeople(ctx, []string{input.ID})
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to check directory for local account %s: %w", input.ID, err))
+		return nil, fmt.Errorf("failed to check directory: %w", err)
+	}
+	if len(people) > 0 {
+		return nil, ErrDirectoryUserExists
+	}
+
+	hash, err := passhash.Hash(input.Password)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to hash password for local account %s: %w", input.ID, err))
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	now := time.Now()
+	account := &domain.LocalAccount{
+		ID:           input.ID,
+		Username:     strings.TrimSpace(input.Username),
+		PasswordHash: hash,
+		Role:         input.Role,
+		Groups:       input.Groups,
+		ExpiresAt:    input.ExpiresAt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := l.repos.LocalAccountRepo.Create(ctx, account); err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("local account %s created with role %s", account.ID, account.Role))
+	return account, nil
+}
+
+// Update replaces the name, role, groups and expiry of an account. Signed-in
+// users pick the changes up on their next profile resync.
+func (l *LocalAccountServiceImpl) Update(ctx context.Context, input LocalAccountInput) (*domain.LocalAccount, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	input.ID = localAccountID(input.ID)
+	if err := validateLocalAccount(input); err != nil {
+		return nil, err
+	}
+
+	account := &domain.LocalAccount{
+		ID:        input.ID,
+		Username:  strings.TrimSpace(input.Username),
+		Role:      input.Role,
+		Groups:    input.Groups,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := l.repos.LocalAccountRepo.Update(ctx, account); err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("local account %s updated", input.ID))
+	return l.repos.LocalAccountRepo.Get(ctx, input.ID)
+}
+
+// SetPassword replaces the password and signs the user out everywhere,
+// including personal access tokens.
+func (l *LocalAccountServiceImpl) SetPassword(ctx context.Context, userID, password string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	userID = localAccountID(userID)
+	if err := CheckPasswordStrength(l.cfg, userID, password); err != nil {
+		return err
+	}
+
+	hash, err := passhash.Hash(password)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to hash password for local account %s: %w", userID, err))
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := l.repos.LocalAccountRepo.SetPassword(ctx, userID, hash); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("password of local account %s replaced", userID))
+	return l.revokeSessions(ctx, userID)
+}
+
+func (l *LocalAccountServiceImpl) Delete(ctx context.Context, userID string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	userID = localAccountID(userID)
+	if err := l.repos.LocalAccountRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("local account %s deleted", userID))
+	return l.revokeSessions(ctx, userID)
+}
+
+// localAccountID normalises an account ID the way Create stores it.
+func localAccountID(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+func (l *LocalAccountServiceImpl) revokeSessions(ctx context.Context, userID string) error {
+	if err := l.repos.SessionRepo.RevokeAllUserSessions(ctx, userID); err != nil {
+		logger.Error(fmt.Errorf("failed to revoke sessions of local account %s: %w", userID, err))
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return revokePersonalTokens(ctx, l.repos, userID)
+}
+
+func validateLocalAccount(input LocalAccountInput) error {
+	if strings.TrimSpace(input.Username) == "" {
+		return fmt.Errorf("%w: username is required", ErrInvalidLocalAccount)
+	}
+
+	switch input.Role {
+	case RoleStudent, RoleTeacher, RoleAdmin:
+	default:
+		return fmt.Errorf("%w: role must be student, teacher or admin", ErrInvalidLocalAccount)
+	}
+
+	if input.Groups != nil && input.Role != RoleStudent {
+		return fmt.Errorf("%w: only students can have groups", ErrInvalidLocalAccount)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+	"github.com/anton1ks96/college-auth-svc/pkg/passhash"
+)
+
+type memoryLocalAccounts map[string]domain.LocalAccount
+
+func (m memoryLocalAccounts) Create(_ context.Context, account *domain.LocalAccount) error {
+	if _, ok := m[account.ID]; ok {
+		return repository.ErrLocalAccountExists
+	}
+	m[account.ID] = *account
+	return nil
+}
+
+func (m memoryLocalAccounts) Get(_ context.Context, userID string) (*domain.LocalAccount, error) {
+	account, ok := m[userID]
+	if !ok {
+		return nil, repository.ErrLocalAccountNotFound
+	}
+	return &account, nil
+}
+
+func (m memoryLocalAccounts) List(_ context.Context) ([]domain.LocalAccount, error) {
+	accounts := []domain.LocalAccount{}
+	for _, account := range m {
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (m memoryLocalAccounts) Update(_ context.Context, account *domain.LocalAccount) error {
+	stored, ok := m[account.ID]
+	if !ok {
+		return repository.ErrLocalAccountNotFound
+	}
+	stored.Username = account.Username
+	stored.Role = account.Role
+	stored.Groups = account.Groups
+	stored.ExpiresAt = account.ExpiresAt
+	m[account.ID] = stored
+	return nil
+}
+
+func (m memoryLocalAccounts) SetPassword(_ context.Context, userID, passwordHash string) error {
+	stored, ok := m[userID]
+	if !ok {
+		return repository.ErrLocalAccountNotFound
+	}
+	stored.PasswordHash = passwordHash
+	m[userID] = stored
+	return nil
+}
+
+func (m memoryLocalAccounts) Delete(_ context.Context, userID string) error {
+	if _, ok := m[userID]; !ok {
+		return repository.ErrLocalAccountNotFound
+	}
+	delete(m, userID)
+	return nil
+}
+
+func newLocalAccountRepos(t *testing.T, ldapURL string) (Repositories, memoryLocalAccounts, *memorySessions) {
+	t.Helper()
+
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          ldapURL,
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+			DialTimeout:  200 * time.Millisecond,
+		},
+	}
+
+	pool := ldappool.New(cfg.LDAP)
+	accounts := memoryLocalAccounts{}
+	sessions := &memorySessions{}
+	return Repositories{
+		UserRepo:          repository.NewUserRepository(cfg, pool),
+		DirectoryRepo:     repository.NewDirectoryRepository(cfg, pool),
+		ProfileRepo:       memoryProfiles{},
+		SessionRepo:       sessions,
+		LocalAccountRepo:  accounts,
+		PersonalTokenRepo: memoryPersonalTokens{},
+	}, accounts, sessions
+}
+
+func TestLocalAccountService(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	repos, accounts, sessions := newLocalAccountRepos(t, srv.URL())
+	svc := NewLocalAccountService(repos, &config.PasswordConfig{MinLength: 8, ForbidUserID: true})
+	ctx := context.Background()
+
+	input := LocalAccountInput{ID: "i24s0001", Username: "Двойник", Password: "long-enough", Role: RoleStudent}
+	if _, err := svc.Create(ctx, input); !errors.Is(err, ErrDirectoryUserExists) {
+		t.Errorf("expected %v, got %v", ErrDirectoryUserExists, err)
+	}
+
+	input.ID = "Proctor"
+	input.Password = "proctor-1"
+	var weakErr *WeakPasswordError
+	if _, err := svc.Create(ctx, input); !errors.As(err, &weakErr) {
+		t.Errorf("expected a weak password error, got %v", err)
+	}
+
+	input.Password = "long-enough"
+	input.Role = RoleTeacher
+	input.Groups = &domain.UserGroups{AcademicGroup: "ИТ24-11"}
+	if _, err := svc.Create(ctx, input); !errors.Is(err, ErrInvalidLocalAccount) {
+		t.Errorf("expected %v, got %v", ErrInvalidLocalAccount, err)
+	}
+
+	input.Groups = nil
+	account, err := svc.Create(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if account.ID != "proctor" {
+		t.Errorf("expected id %q, got %q", "proctor", account.ID)
+	}
+	if ok, _ := passhash.Verify("long-enough", accounts["proctor"].PasswordHash); !ok {
+		t.Error("expected the stored hash to match the password")
+	}
+	if _, err := svc.Create(ctx, input); !errors.Is(err, repository.ErrLocalAccountExists) {
+		t.Errorf("expected %v, got %v", repository.ErrLocalAccountExists, err)
+	}
+
+	updated, err := svc.Update(ctx, LocalAccountInput{ID: " Proctor ", Username: "Проктор", Role: RoleTeacher})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Username != "Проктор" {
+		t.Errorf("expected username %q, got %q", "Проктор", updated.Username)
+	}
+
+	if _, err := svc.Get(ctx, "PROCTOR"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := svc.SetPassword(ctx, " Proctor", "another-one"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tokens := repos.PersonalTokenRepo.(memoryPersonalTokens)
+	tokens["pat1"] = domain.PersonalToken{ID: "pat1", UserID: "proctor"}
+	if err := svc.Delete(ctx, "Proctor"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sessions.revoked) != 2 || sessions.revoked[0] != "proctor" || sessions.revoked[1] != "proctor" {
+		t.Errorf("expected sessions to be revoked twice, got %v", sessions.revoked)
+	}
+	if len(tokens) != 0 {
+		t.Errorf("expected personal access tokens to be revoked, got %v", tokens)
+	}
+	if err := svc.Delete(ctx, "proctor"); !errors.Is(err, repository.ErrLocalAccountNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrLocalAccountNotFound, err)
+	}
+}
+
+func TestProfileLoaderReloadsLocalAccount(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	repos, accounts, sessions := newLocalAccountRepos(t, srv.URL())
+	profiles := repos.ProfileRepo.(memoryProfiles)
+	loader := newProfileLoader(repos, &config.ProfileConfig{StaleAfter: time.Hour})
+
+	old := time.Now().Add(-2 * time.Hour)
+	accounts["guest"] = domain.LocalAccount{ID: "guest", Username: "Новое имя", Role: RoleTeacher}
+	profiles["guest"] = domain.UserProfile{ID: "guest", Username: "Старое имя", Role: RoleTeacher, Source: domain.ProfileSourceLocal, SyncedAt: old}
+
+	profile, err := loader.load(context.Background(), "guest", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Username != "Новое имя" {
+		t.Errorf("expected %q, got %q", "Новое имя", profile.Username)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	account := accounts["guest"]
+	account.ExpiresAt = &past
+	accounts["guest"] = account
+	stored := profiles["guest"]
+	stored.SyncedAt = old
+	profiles["guest"] = stored
+
+	if _, err := loader.load(context.Background(), "guest", true); err == nil || err.Error() != "account disabled" {
+		t.Errorf("expected error %q, got %v", "account disabled", err)
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != "guest" {
+		t.Errorf("expected sessions of guest to be revoked, got %v", sessions.revoked)
+	}
+}
+
+func TestChangePasswordOfLocalAccount(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	repos, accounts, _ := newLocalAccountRepos(t, srv.URL())
+	tm := auth.NewManager(&config.Config{JWT: config.JWTConfig{SigningKey: "test-key"}})
+	svc := NewPasswordService(*tm, repos, &config.PasswordConfig{MinLength: 8}, &config.App{})
+	ctx := context.Background()
+
+	hash, err := passhash.Hash("guest-pass")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	accounts["guest.lecturer"] = domain.LocalAccount{ID: "guest.lecturer", PasswordHash: hash, Role: RoleTeacher}
+
+	input := ChangePasswordInput{UserID: "guest.lecturer", OldPassword: "wrong-pass", NewPassword: "brand-new-pass"}
+	if err := svc.ChangePassword(ctx, input); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+
+	input.OldPassword = "guest-pass"
+	if err := svc.ChangePassword(ctx, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok, _ := passhash.Verify("brand-new-pass", accounts["guest.lecturer"].PasswordHash); !ok {
+		t.Error("expected the local password to be replaced")
+	}
+
+	input.UserID = "nobody"
+	if err := svc.ChangePassword(ctx, input); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCredentials, err)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/passhash"
+)
+
+type ChangePasswordInput struct {
+	UserID              string
+	OldPassword         string
+	NewPassword         string
+	RevokeOtherSessions bool
+	CurrentRefreshToken string
+}
+
+type PasswordService interface {
+	ChangePassword(ctx context.Context, input ChangePasswordInput) error
+}
+
+// WeakPasswordError lists every strength rule the new password violates.
+type WeakPasswordError struct {
+	Violations []string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return "password does not meet requirements: " + strings.Join(e.Violations, ", ")
+}
+
+type PasswordServiceImpl struct {
+	tokenManager *auth.Manager
+	repos        Repositories
+	cfg          *config.PasswordConfig
+	appCfg       *config.App
+}
+
+func NewPasswordService(tm auth.Manager, repos Repositories, cfg *config.PasswordConfig, appCfg *config.App) *PasswordServiceImpl {
+	return &PasswordServiceImpl{
+		tokenManager: &tm,
+		repos:        repos,
+		cfg:          cfg,
+		appCfg:       appCfg,
+	}
+}
+
+func (p *PasswordServiceImpl) ChangePassword(ctx context.Context, input ChangePasswordInput) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if input.OldPassword == "" || input.NewPassword == "" {
+		return fmt.Errorf("old and new password are required")
+	}
+
+	if p.appCfg.Test {
+		return fmt.Errorf("password change is not available in test mode")
+	}
+
+	if input.OldPassword == input.NewPassword {
+		return &WeakPasswordError{Violations: []string{"must differ from the current password"}}
+	}
+
+	if err := CheckPasswordStrength(p.cfg, input.UserID, input.NewPassword); err != nil {
+		return err
+	}
+
+	if err := p.changePassword(ctx, input); err != nil {
+		logger.Error(fmt.Errorf("password change failed for user %s: %w", input.UserID, err))
+		return err
+	}
+
+	if err := revokePersonalTokens(ctx, p.repos, input.UserID); err != nil {
+		return fmt.Errorf("password changed, but %w", err)
+	}
+
+	if !input.RevokeOtherSessions {
+		return nil
+	}
+
+	keepJTI := p.sessionJTI(input.UserID, input.CurrentRefreshToken)
+	if keepJTI == "" {
+		err := p.repos.SessionRepo.RevokeAllUserSessions(ctx, input.UserID)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to revoke sessions after password change for user %s: %w", input.UserID, err))
+			return fmt.Errorf("password changed, but failed to revoke sessions")
+		}
+		return nil
+	}
+
+	if err := p.repos.SessionRepo.RevokeOtherUserSessions(ctx, input.UserID, keepJTI); err != nil {
+		logger.Error(fmt.Errorf("failed to revoke other sessions after password change for user %s: %w", input.UserID, err))
+		return fmt.Errorf("password changed, but failed to revoke sessions")
+	}
+
+	return nil
+}
+
+// changePassword stores the new password where the account lives. As at
+// sign-in, LDAP is asked first and local accounts only hold users it does not
+// know; they keep a password hash in Mongo.
+func (p *PasswordServiceImpl) changePassword(ctx context.Context, input ChangePasswordInput) error {
+	err := p.repos.UserRepo.ChangePassword(ctx, input.UserID, input.OldPassword, input.NewPassword)
+	if !errors.Is(err, domain.ErrAccountNotFound) {
+		return err
+	}
+
+	account, err := p.repos.LocalAccountRepo.Get(ctx, input.UserID)
+	if errors.Is(err, repository.ErrLocalAccountNotFound) {
+		return domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get local account: %w", err)
+	}
+
+	if ok, err := passhash.Verify(input.OldPassword, account.PasswordHash); err != nil || !ok {
+		logger.Warn(fmt.Sprintf("password change for local account %s rejected: wrong current password", input.UserID))
+		return domain.ErrInvalidCredentials
+	}
+
+	hash, err := passhash.Hash(input.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return p.repos.LocalAccountRepo.SetPassword(ctx, input.UserID, hash)
+}
+
+// sessionJTI returns the JTI of the caller's own refresh token so it survives
+// revocation, or an empty string if the token is missing or foreign.
+func (p *PasswordServiceImpl) sessionJTI(userID, refreshToken string) string {
+	if refreshToken == "" {
+		return ""
+	}
+
+	if err := p.tokenManager.ValidateRefreshToken(refreshToken); err != nil {
+		return ""
+	}
+
+	owner, err := p.tokenManager.ExtractClaim(refreshToken, "user_id")
+	if err != nil || owner != userID {
+		return ""
+	}
+
+	jti, err := p.tokenManager.ExtractClaim(refreshToken, "jti")
+	if err != nil {
+		return ""
+	}
+
+	return jti
+}
+
+func CheckPasswordStrength(cfg *config.PasswordConfig, userID, password string) error {
+	var violations []string
+
+	if cfg.MinLength > 0 && utf8.RuneCountInString(password) < cfg.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", cfg.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSpecial = true
+		}
+	}
+
+	if cfg.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if cfg.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if cfg.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if cfg.RequireSpecial && !hasSpecial {
+		violations = append(violations, "must contain a special character")
+	}
+	if cfg.ForbidUserID && userID != "" && strings.Contains(strings.ToLower(password), strings.ToLower(userID)) {
+		violations = append(violations, "must not contain the user ID")
+	}
+
+	if len(violations) > 0 {
+		return &WeakPasswordError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/notify"
+)
+
+const (
+	passwordResetPurpose = "password-reset"
+
+	defaultResetWindow   = time.Hour
+	defaultResetsPerUser = 3
+	defaultResetsPerIP   = 20
+	resetDeliveryTimeout = 30 * time.Second
+)
+
+var ErrResetThrottled = errors.New("too many password reset requests")
+
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, userID, clientIP string) error
+	ConfirmReset(ctx context.Context, token, newPassword string) error
+}
+
+type PasswordResetServiceImpl struct {
+	tokenManager *auth.Manager
+	repos        Repositories
+	notifier     notify.Notifier
+	cfg          *config.Config
+	window       time.Duration
+	perUser      int
+	perIP        int
+	// pending tracks deliveries still running in the background.
+	pending sync.WaitGroup
+}
+
+func NewPasswordResetService(tm auth.Manager, repos Repositories, notifier notify.Notifier, cfg *config.Config) *PasswordResetServiceImpl {
+	p := &PasswordResetServiceImpl{
+		tokenManager: &tm,
+		repos:        repos,
+		notifier:     notifier,
+		cfg:          cfg,
+		window:       cfg.Reset.Window,
+		perUser:      cfg.Reset.MaxPerUser,
+		perIP:        cfg.Reset.MaxPerIP,
+	}
+
+	if p.window <= 0 {
+		p.window = defaultResetWindow
+	}
+	if p.perUser <= 0 {
+		p.perUser = defaultResetsPerUser
+	}
+	if p.perIP <= 0 {
+		p.perIP = defaultResetsPerIP
+	}
+
+	return p
+}
+
+// RequestReset throttles the request per user ID and client IP and hands the
+// rest to the background: whether the account exists, has a mail address or
+// the mail could be sent never changes the result, so the caller cannot
+// probe which accounts exist. Earlier tokens stay valid until they expire or
+// one of them is used.
+func (p *PasswordResetServiceImpl) RequestReset(ctx context.Context, userID, clientIP string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if userID == "" {
+		return fmt.Errorf("empty user id")
+	}
+
+	if p.cfg.App.Test {
+		return fmt.Errorf("password reset is not available in test mode")
+	}
+
+	for _, limit := range []struct {
+		key string
+		max int
+	}{
+		{key: "user:" + strings.ToLower(userID), max: p.perUser},
+		{key: "ip:" + clientIP, max: p.perIP},
+	} {
+		count, err := p.repos.ResetRepo.CountAttempt(ctx, limit.key, p.window)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to throttle password reset for %s: %w", limit.key, err))
+			return fmt.Errorf("failed to create password reset")
+		}
+		if count > limit.max {
+			logger.Warn(fmt.Sprintf("password reset for user %s from %s throttled on %s", userID, clientIP, limit.key))
+			return ErrResetThrottled
+		}
+	}
+
+	p.pending.Add(1)
+	go func() {
+		defer p.pending.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), resetDeliveryTimeout)
+		defer cancel()
+		p.deliver(ctx, userID)
+	}()
+
+	return nil
+}
+
+// deliver issues a reset token and mails it. Every failure is only logged.
+func (p *PasswordResetServiceImpl) deliver(ctx context.Context, userID string) {
+	mail, err := p.repos.UserRepo.GetMail(ctx, userID)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("password reset requested for unknown user %s: %v", userID, err))
+		return
+	}
+
+	if mail == "" {
+		logger.Warn(fmt.Sprintf("password reset requested for user %s without a mail address", userID))
+		return
+	}
+
+	token, hash, err := p.tokenManager.NewOpaqueToken(passwordResetPurpose)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to generate password reset token for user %s: %w", userID, err))
+		return
+	}
+
+	reset := domain.PasswordReset{
+		TokenHash: hash,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(p.cfg.Reset.TokenTTL),
+		CreatedAt: time.Now(),
+	}
+
+	if err := p.repos.ResetRepo.Create(ctx, &reset); err != nil {
+		return
+	}
+
+	link := strings.ReplaceAll(p.cfg.Reset.LinkURL, "{token}", token)
+	msg := notify.Message{
+		To:      mail,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Для учётной записи %s запрошен сброс пароля.\n\n"+
+			"Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует %s. Если вы не запрашивали сброс, просто проигнорируйте это письмо.\n",
+			userID, link, p.cfg.Reset.TokenTTL),
+	}
+
+	if err := p.notifier.Send(ctx, msg); err != nil {
+		logger.Error(fmt.Errorf("failed to deliver password reset for user %s: %w", userID, err))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("password reset issued for user %s", userID))
+}
+
+func (p *PasswordResetServiceImpl) ConfirmReset(ctx context.Context, token, newPassword string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if token == "" || newPassword == "" {
+		return fmt.Errorf("token and new password are required")
+	}
+
+	hash, err := p.tokenManager.VerifyOpaqueToken(passwordResetPurpose, token)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("invalid password reset token: %v", err))
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	reset, err := p.repos.ResetRepo.GetActive(ctx, hash)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("password reset token rejected: %v", err))
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	if err := CheckPasswordStrength(&p.cfg.Password, reset.UserID, newPassword); err != nil {
+		return err
+	}
+
+	// Consuming first keeps concurrent confirmations from both resetting the
+	// password; the token is released again if LDAP refuses the new one.
+	if _, err := p.repos.ResetRepo.Consume(ctx, hash); err != nil {
+		logger.Warn(fmt.Sprintf("password reset token for user %s already used: %v", reset.UserID, err))
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	if err := p.repos.UserRepo.ResetPassword(ctx, reset.UserID, newPassword); err != nil {
+		logger.Error(fmt.Errorf("password reset failed for user %s: %w", reset.UserID, err))
+		if releaseErr := p.repos.ResetRepo.Release(context.WithoutCancel(ctx), hash); releaseErr != nil {
+			logger.Error(fmt.Errorf("failed to release password reset token of user %s: %w", reset.UserID, releaseErr))
+		}
+		return err
+	}
+
+	if err := p.repos.ResetRepo.DeleteForUser(ctx, reset.UserID); err != nil {
+		logger.Warn(fmt.Sprintf("failed to drop remaining password resets of user %s: %v", reset.UserID, err))
+	}
+
+	if err := p.repos.SessionRepo.RevokeAllUserSessions(ctx, reset.UserID); err != nil {
+		logger.Error(fmt.Errorf("failed to revoke sessions after password reset for user %s: %w", reset.UserID, err))
+	}
+	if err := revokePersonalTokens(ctx, p.repos, reset.UserID); err != nil {
+		return fmt.Errorf("password reset, but %w", err)
+	}
+
+	return nil
+}
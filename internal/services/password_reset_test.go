@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+	"github.com/anton1ks96/college-auth-svc/pkg/notify"
+)
+
+type memoryResets struct {
+	mu       sync.Mutex
+	resets   map[string]domain.PasswordReset
+	attempts map[string]int
+}
+
+func newMemoryResets() *memoryResets {
+	return &memoryResets{resets: make(map[string]domain.PasswordReset), attempts: make(map[string]int)}
+}
+
+func (m *memoryResets) Create(_ context.Context, reset *domain.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resets[reset.TokenHash] = *reset
+	return nil
+}
+
+func (m *memoryResets) GetActive(_ context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reset, ok := m.resets[tokenHash]
+	if !ok || reset.UsedAt != nil || !reset.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("reset token not found or expired")
+	}
+	return &reset, nil
+}
+
+func (m *memoryResets) Consume(ctx context.Context, tokenHash string) (*domain.PasswordReset, error) {
+	reset, err := m.GetActive(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	reset.UsedAt = &now
+	m.resets[tokenHash] = *reset
+	return reset, nil
+}
+
+func (m *memoryResets) Release(_ context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reset := m.resets[tokenHash]
+	reset.UsedAt = nil
+	m.resets[tokenHash] = reset
+	return nil
+}
+
+func (m *memoryResets) DeleteForUser(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, reset := range m.resets {
+		if reset.UserID == userID {
+			delete(m.resets, hash)
+		}
+	}
+	return nil
+}
+
+func (m *memoryResets) CountAttempt(_ context.Context, key string, _ time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts[key]++
+	return m.attempts[key], nil
+}
+
+type memoryNotifier struct {
+	mu       sync.Mutex
+	messages []notify.Message
+}
+
+func (m *memoryNotifier) Send(_ context.Context, msg notify.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege, ldaptest.WithManager(ldaptest.ServiceDN))
+	repos, _, _ := newLocalAccountRepos(t, srv.URL())
+	resets := newMemoryResets()
+	repos.ResetRepo = resets
+
+	cfg := &config.Config{
+		JWT:      config.JWTConfig{AccessTokenTTL: "60m", SigningKey: "test-key"},
+		Reset:    config.ResetConfig{TokenTTL: time.Hour, LinkURL: "https://portal/reset?token={token}", MaxPerUser: 2, MaxPerIP: 5},
+		Password: config.PasswordConfig{MinLength: 8},
+	}
+	notifier := &memoryNotifier{}
+	svc := NewPasswordResetService(*auth.NewManager(cfg), repos, notifier, cfg)
+	ctx := context.Background()
+
+	// Known and unknown users get the same answer.
+	for _, userID := range []string{"i24s0001", "nobody", "i24s0001"} {
+		if err := svc.RequestReset(ctx, userID, "10.0.0.1"); err != nil {
+			t.Fatalf("unexpected error for %s: %v", userID, err)
+		}
+	}
+	if err := svc.RequestReset(ctx, "i24s0001", "10.0.0.1"); !errors.Is(err, ErrResetThrottled) {
+		t.Errorf("expected %v, got %v", ErrResetThrottled, err)
+	}
+	for _, userID := range []string{"ghost1", "ghost2"} {
+		if err := svc.RequestReset(ctx, userID, "10.0.0.1"); err != nil {
+			t.Fatalf("unexpected error for %s: %v", userID, err)
+		}
+	}
+	if err := svc.RequestReset(ctx, "ghost3", "10.0.0.1"); !errors.Is(err, ErrResetThrottled) {
+		t.Errorf("expected the per-IP limit to apply, got %v", err)
+	}
+	svc.pending.Wait()
+
+	if len(notifier.messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(notifier.messages))
+	}
+	// Both tokens of i24s0001 stay valid.
+	if len(resets.resets) != 2 {
+		t.Errorf("expected 2 outstanding tokens, got %d", len(resets.resets))
+	}
+
+	token := notifier.messages[0].Body[strings.Index(notifier.messages[0].Body, "token=")+len("token="):]
+	token = strings.Fields(token)[0]
+
+	// LDAP refuses the reset of an account it does not know; the token
+	// stays usable.
+	for hash, reset := range resets.resets {
+		reset.UserID = "ghost"
+		resets.resets[hash] = reset
+	}
+	if err := svc.ConfirmReset(ctx, token, "Ab12345678"); err == nil {
+		t.Fatal("expected the reset to fail")
+	}
+	for hash, reset := range resets.resets {
+		reset.UserID = "i24s0001"
+		resets.resets[hash] = reset
+	}
+
+	if err := svc.ConfirmReset(ctx, token, "Ab12345678"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resets.resets) != 0 {
+		t.Errorf("expected the remaining tokens to be dropped, got %d", len(resets.resets))
+	}
+	if err := svc.ConfirmReset(ctx, token, "Ab12345678"); err == nil {
+		t.Error("expected a used token to be refused")
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+)
+
+const (
+	defaultPersonalTokenTTL = 90 * 24 * time.Hour
+	maxPersonalTokenName    = 64
+	// touchInterval limits last-used writes for tokens used in a tight loop.
+	touchInterval = time.Minute
+)
+
+var ErrInvalidPersonalToken = errors.New("invalid personal access token")
+
+type PersonalTokenInput struct {
+	UserID    string
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type PersonalTokenService interface {
+	Create(ctx context.Context, input PersonalTokenInput) (string, *domain.PersonalToken, error)
+	List(ctx context.Context, userID string) ([]domain.PersonalToken, error)
+	Revoke(ctx context.Context, userID, id string) error
+	Authenticate(ctx context.Context, token string) (*domain.UserExtended, error)
+}
+
+type PersonalTokenServiceImpl struct {
+	personalTokens
+	cfg *config.PersonalTokenConfig
+}
+
+func NewPersonalTokenService(tm auth.Manager, repos Repositories, cfg *config.PersonalTokenConfig, profileCfg *config.ProfileConfig) *PersonalTokenServiceImpl {
+	return &PersonalTokenServiceImpl{
+		personalTokens: newPersonalTokens(&tm, repos, profileCfg),
+		cfg:            cfg,
+	}
+}
+
+// Create issues a token for the user. The token itself is only returned
+// here; afterwards it is known by its ID and name.
+func (p *PersonalTokenServiceImpl) Create(ctx context.Context, input PersonalTokenInput) (string, *domain.PersonalToken, error) {
+	if ctx.Err() != nil {
+		return "", nil, ctx.Err()
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > maxPersonalTokenName {
+		return "", nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidPersonalToken, maxPersonalTokenName)
+	}
+
+	if len(input.Scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidPersonalToken)
+	}
+	scopes := slices.Compact(slices.Sorted(slices.Values(input.Scopes)))
+	for _, scope := range scopes {
+		if !slices.Contains(p.cfg.Scopes, scope) {
+			return "", nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidPersonalToken, scope)
+		}
+	}
+
+	now := time.Now()
+	expiresAt, err := p.expiry(now, input.ExpiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	existing, err := p.repos.PersonalTokenRepo.ListForUser(ctx, input.UserID)
+	if err != nil {
+		return "", nil, err
+	}
+	// The TTL index removes expired tokens, but only about once a minute.
+	existing = slices.DeleteFunc(existing, func(t domain.PersonalToken) bool { return t.Expired(now) })
+	if p.cfg.MaxPerUser > 0 && len(existing) >= p.cfg.MaxPerUser {
+		return "", nil, fmt.Errorf("%w: limit of %d tokens reached", ErrInvalidPersonalToken, p.cfg.MaxPerUser)
+	}
+
+	token, hash, err := p.tokenManager.NewPersonalToken()
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to generate personal access token for user %s: %w", input.UserID, err))
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	record := &domain.PersonalToken{
+		ID:        hex.EncodeToString(id),
+		UserID:    input.UserID,
+		Name:      name,
+		Scopes:    scopes,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}
+	if err := p.repos.PersonalTokenRepo.Create(ctx, record); err != nil {
+		return "", nil, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("user %s created personal access token %s (%s)", input.UserID, record.ID, strings.Join(scopes, ",")))
+	return token, record, nil
+}
+
+func (p *PersonalTokenServiceImpl) expiry(now time.Time, requested *time.Time) (time.Time, error) {
+	maxTTL := p.cfg.MaxTTL
+	if requested == nil {
+		ttl := p.cfg.DefaultTTL
+		if ttl <= 0 {
+			ttl = defaultPersonalTokenTTL
+		}
+		if maxTTL > 0 && ttl > maxTTL {
+			ttl = maxTTL
+		}
+		return now.Add(ttl), nil
+	}
+
+	if !requested.After(now) {
+		return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidPersonalToken)
+	}
+	if maxTTL > 0 && requested.Sub(now) > maxTTL {
+		return time.Time{}, fmt.Errorf("%w: expires_at is more than %s away", ErrInvalidPersonalToken, maxTTL)
+	}
+	return *requested, nil
+}
+
+func (p *PersonalTokenServiceImpl) List(ctx context.Context, userID string) ([]domain.PersonalToken, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return p.repos.PersonalTokenRepo.ListForUser(ctx, userID)
+}
+
+func (p *PersonalTokenServiceImpl) Revoke(ctx context.Context, userID, id string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err := p.repos.PersonalTokenRepo.Delete(ctx, userID, id); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("user %s revoked personal access token %s", userID, id))
+	return nil
+}
+
+// revokePersonalTokens deletes every token of a user whose password was
+// changed or reset or whose account was removed.
+func revokePersonalTokens(ctx context.Context, repos Repositories, userID string) error {
+	if err := repos.PersonalTokenRepo.DeleteForUser(ctx, userID); err != nil {
+		logger.Error(fmt.Errorf("failed to revoke personal access tokens of %s: %w", userID, err))
+		return fmt.Errorf("failed to revoke personal access tokens: %w", err)
+	}
+	return nil
+}
+
+func (p *PersonalTokenServiceImpl) Authenticate(ctx context.Context, token string) (*domain.UserExtended, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return p.authenticate(ctx, token)
+}
+
+// personalTokens resolves personal access tokens to their owner wherever
+// access tokens are accepted.
+type personalTokens struct {
+	tokenManager *auth.Manager
+	repos        Repositories
+	profiles     profileLoader
+	gate         accessGate
+}
+
+func newPersonalTokens(tm *auth.Manager, repos Repositories, profileCfg *config.ProfileConfig) personalTokens {
+	return personalTokens{
+		tokenManager: tm,
+		repos:        repos,
+		profiles:     newProfileLoader(repos, profileCfg),
+		gate:         accessGate{repos: repos},
+	}
+}
+
+// authenticate returns the current profile of the token owner with the
+// token's scopes. Every use is treated like an access token request: the
+// profile is resynced when stale, and suspensions and the access policy
+// apply.
+func (p personalTokens) authenticate(ctx context.Context, token string) (*domain.UserExtended, error) {
+	hash, err := p.tokenManager.VerifyPersonalToken(token)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("malformed personal access token: %v", err))
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	record, err := p.repos.PersonalTokenRepo.GetByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrPersonalTokenNotFound) {
+			logger.Warn("use of unknown or revoked personal access token")
+			return nil, fmt.Errorf("invalid token")
+		}
+		logger.Error(fmt.Errorf("failed to look up personal access token: %w", err))
+		return nil, fmt.Errorf("authentication service unavailable")
+	}
+
+	now := time.Now()
+	if record.Expired(now) {
+		logger.Warn(fmt.Sprintf("use of expired personal access token %s of user %s", record.ID, record.UserID))
+		return nil, fmt.Errorf("token expired")
+	}
+
+	profile, err := p.profiles.load(ctx, record.UserID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.gate.checkProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= touchInterval {
+		if err := p.repos.PersonalTokenRepo.Touch(ctx, record.ID, now); err != nil {
+			logger.Warn(fmt.Sprintf("failed to record use of personal access token %s: %v", record.ID, err))
+		}
+	}
+
+	user := profile.Extended()
+	user.Scopes = record.Scopes
+	return user, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+)
+
+type memoryPersonalTokens map[string]domain.PersonalToken
+
+func (m memoryPersonalTokens) Create(_ context.Context, token *domain.PersonalToken) error {
+	m[token.ID] = *token
+	return nil
+}
+
+func (m memoryPersonalTokens) GetByHash(_ context.Context, tokenHash string) (*domain.PersonalToken, error) {
+	for _, token := range m {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrPersonalTokenNotFound
+}
+
+func (m memoryPersonalTokens) ListForUser(_ context.Context, userID string) ([]domain.PersonalToken, error) {
+	tokens := []domain.PersonalToken{}
+	for _, token := range m {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (m memoryPersonalTokens) Delete(_ context.Context, userID, id string) error {
+	token, ok := m[id]
+	if !ok || token.UserID != userID {
+		return repository.ErrPersonalTokenNotFound
+	}
+	delete(m, id)
+	return nil
+}
+
+func (m memoryPersonalTokens) DeleteForUser(_ context.Context, userID string) error {
+	for id, token := range m {
+		if token.UserID == userID {
+			delete(m, id)
+		}
+	}
+	return nil
+}
+
+func (m memoryPersonalTokens) Touch(_ context.Context, id string, usedAt time.Time) error {
+	token := m[id]
+	token.LastUsedAt = &usedAt
+	m[id] = token
+	return nil
+}
+
+func TestPersonalTokens(t *testing.T) {
+	tokens := memoryPersonalTokens{}
+	access := &memoryAccess{suspensions: map[string]domain.Suspension{}}
+	repos := Repositories{
+		ProfileRepo:       memoryProfiles{"t001": {ID: "t001", Username: "Петров Пётр", Role: RoleTeacher}},
+		PersonalTokenRepo: tokens,
+		AccessRepo:        access,
+	}
+
+	tm := auth.NewManager(&config.Config{JWT: config.JWTConfig{AccessTokenTTL: "60m", SigningKey: "test-key"}})
+	cfg := &config.PersonalTokenConfig{Scopes: []string{"export", "grades:write"}, MaxTTL: 365 * 24 * time.Hour, MaxPerUser: 2}
+	svc := NewPersonalTokenService(*tm, repos, cfg, &config.ProfileConfig{})
+	users := NewAppUserService(*tm, repos, time.Hour, time.Hour, &config.ProfileConfig{}, nil)
+	ctx := context.Background()
+
+	token, record, err := svc.Create(ctx, PersonalTokenInput{UserID: "t001", Name: " grade import ", Scopes: []string{"grades:write", "export", "export"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(token, auth.PersonalTokenPrefix) {
+		t.Errorf("expected token to start with %q, got %q", auth.PersonalTokenPrefix, token)
+	}
+	if record.Name != "grade import" || !reflect.DeepEqual(record.Scopes, []string{"export", "grades:write"}) {
+		t.Errorf("unexpected token record %+v", record)
+	}
+	if strings.Contains(record.TokenHash, strings.TrimPrefix(token, auth.PersonalTokenPrefix)) {
+		t.Error("expected only a hash of the token to be stored")
+	}
+	if want := time.Now().Add(defaultPersonalTokenTTL); record.ExpiresAt.After(want) {
+		t.Errorf("expected default expiry before %s, got %s", want, record.ExpiresAt)
+	}
+
+	user, err := users.ValidateAccessToken(ctx, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.ID != "t001" || !reflect.DeepEqual(user.Scopes, record.Scopes) {
+		t.Errorf("expected t001 with scopes %v, got %q with %v", record.Scopes, user.ID, user.Scopes)
+	}
+	if tokens[record.ID].LastUsedAt == nil {
+		t.Error("expected the token use to be recorded")
+	}
+
+	later := time.Now().Add(2 * 365 * 24 * time.Hour)
+	invalid := []PersonalTokenInput{
+		{UserID: "t001", Scopes: []string{"export"}},
+		{UserID: "t001", Name: "no scopes"},
+		{UserID: "t001", Name: "admin", Scopes: []string{"admin"}},
+		{UserID: "t001", Name: "forever", Scopes: []string{"export"}, ExpiresAt: &later},
+	}
+	for _, input := range invalid {
+		if _, _, err := svc.Create(ctx, input); !errors.Is(err, ErrInvalidPersonalToken) {
+			t.Errorf("expected %v for %+v, got %v", ErrInvalidPersonalToken, input, err)
+		}
+	}
+
+	if _, _, err := svc.Create(ctx, PersonalTokenInput{UserID: "t001", Name: "second", Scopes: []string{"export"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := svc.Create(ctx, PersonalTokenInput{UserID: "t001", Name: "third", Scopes: []string{"export"}}); !errors.Is(err, ErrInvalidPersonalToken) {
+		t.Errorf("expected the per-user limit to apply, got %v", err)
+	}
+
+	access.suspensions["t001"] = domain.Suspension{UserID: "t001", Reason: "left the college"}
+	if _, err := svc.Authenticate(ctx, token); !errors.Is(err, domain.ErrAccountSuspended) {
+		t.Errorf("expected %v, got %v", domain.ErrAccountSuspended, err)
+	}
+	delete(access.suspensions, "t001")
+
+	expired := tokens[record.ID]
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	tokens[record.ID] = expired
+	if _, err := svc.Authenticate(ctx, token); err == nil {
+		t.Error("expected an expired token to be refused")
+	}
+
+	if err := svc.Revoke(ctx, "t002", record.ID); !errors.Is(err, repository.ErrPersonalTokenNotFound) {
+		t.Errorf("expected another user's token to be hidden, got %v", err)
+	}
+	if err := svc.Revoke(ctx, "t001", record.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, token); err == nil {
+		t.Error("expected a revoked token to be refused")
+	}
+
+	if _, err := svc.Authenticate(ctx, auth.PersonalTokenPrefix+"forged.signature"); err == nil {
+		t.Error("expected a forged token to be refused")
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+)
+
+type ProfileService interface {
+	Get(ctx context.Context, userID string) (*domain.UserProfile, error)
+	SetFlags(ctx context.Context, userID string, flags []string) error
+}
+
+type ProfileServiceImpl struct {
+	repos Repositories
+}
+
+func NewProfileService(repos Repositories) *ProfileServiceImpl {
+	return &ProfileServiceImpl{repos: repos}
+}
+
+func (p *ProfileServiceImpl) Get(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return p.repos.ProfileRepo.GetByID(ctx, userID)
+}
+
+// SetFlags replaces the flags of a stored profile. Flags are lower-cased and
+// deduplicated; an empty list clears them.
+func (p *ProfileServiceImpl) SetFlags(ctx context.Context, userID string, flags []string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	normalized := []string{}
+	for _, flag := range flags {
+		flag = strings.ToLower(strings.TrimSpace(flag))
+		if flag != "" && !slices.Contains(normalized, flag) {
+			normalized = append(normalized, flag)
+		}
+	}
+
+	if err := p.repos.ProfileRepo.SetFlags(ctx, userID, normalized); err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("user %s flags=%v", userID, normalized))
+	return nil
+}
+
+// errAccountDisabled is returned when a reload finds that the user lost
+// access at the source.
+var errAccountDisabled = errors.New("account disabled")
+
+// profileLoader resolves the profile behind a token for the sign-in services.
+type profileLoader struct {
+	repos      Repositories
+	staleAfter time.Duration
+	// storedOnly skips creating missing profiles, for callers that must
+	// only see users who have signed in.
+	storedOnly bool
+	// keepSessions reports lost access without revoking the user's
+	// sessions, for callers acting on someone else's request.
+	keepSessions bool
+}
+
+func newProfileLoader(repos Repositories, cfg *config.ProfileConfig) profileLoader {
+	return profileLoader{repos: repos, staleAfter: cfg.StaleAfter}
+}
+
+// load returns the stored profile of a token holder. With resync, a
+// directory or local profile older than the staleness window is reloaded from
+// its source first; if the user has lost access there, all of their sessions
+// are revoked. An unreachable LDAP keeps the stored data.
+func (p profileLoader) load(ctx context.Context, userID string, resync bool) (*domain.UserProfile, error) {
+	profile, err := p.repos.ProfileRepo.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrProfileNotFound) && !p.storedOnly {
+		return p.create(ctx, userID)
+	}
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get profile for user %s: %w", userID, err))
+		return nil, fmt.Errorf("failed to get user data: %w", err)
+	}
+
+	if !resync || !p.stale(profile) {
+		return profile, nil
+	}
+
+	if profile.Source == domain.ProfileSourceLocal {
+		return p.reloadLocal(ctx, profile)
+	}
+
+	fresh, err := p.reloadLDAP(ctx, userID)
+	switch {
+	case lostAccess(err):
+		p.revoke(ctx, userID, err)
+		return nil, errAccountDisabled
+	case err != nil:
+		logger.Warn(fmt.Sprintf("failed to reload stale profile of %s, using stored data: %v", userID, err))
+		return profile, nil
+	}
+
+	logger.Debug(fmt.Sprintf("reloaded stale profile of %s from LDAP", userID))
+
+	fresh.Flags = profile.Flags
+	fresh.CreatedAt = profile.CreatedAt
+	fresh.LastLoginAt = profile.LastLoginAt
+	return fresh, nil
+}
+
+// create stores the missing profile of a token issued before profiles were
+// kept. Such tokens only ever belonged to LDAP users.
+func (p profileLoader) create(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	fresh, err := p.reloadLDAP(ctx, userID)
+	switch {
+	case lostAccess(err):
+		p.revoke(ctx, userID, err)
+		return nil, errAccountDisabled
+	case err != nil:
+		logger.Error(fmt.Errorf("failed to create missing profile of %s: %w", userID, err))
+		return nil, fmt.Errorf("failed to get user data: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("created missing profile of %s from LDAP", userID))
+
+	fresh.CreatedAt = time.Now()
+	return fresh, nil
+}
+
+// reloadLDAP reads the profile from LDAP and stores it. While LDAP is
+// unreachable the directory mirror, when enabled, answers instead; its data
+// is served but not stored, so LDAP is asked again on the next use. Only
+// LDAP itself can report lost access.
+func (p profileLoader) reloadLDAP(ctx context.Context, userID string) (*domain.UserProfile, error) {
+	fresh, err := p.repos.UserRepo.Reload(ctx, userID)
+	mirrored := false
+	if errors.Is(err, ldappool.ErrUnavailable) {
+		if fromMirror, mirrorErr := p.repos.UserRepo.ReloadFromMirror(ctx, userID); mirrorErr == nil {
+			logger.Warn(fmt.Sprintf("LDAP unavailable, reloaded profile of %s from the directory mirror", userID))
+			fresh, err, mirrored = fromMirror, nil, true
+		} else {
+			logger.Debug(fmt.Sprintf("no mirrored profile of %s: %v", userID, mirrorErr))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Groups are only kept for students, as on app sign-in.
+	if fresh.Role == "teacher" || fresh.Role == "admin" {
+		fresh.Groups = &domain.UserGroups{}
+	}
+
+	if mirrored {
+		return fresh, nil
+	}
+
+	if err := p.repos.ProfileRepo.UpdateFromSource(ctx, fresh); err != nil {
+		logger.Warn(fmt.Sprintf("failed to store reloaded profile of %s: %v", userID, err))
+	}
+
+	return fresh, nil
+}
+
+func (p profileLoader) revoke(ctx context.Context, userID string, reason error) {
+	if p.keepSessions {
+		logger.Warn(fmt.Sprintf("user %s lost access, sessions kept: %v", userID, reason))
+		return
+	}
+
+	logger.Warn(fmt.Sprintf("revoking sessions of user %s: %v", userID, reason))
+	if err := p.repos.SessionRepo.RevokeAllUserSessions(ctx, userID); err != nil {
+		logger.Error(fmt.Errorf("failed to revoke sessions of user %s: %w", userID, err))
+	}
+}
+
+// lostAccess reports whether a reload error means the user may no longer
+// sign in, as opposed to LDAP being unreachable.
+func lostAccess(err error) bool {
+	return errors.Is(err, domain.ErrAccountNotFound) ||
+		errors.Is(err, domain.ErrAccountLocked) ||
+		errors.Is(err, domain.ErrAccountInactive)
+}
+
+// reloadLocal refreshes a local account profile. Deleted and expired
+// accounts lose their sessions like users removed from LDAP.
+func (p profileLoader) reloadLocal(ctx context.Context, profile *domain.UserProfile) (*domain.UserProfile, error) {
+	account, err := p.repos.LocalAccountRepo.Get(ctx, profile.ID)
+	switch {
+	case errors.Is(err, repository.ErrLocalAccountNotFound):
+		err = domain.ErrAccountNotFound
+	case err == nil && account.Expired(time.Now()):
+		err = domain.ErrAccountExpired
+	case err != nil:
+		logger.Warn(fmt.Sprintf("failed to reload stale profile of %s, using stored data: %v", profile.ID, err))
+		return profile, nil
+	}
+	if err != nil {
+		p.revoke(ctx, profile.ID, err)
+		return nil, errAccountDisabled
+	}
+
+	user := account.Extended()
+	fresh := &domain.UserProfile{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Roles:    user.Roles,
+		Groups: &domain.UserGroups{
+			AcademicGroup: user.AcademicGroup,
+			Profile:       user.Profile,
+			Subgroup:      user.Subgroup,
+			EnglishGroup:  user.EnglishGroup,
+			ExtraGroups:   user.ExtraGroups,
+		},
+		Source:   domain.ProfileSourceLocal,
+		SyncedAt: time.Now(),
+	}
+
+	if err := p.repos.ProfileRepo.UpdateFromSource(ctx, fresh); err != nil {
+		logger.Warn(fmt.Sprintf("failed to store reloaded profile of %s: %v", profile.ID, err))
+	}
+
+	fresh.Flags = profile.Flags
+	fresh.CreatedAt = profile.CreatedAt
+	fresh.LastLoginAt = profile.LastLoginAt
+	return fresh, nil
+}
+
+func (p profileLoader) stale(profile *domain.UserProfile) bool {
+	if profile.Source != domain.ProfileSourceLDAP && profile.Source != domain.ProfileSourceLocal {
+		return false
+	}
+	return p.staleAfter > 0 && time.Since(profile.SyncedAt) >= p.staleAfter
+}
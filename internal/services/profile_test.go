@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+type memoryProfiles map[string]domain.UserProfile
+
+func (m memoryProfiles) RecordSignIn(_ context.Context, profile *domain.UserProfile) error {
+	return m.UpdateFromSource(context.Background(), profile)
+}
+
+func (m memoryProfiles) UpdateFromSource(_ context.Context, profile *domain.UserProfile) error {
+	stored := *profile
+	stored.Flags = m[profile.ID].Flags
+	stored.SyncedAt = time.Now()
+	m[profile.ID] = stored
+	return nil
+}
+
+func (m memoryProfiles) GetByID(_ context.Context, userID string) (*domain.UserProfile, error) {
+	profile, ok := m[userID]
+	if !ok {
+		return nil, repository.ErrProfileNotFound
+	}
+	return &profile, nil
+}
+
+func (m memoryProfiles) SetFlags(_ context.Context, userID string, flags []string) error {
+	profile, ok := m[userID]
+	if !ok {
+		return repository.ErrProfileNotFound
+	}
+	profile.Flags = flags
+	m[userID] = profile
+	return nil
+}
+
+// memorySessions only tracks saved sessions and which users had their
+// sessions revoked.
+type memorySessions struct {
+	repository.SessionMongoRepository
+	saved   []domain.RefreshSession
+	revoked []string
+}
+
+func (m *memorySessions) SaveRefreshToken(_ context.Context, session *domain.RefreshSession) error {
+	m.saved = append(m.saved, *session)
+	return nil
+}
+
+func (m *memorySessions) RevokeAllUserSessions(_ context.Context, userID string) error {
+	m.revoked = append(m.revoked, userID)
+	return nil
+}
+
+func newTestProfileLoader(t *testing.T, ldapURL string) (profileLoader, memoryProfiles, *memorySessions) {
+	t.Helper()
+
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          ldapURL,
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+			DialTimeout:  200 * time.Millisecond,
+		},
+	}
+
+	profiles := memoryProfiles{}
+	sessions := &memorySessions{}
+	repos := Repositories{
+		UserRepo:    repository.NewUserRepository(cfg, ldappool.New(cfg.LDAP)),
+		ProfileRepo: profiles,
+		SessionRepo: sessions,
+	}
+
+	return newProfileLoader(repos, &config.ProfileConfig{StaleAfter: time.Hour}), profiles, sessions
+}
+
+func TestProfileLoaderReloadsStaleProfile(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	loader, profiles, _ := newTestProfileLoader(t, srv.URL())
+
+	old := time.Now().Add(-2 * time.Hour)
+	profiles["i24s0001"] = domain.UserProfile{
+		ID:       "i24s0001",
+		Username: "Иванов Иван Иванович",
+		Role:     "student",
+		Groups:   &domain.UserGroups{AcademicGroup: "ИТ24-11", Subgroup: "Подгр2"},
+		Source:   domain.ProfileSourceLDAP,
+		SyncedAt: old,
+	}
+
+	profile, err := loader.load(context.Background(), "i24s0001", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Groups.Subgroup != "Подгр2" {
+		t.Errorf("expected stored subgroup without resync, got %q", profile.Groups.Subgroup)
+	}
+
+	profile, err = loader.load(context.Background(), "i24s0001", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Groups.Subgroup != "Подгр1" {
+		t.Errorf("expected subgroup %q from LDAP, got %q", "Подгр1", profile.Groups.Subgroup)
+	}
+	if !profiles["i24s0001"].SyncedAt.After(old) {
+		t.Error("expected the reloaded profile to be stored")
+	}
+}
+
+func TestProfileLoaderRevokesLostAccess(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	loader, profiles, sessions := newTestProfileLoader(t, srv.URL())
+
+	old := time.Now().Add(-2 * time.Hour)
+	profiles["i19s0500"] = domain.UserProfile{ID: "i19s0500", Role: "student", Source: domain.ProfileSourceLDAP, SyncedAt: old}
+	profiles["admin"] = domain.UserProfile{ID: "admin", Role: "admin", Source: domain.ProfileSourceBuiltin, SyncedAt: old}
+
+	if _, err := loader.load(context.Background(), "i19s0500", true); err == nil || err.Error() != "account disabled" {
+		t.Errorf("expected error %q, got %v", "account disabled", err)
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != "i19s0500" {
+		t.Errorf("expected sessions of i19s0500 to be revoked, got %v", sessions.revoked)
+	}
+
+	// Profiles that do not come from LDAP are never reloaded.
+	if _, err := loader.load(context.Background(), "admin", true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestProfileLoaderKeepsStoredDataWhenLDAPIsDown(t *testing.T) {
+	loader, profiles, sessions := newTestProfileLoader(t, "ldap://127.0.0.1:1")
+
+	profiles["i24s0001"] = domain.UserProfile{
+		ID:       "i24s0001",
+		Role:     "student",
+		Source:   domain.ProfileSourceLDAP,
+		SyncedAt: time.Now().Add(-2 * time.Hour),
+	}
+
+	profile, err := loader.load(context.Background(), "i24s0001", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Role != "student" || len(sessions.revoked) != 0 {
+		t.Errorf("expected the stored profile and no revocation, got %+v and %v", profile, sessions.revoked)
+	}
+}
+
+func TestProfileLoaderCreatesMissingProfile(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	loader, profiles, sessions := newTestProfileLoader(t, srv.URL())
+
+	profile, err := loader.load(context.Background(), "i24s0001", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Role != "student" || profile.Groups.AcademicGroup != "ИТ24-11" {
+		t.Errorf("expected the student profile from LDAP, got %+v", profile)
+	}
+	if _, ok := profiles["i24s0001"]; !ok {
+		t.Error("expected the created profile to be stored")
+	}
+
+	if _, err := loader.load(context.Background(), "i19s0500", false); err == nil || err.Error() != "account disabled" {
+		t.Errorf("expected error %q, got %v", "account disabled", err)
+	}
+	if len(sessions.revoked) != 1 || sessions.revoked[0] != "i19s0500" {
+		t.Errorf("expected sessions of i19s0500 to be revoked, got %v", sessions.revoked)
+	}
+}
+
+func TestProfileLoaderFallsBackToMirror(t *testing.T) {
+	sync, _, mirror := newDirectorySync(t)
+	if _, err := sync.Sync(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loader, profiles, sessions := newTestProfileLoader(t, "ldap://127.0.0.1:1")
+	loader.repos.UserRepo.(*repository.UserRepository).UseMirror(mirror)
+
+	old := time.Now().Add(-2 * time.Hour)
+	profiles["i24s0001"] = domain.UserProfile{
+		ID:       "i24s0001",
+		Role:     "student",
+		Groups:   &domain.UserGroups{AcademicGroup: "ИТ24-11", Subgroup: "Подгр2"},
+		Source:   domain.ProfileSourceLDAP,
+		SyncedAt: old,
+	}
+
+	profile, err := loader.load(context.Background(), "i24s0001", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Role != "student" || profile.Groups.Subgroup != "Подгр1" {
+		t.Errorf("expected the mirrored student profile, got %+v", profile)
+	}
+	if !profiles["i24s0001"].SyncedAt.Equal(old) {
+		t.Error("expected mirrored data not to be stored as synced")
+	}
+
+	// A missing profile is served from the mirror as well.
+	profile, err = loader.load(context.Background(), "t001", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Role != "teacher" {
+		t.Errorf("expected role %q, got %q", "teacher", profile.Role)
+	}
+
+	// i19s0500 is locked in LDAP, which the mirror cannot tell; only LDAP
+	// itself revokes access.
+	profiles["i19s0500"] = domain.UserProfile{ID: "i19s0500", Role: "student", Source: domain.ProfileSourceLDAP, SyncedAt: old}
+	if _, err := loader.load(context.Background(), "i19s0500", true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(sessions.revoked) != 0 {
+		t.Errorf("expected no revocation, got %v", sessions.revoked)
+	}
+}
+
+func TestProfileFlags(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	loader, profiles, sessions := newTestProfileLoader(t, srv.URL())
+	svc := NewProfileService(loader.repos)
+
+	ctx := context.Background()
+	if err := svc.SetFlags(ctx, "i24s0001", []string{"staff"}); !errors.Is(err, repository.ErrProfileNotFound) {
+		t.Errorf("expected %v, got %v", repository.ErrProfileNotFound, err)
+	}
+
+	profiles["i24s0001"] = domain.UserProfile{ID: "i24s0001", Role: "student", Source: domain.ProfileSourceLDAP}
+	if err := svc.SetFlags(ctx, "i24s0001", []string{" Mentor", "mentor", "", "exchange"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Flags survive a reload from LDAP and do not block the user.
+	profile, err := loader.load(ctx, "i24s0001", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"mentor", "exchange"}; !reflect.DeepEqual(profile.Flags, want) {
+		t.Errorf("expected flags %v, got %v", want, profile.Flags)
+	}
+	if stored := profiles["i24s0001"]; !reflect.DeepEqual(stored.Flags, profile.Flags) {
+		t.Errorf("expected stored flags %v, got %v", profile.Flags, stored.Flags)
+	}
+	if len(sessions.revoked) != 0 {
+		t.Errorf("expected no revoked sessions, got %v", sessions.revoked)
+	}
+}
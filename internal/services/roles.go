@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+)
+
+type RoleService interface {
+	DryRun(ctx context.Context, userID string) (*domain.RoleDecision, error)
+}
+
+type RoleServiceImpl struct {
+	repos Repositories
+}
+
+func NewRoleService(repos Repositories) *RoleServiceImpl {
+	return &RoleServiceImpl{repos: repos}
+}
+
+func (r *RoleServiceImpl) DryRun(ctx context.Context, userID string) (*domain.RoleDecision, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if userID == "" {
+		return nil, fmt.Errorf("empty user id")
+	}
+
+	decision, err := r.repos.UserRepo.ExplainRole(ctx, userID)
+	if err != nil {
+		logger.Error(fmt.Errorf("role dry-run failed for user %s: %w", userID, err))
+		return nil, err
+	}
+
+	return decision, nil
+}
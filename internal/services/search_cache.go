@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/lrucache"
+	"golang.org/x/sync/singleflight"
+)
+
+type SearchCacheService interface {
+	Stats() SearchCacheStats
+	Flush() int
+}
+
+type SearchCacheStats struct {
+	lrucache.Stats
+	// Shared counts searches answered by an identical search in flight.
+	Shared uint64 `json:"shared"`
+	TTL    string `json:"ttl"`
+}
+
+// mirrorAwareSearch is implemented by searches that fall back to the
+// directory mirror and report when they did.
+type mirrorAwareSearch interface {
+	searchPeople(ctx context.Context, role, query string) ([]domain.Person, bool, error)
+}
+
+// CachedStudentService answers repeated searches from an LRU cache and
+// collapses concurrent identical searches into one directory query. Results
+// answered from the directory mirror during an LDAP outage are not cached,
+// so they stop being served once LDAP is back.
+type CachedStudentService struct {
+	next   StudentService
+	ttl    time.Duration
+	cache  *lrucache.Cache[[]domain.Person]
+	flight singleflight.Group
+	shared atomic.Uint64
+}
+
+func NewCachedStudentService(next StudentService, cfg *config.SearchConfig) *CachedStudentService {
+	return &CachedStudentService{
+		next:  next,
+		ttl:   cfg.CacheTTL,
+		cache: lrucache.New[[]domain.Person](cfg.CacheSize, cfg.CacheTTL),
+	}
+}
+
+func (c *CachedStudentService) SearchPeople(ctx context.Context, role, query string) ([]domain.Person, error) {
+	if ctx.Err() != nil {
+		return []domain.Person{}, nil
+	}
+
+	query = strings.TrimSpace(query)
+	if role == RoleAny {
+		role = ""
+	}
+
+	key := role + "\x00" + strings.ToLower(query)
+	if people, ok := c.cache.Get(key); ok {
+		return clonePeople(people), nil
+	}
+
+	// The search outlives a caller that gives up, since others may be
+	// waiting for the same result.
+	leader := false
+	v, err, shared := c.flight.Do(key, func() (any, error) {
+		leader = true
+		people, fromMirror, err := c.search(context.WithoutCancel(ctx), role, query)
+		if err != nil {
+			return nil, err
+		}
+		if !fromMirror {
+			c.cache.Set(key, people)
+		}
+		return people, nil
+	})
+	if shared && !leader {
+		c.shared.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return clonePeople(v.([]domain.Person)), nil
+}
+
+func (c *CachedStudentService) search(ctx context.Context, role, query string) ([]domain.Person, bool, error) {
+	if next, ok := c.next.(mirrorAwareSearch); ok {
+		return next.searchPeople(ctx, role, query)
+	}
+
+	people, err := c.next.SearchPeople(ctx, role, query)
+	return people, false, err
+}
+
+// clonePeople copies a cached result so callers cannot change what other
+// callers are served.
+func clonePeople(people []domain.Person) []domain.Person {
+	clone := slices.Clone(people)
+	for i := range clone {
+		clone[i].Roles = slices.Clone(clone[i].Roles)
+		clone[i].ExtraGroups = maps.Clone(clone[i].ExtraGroups)
+	}
+	return clone
+}
+
+func (c *CachedStudentService) SearchStudents(ctx context.Context, query string) ([]domain.Person, error) {
+	return c.SearchPeople(ctx, RoleStudent, query)
+}
+
+func (c *CachedStudentService) SearchTeachers(ctx context.Context, query string) ([]domain.Person, error) {
+	return c.SearchPeople(ctx, RoleTeacher, query)
+}
+
+func (c *CachedStudentService) Stats() SearchCacheStats {
+	return SearchCacheStats{
+		Stats:  c.cache.Stats(),
+		Shared: c.shared.Load(),
+		TTL:    c.ttl.String(),
+	}
+}
+
+func (c *CachedStudentService) Flush() int {
+	n := c.cache.Flush()
+	logger.Info(fmt.Sprintf("search cache flushed, %d entries removed", n))
+	return n
+}
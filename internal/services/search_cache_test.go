@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+)
+
+// countingSearch counts the searches that reach it; release, when set,
+// holds them until it is closed.
+type countingSearch struct {
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (s *countingSearch) SearchPeople(_ context.Context, role, query string) ([]domain.Person, error) {
+	s.calls.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []domain.Person{{ID: query, Role: role}}, nil
+}
+
+func (s *countingSearch) SearchStudents(ctx context.Context, query string) ([]domain.Person, error) {
+	return s.SearchPeople(ctx, RoleStudent, query)
+}
+
+func (s *countingSearch) SearchTeachers(ctx context.Context, query string) ([]domain.Person, error) {
+	return s.SearchPeople(ctx, RoleTeacher, query)
+}
+
+func TestSearchCacheHits(t *testing.T) {
+	next := &countingSearch{}
+	svc := NewCachedStudentService(next, &config.SearchConfig{CacheSize: 10, CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	for _, q := range []string{"Иванов", "иванов", " ИВАНОВ "} {
+		if _, err := svc.SearchStudents(ctx, q); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := svc.SearchTeachers(ctx, "иванов"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := next.calls.Load(); n != 2 {
+		t.Errorf("expected 2 searches, got %d", n)
+	}
+	if stats := svc.Stats(); stats.Hits != 2 || stats.Misses != 2 || stats.Size != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if n := svc.Flush(); n != 2 {
+		t.Errorf("expected 2 flushed entries, got %d", n)
+	}
+	if _, err := svc.SearchStudents(ctx, "иванов"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := next.calls.Load(); n != 3 {
+		t.Errorf("expected a search after flush, got %d searches", n)
+	}
+}
+
+func TestSearchCacheCollapsesConcurrentSearches(t *testing.T) {
+	next := &countingSearch{release: make(chan struct{})}
+	svc := NewCachedStudentService(next, &config.SearchConfig{CacheSize: 10, CacheTTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.SearchStudents(context.Background(), "петров"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	// Give every goroutine time to join the search in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if n := next.calls.Load(); n != 1 {
+		t.Errorf("expected 1 search, got %d", n)
+	}
+	if shared := svc.Stats().Shared; shared != 4 {
+		t.Errorf("expected 4 shared results, got %d", shared)
+	}
+}
+
+func TestSearchCacheSkipsErrors(t *testing.T) {
+	next := &countingSearch{err: errors.New("search failed")}
+	svc := NewCachedStudentService(next, &config.SearchConfig{CacheSize: 10, CacheTTL: time.Minute})
+
+	for range 2 {
+		if _, err := svc.SearchStudents(context.Background(), "q"); err == nil {
+			t.Error("expected an error")
+		}
+	}
+
+	if n := next.calls.Load(); n != 2 {
+		t.Errorf("expected failed searches not to be cached, got %d searches", n)
+	}
+}
+
+func TestSearchCacheSkipsMirrorResults(t *testing.T) {
+	mirror := newMemoryMirror()
+	_ = mirror.UpsertUsers(context.Background(), []domain.DirectoryUser{
+		{UserID: "i24s0001", Username: "Иванов Иван Иванович", Branch: domain.DirectoryBranchPeople},
+	})
+
+	cfg := &config.Config{LDAP: config.LDAPConfig{URL: "ldap://127.0.0.1:1", DialTimeout: 200 * time.Millisecond}}
+	next := NewStudentService(repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP)), mirror)
+	svc := NewCachedStudentService(next, &config.SearchConfig{CacheSize: 10, CacheTTL: time.Minute})
+
+	people, err := svc.SearchStudents(context.Background(), "иван")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(people) != 1 {
+		t.Fatalf("expected 1 person from the mirror, got %v", people)
+	}
+	if size := svc.Stats().Size; size != 0 {
+		t.Errorf("expected mirror results not to be cached, got %d entries", size)
+	}
+}
+
+func TestSearchCacheReturnsCopies(t *testing.T) {
+	next := &countingSearch{}
+	svc := NewCachedStudentService(next, &config.SearchConfig{CacheSize: 10, CacheTTL: time.Minute})
+	ctx := context.Background()
+
+	first, err := svc.SearchStudents(ctx, "иванов")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first[0].ID = "changed"
+
+	second, err := svc.SearchStudents(ctx, "иванов")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second[0].ID != "иванов" {
+		t.Errorf("expected the cached result to be unchanged, got %q", second[0].ID)
+	}
+}
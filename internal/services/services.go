@@ -9,12 +9,16 @@ import (
 	"github.com/anton1ks96/college-auth-svc/internal/domain"
 	"github.com/anton1ks96/college-auth-svc/internal/repository"
 	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
 	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/anton1ks96/college-auth-svc/pkg/notify"
 )
 
 type SignInInput struct {
 	UserID   string `json:"userid" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	OTP      string `json:"otp"`
+	ClientIP string `json:"-"`
 }
 
 type Tokens struct {
@@ -30,19 +34,43 @@ type User interface {
 }
 
 type Services struct {
-	UserService    User
-	AppUserService AppUser
-	StudentService StudentService
+	UserService          User
+	AppUserService       AppUser
+	StudentService       StudentService
+	RoleService          RoleService
+	HealthService        HealthService
+	PasswordService      PasswordService
+	PasswordResetService PasswordResetService
+	DirectorySyncService DirectorySyncService
+	ProfileService       ProfileService
+	DirectoryService     DirectoryService
+	SearchCacheService   SearchCacheService
+	ExportService        ExportService
+	LocalAccountService  LocalAccountService
+	ImpersonationService ImpersonationService
+	AccessService        AccessService
+	PersonalTokenService PersonalTokenService
 }
 
 type Repositories struct {
-	UserRepo    repository.UserLDAPRepository
-	SessionRepo repository.SessionMongoRepository
+	UserRepo          repository.UserLDAPRepository
+	SessionRepo       repository.SessionMongoRepository
+	ProfileRepo       repository.ProfileMongoRepository
+	LocalAccountRepo  repository.LocalAccountMongoRepository
+	FixtureRepo       repository.FixtureUserRepository
+	AuditRepo         repository.AuditMongoRepository
+	AccessRepo        repository.AccessMongoRepository
+	PersonalTokenRepo repository.PersonalTokenMongoRepository
+	ResetRepo         repository.PasswordResetMongoRepository
+	DirectoryRepo     repository.DirectoryLDAPRepository
+	MirrorRepo        repository.DirectoryMirrorRepository
 }
 
 type Deps struct {
 	Repos        *Repositories
 	TokenManager *auth.Manager
+	LDAPPool     *ldappool.Pool
+	Notifier     notify.Notifier
 	Config       *config.Config
 }
 
@@ -57,13 +85,58 @@ func NewServices(deps Deps) *Services {
 		logger.Fatal(fmt.Errorf("invalid refresh token TTL: %w", err))
 	}
 
-	userService := NewUserService(*deps.TokenManager, *deps.Repos, accessTTL, refreshTTL, &deps.Config.App)
-	appUserService := NewAppUserService(*deps.TokenManager, *deps.Repos, accessTTL, refreshTTL, &deps.Config.App)
-	studentService := NewStudentService(deps.Config, &deps.Config.App)
+	chain, err := newAuthChain(*deps.Repos, deps.Config)
+	if err != nil {
+		logger.Fatal(fmt.Errorf("invalid identity providers: %w", err))
+	}
+
+	userService := NewUserService(*deps.TokenManager, *deps.Repos, accessTTL, refreshTTL, &deps.Config.Profile, chain)
+	appUserService := NewAppUserService(*deps.TokenManager, *deps.Repos, accessTTL, refreshTTL, &deps.Config.Profile, chain)
+	var mirror repository.DirectoryMirrorRepository
+	if deps.Config.Sync.Enabled && deps.Config.Sync.Fallback {
+		mirror = deps.Repos.MirrorRepo
+	}
+
+	studentService := NewCachedStudentService(
+		NewStudentService(deps.Repos.DirectoryRepo, mirror),
+		&deps.Config.Search,
+	)
+	roleService := NewRoleService(*deps.Repos)
+	healthService := NewHealthService(deps.LDAPPool)
+	passwordService := NewPasswordService(*deps.TokenManager, *deps.Repos, &deps.Config.Password, &deps.Config.App)
+	passwordResetService := NewPasswordResetService(*deps.TokenManager, *deps.Repos, deps.Notifier, deps.Config)
+	directorySyncService := NewDirectorySyncService(*deps.Repos, &deps.Config.Sync)
+	profileService := NewProfileService(*deps.Repos)
+	// Fuzzy search always reads names from the mirror, which LDAP cannot
+	// answer at all; fallback only decides whether the mirror stands in for
+	// LDAP during an outage.
+	var names repository.DirectoryMirrorRepository
+	if deps.Config.Sync.Enabled {
+		names = deps.Repos.MirrorRepo
+	}
+	directoryService := NewDirectoryService(*deps.Repos, &deps.Config.Directory, names)
+	exportService := NewExportService(*deps.Repos, studentService)
+	localAccountService := NewLocalAccountService(*deps.Repos, &deps.Config.Password)
+	accessService := NewAccessService(*deps.Repos)
+	personalTokenService := NewPersonalTokenService(*deps.TokenManager, *deps.Repos, &deps.Config.PersonalToken, &deps.Config.Profile)
+	impersonationService := NewImpersonationService(*deps.TokenManager, *deps.Repos, &deps.Config.Impersonation, &deps.Config.Profile)
 
 	return &Services{
-		UserService:    userService,
-		AppUserService: appUserService,
-		StudentService: studentService,
+		UserService:          userService,
+		AppUserService:       appUserService,
+		StudentService:       studentService,
+		RoleService:          roleService,
+		HealthService:        healthService,
+		PasswordService:      passwordService,
+		PasswordResetService: passwordResetService,
+		DirectorySyncService: directorySyncService,
+		ProfileService:       profileService,
+		DirectoryService:     directoryService,
+		SearchCacheService:   studentService,
+		ExportService:        exportService,
+		LocalAccountService:  localAccountService,
+		ImpersonationService: impersonationService,
+		AccessService:        accessService,
+		PersonalTokenService: personalTokenService,
 	}
 }
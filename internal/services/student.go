@@ -2,154 +2,157 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
 
-	"github.com/anton1ks96/college-auth-svc/internal/config"
 	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
 	"github.com/anton1ks96/college-auth-svc/pkg/logger"
-	"github.com/go-ldap/ldap/v3"
 )
 
 type StudentService interface {
-	SearchStudents(ctx context.Context, query string) ([]domain.StudentInfo, error)
-	SearchTeachers(ctx context.Context, query string) ([]domain.StudentInfo, error)
+	SearchPeople(ctx context.Context, role, query string) ([]domain.Person, error)
+	SearchStudents(ctx context.Context, query string) ([]domain.Person, error)
+	SearchTeachers(ctx context.Context, query string) ([]domain.Person, error)
 }
 
+// Roles accepted by SearchPeople; RoleAny and "" match everyone.
+const (
+	RoleAny     = "any"
+	RoleStudent = "student"
+	RoleTeacher = "teacher"
+	RoleAdmin   = "admin"
+)
+
+const searchLimit = 50
+
+var (
+	ErrUnknownRole     = errors.New("unknown role")
+	errLDAPUnavailable = errors.New("LDAP connection failed")
+)
+
 type StudentServiceImpl struct {
-	cfg    *config.Config
-	appCfg *config.App
+	directory repository.DirectoryLDAPRepository
+	mirror    repository.DirectoryMirrorRepository
 }
 
-func NewStudentService(cfg *config.Config, appCfg *config.App) *StudentServiceImpl {
+// NewStudentService creates the search service. mirror may be nil; when set,
+// searches are answered from it while LDAP is unavailable.
+func NewStudentService(directory repository.DirectoryLDAPRepository, mirror repository.DirectoryMirrorRepository) *StudentServiceImpl {
 	return &StudentServiceImpl{
-		cfg:    cfg,
-		appCfg: appCfg,
+		directory: directory,
+		mirror:    mirror,
 	}
 }
 
-func (s *StudentServiceImpl) SearchStudents(ctx context.Context, query string) ([]domain.StudentInfo, error) {
+// SearchPeople returns up to searchLimit people of the given role whose uid
+// or name contains query, with their roles and groups, sorted by name. It
+// backs autocompletion, so LDAP is asked for at most searchLimit entries per
+// branch rather than every match.
+func (s *StudentServiceImpl) SearchPeople(ctx context.Context, role, query string) ([]domain.Person, error) {
+	people, _, err := s.searchPeople(ctx, role, query)
+	return people, err
+}
+
+// searchPeople is SearchPeople that also reports whether the directory
+// mirror answered because LDAP was unavailable.
+func (s *StudentServiceImpl) searchPeople(ctx context.Context, role, query string) ([]domain.Person, bool, error) {
 	if ctx.Err() != nil {
-		return []domain.StudentInfo{}, nil
+		return []domain.Person{}, false, nil
 	}
 
-	if query == "" {
-		return []domain.StudentInfo{}, nil
+	role, err := normalizeRole(role)
+	if err != nil {
+		return nil, false, err
 	}
 
-	if s.appCfg.Test {
-		return []domain.StudentInfo{
-			{ID: "i24s0291", Username: "Коломацкий Иван"},
-			{ID: "i24s0002", Username: "Джапаридзе Артем"},
-		}, nil
+	if query == "" {
+		return []domain.Person{}, false, nil
 	}
 
-	l, err := ldap.DialURL(s.cfg.LDAP.URL)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to connect to LDAP: %w", err))
-		return nil, fmt.Errorf("LDAP connection failed")
-	}
-	defer l.Close()
-
-	filter := fmt.Sprintf(
-		"(&(objectClass=person)(!(uid=t*))(|(uid=*%s*)(cn=*%s*)))",
-		ldap.EscapeFilter(query),
-		ldap.EscapeFilter(query),
-	)
-
-	searchRequest := ldap.NewSearchRequest(
-		"ou=People,dc=it-college,dc=ru",
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		50,
-		0,
-		false,
-		filter,
-		[]string{"uid", "cn"},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
+	people, err := s.directory.SuggestPeople(ctx, domain.PersonQuery{
+		Query: query,
+		Role:  role,
+		Limit: searchLimit,
+	})
 	if err != nil {
+		if errors.Is(err, ldappool.ErrUnavailable) {
+			logger.Error(fmt.Errorf("failed to connect to LDAP: %w", err))
+			if s.mirror != nil {
+				people, err := s.searchMirror(ctx, role, query)
+				return people, true, err
+			}
+			return nil, false, errLDAPUnavailable
+		}
 		logger.Error(fmt.Errorf("LDAP search failed: %w", err))
-		return nil, fmt.Errorf("search failed")
+		return nil, false, fmt.Errorf("search failed")
 	}
 
-	var students []domain.StudentInfo
-	for _, entry := range sr.Entries {
-		uid := entry.GetAttributeValue("uid")
-		cn := entry.GetAttributeValue("cn")
+	return people, false, nil
+}
 
-		if !strings.HasPrefix(uid, "t") && uid != "" && cn != "" {
-			students = append(students, domain.StudentInfo{
-				ID:       uid,
-				Username: cn,
-			})
-		}
+// normalizeRole checks a role accepted by SearchPeople and returns "" for
+// any role.
+func normalizeRole(role string) (string, error) {
+	switch role {
+	case "", RoleAny:
+		return "", nil
+	case RoleStudent, RoleTeacher, RoleAdmin:
+		return role, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnknownRole, role)
 	}
+}
 
-	return students, nil
+func (s *StudentServiceImpl) SearchStudents(ctx context.Context, query string) ([]domain.Person, error) {
+	return s.SearchPeople(ctx, RoleStudent, query)
 }
 
-func (s *StudentServiceImpl) SearchTeachers(ctx context.Context, query string) ([]domain.StudentInfo, error) {
-	if ctx.Err() != nil {
-		return []domain.StudentInfo{}, nil
-	}
+func (s *StudentServiceImpl) SearchTeachers(ctx context.Context, query string) ([]domain.Person, error) {
+	return s.SearchPeople(ctx, RoleTeacher, query)
+}
 
-	if query == "" {
-		return []domain.StudentInfo{}, nil
+// searchMirror answers from the mirror, which has no roles or groups; the
+// role is guessed from the branch, so admins cannot be found there.
+func (s *StudentServiceImpl) searchMirror(ctx context.Context, role, query string) ([]domain.Person, error) {
+	var branches []string
+	switch role {
+	case "":
+		branches = []string{domain.DirectoryBranchPeople, domain.DirectoryBranchTeachers}
+	case RoleStudent:
+		branches = []string{domain.DirectoryBranchPeople}
+	case RoleTeacher:
+		branches = []string{domain.DirectoryBranchTeachers}
+	default:
+		return nil, errLDAPUnavailable
 	}
 
-	if s.appCfg.Test {
-		return []domain.StudentInfo{
-			{ID: "t001", Username: "Преподаватель 1"},
-			{ID: "t002", Username: "Преподаватель 2"},
-		}, nil
-	}
+	result := []domain.Person{}
+	for _, branch := range branches {
+		logger.Warn(fmt.Sprintf("LDAP unavailable, searching %s in the directory mirror", branch))
 
-	l, err := ldap.DialURL(s.cfg.LDAP.URL)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to connect to LDAP: %w", err))
-		return nil, fmt.Errorf("LDAP connection failed")
-	}
-	defer l.Close()
-
-	filter := fmt.Sprintf(
-		"(&(objectClass=person)(!(uid=t*))(|(uid=*%s*)(cn=*%s*)))",
-		ldap.EscapeFilter(query),
-		ldap.EscapeFilter(query),
-	)
-
-	searchRequest := ldap.NewSearchRequest(
-		"ou=Teachers,dc=it-college,dc=ru",
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		50,
-		0,
-		false,
-		filter,
-		[]string{"uid", "cn"},
-		nil,
-	)
-
-	sr, err := l.Search(searchRequest)
-	if err != nil {
-		logger.Error(fmt.Errorf("LDAP search failed: %w", err))
-		return nil, fmt.Errorf("search failed")
-	}
+		users, err := s.mirror.SearchUsers(ctx, branch, query, searchLimit)
+		if err != nil {
+			logger.Error(fmt.Errorf("mirror search failed: %w", err))
+			return nil, errLDAPUnavailable
+		}
 
-	var teachers []domain.StudentInfo
-	for _, entry := range sr.Entries {
-		uid := entry.GetAttributeValue("uid")
-		cn := entry.GetAttributeValue("cn")
+		guessed := RoleStudent
+		if branch == domain.DirectoryBranchTeachers {
+			guessed = RoleTeacher
+		}
 
-		if uid != "" && cn != "" {
-			teachers = append(teachers, domain.StudentInfo{
-				ID:       uid,
-				Username: cn,
-			})
+		for _, u := range users {
+			if u.UserID != "" && u.Username != "" {
+				result = append(result, domain.Person{
+					ID:       u.UserID,
+					Username: u.Username,
+					Role:     guessed,
+				})
+			}
 		}
 	}
 
-	return teachers, nil
+	return result, nil
 }
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/internal/domain"
+	"github.com/anton1ks96/college-auth-svc/internal/repository"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldappool"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+func newDirectoryStudentService(t *testing.T) *StudentServiceImpl {
+	t.Helper()
+
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	cfg := &config.Config{
+		LDAP: config.LDAPConfig{
+			URL:          srv.URL(),
+			BindDN:       ldaptest.ServiceDN,
+			BindPassword: ldaptest.ServicePassword,
+		},
+	}
+
+	directory := repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP))
+	return NewStudentService(directory, nil)
+}
+
+func personIDs(people []domain.Person) []string {
+	ids := []string{}
+	for _, p := range people {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func TestSearchStudentsAgainstDirectory(t *testing.T) {
+	svc := newDirectoryStudentService(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			name:  "by uid prefix",
+			query: "i24s",
+			want:  []string{"i24s0001", "i24s0777", "i24s0002"},
+		},
+		{
+			name:  "by name case-insensitively",
+			query: "петрова",
+			want:  []string{"i24s0002"},
+		},
+		{
+			name:  "teachers are not students",
+			query: "Смирнов",
+			want:  []string{},
+		},
+		{
+			name:  "filter metacharacters are escaped",
+			query: "*)(uid=*",
+			want:  []string{},
+		},
+		{
+			name:  "empty query",
+			query: "",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.SearchStudents(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ids := personIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestSearchStudentsIncludesGroups(t *testing.T) {
+	svc := newDirectoryStudentService(t)
+
+	got, err := svc.SearchStudents(context.Background(), "i24s0001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("expected 1 student, got %v", got)
+	}
+	if got[0].Role != RoleStudent || got[0].AcademicGroup != "ИТ24-11" {
+		t.Errorf("expected a student of ИТ24-11, got %+v", got[0])
+	}
+}
+
+func TestSearchTeachers(t *testing.T) {
+	svc := newDirectoryStudentService(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			// uids under ou=Teachers start with t; the search used to
+			// exclude exactly those.
+			name:  "by uid prefix",
+			query: "t00",
+			want:  []string{"t002", "t001"},
+		},
+		{
+			name:  "teachers outside ou=Teachers",
+			query: "ова",
+			want:  []string{"i22s0042", "t002"},
+		},
+		{
+			name:  "students are not teachers",
+			query: "i24s",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.SearchTeachers(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ids := personIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestSearchPeopleByRole(t *testing.T) {
+	svc := newDirectoryStudentService(t)
+
+	tests := []struct {
+		role string
+		want []string
+	}{
+		{role: RoleAdmin, want: []string{"t003"}},
+		{role: RoleAny, want: []string{"t003", "t002", "t001"}},
+		{role: "", want: []string{"t003", "t002", "t001"}},
+	}
+
+	for _, tt := range tests {
+		got, err := svc.SearchPeople(context.Background(), tt.role, "t00")
+		if err != nil {
+			t.Fatalf("unexpected error for role %q: %v", tt.role, err)
+		}
+
+		if ids := personIDs(got); !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("role %q: expected %v, got %v", tt.role, tt.want, ids)
+		}
+	}
+
+	if _, err := svc.SearchPeople(context.Background(), "janitor", "t00"); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("expected %v, got %v", ErrUnknownRole, err)
+	}
+}
+
+func TestSearchStudentsTestMode(t *testing.T) {
+	fixtures, err := repository.NewFixtureRepository(&config.Config{App: config.App{Fixtures: "../../configs/fixtures.yml"}})
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+	svc := NewStudentService(fixtures, nil)
+
+	got, err := svc.SearchStudents(context.Background(), "i24s")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"i24s0002", "i24s0291", "i24s0015", "i24s0048"}
+	if ids := personIDs(got); !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected %v, got %v", want, ids)
+	}
+}
+
+func TestSearchStudentsUnavailable(t *testing.T) {
+	cfg := &config.Config{LDAP: config.LDAPConfig{URL: "ldap://127.0.0.1:1", DialTimeout: 200 * time.Millisecond}}
+	svc := NewStudentService(repository.NewDirectoryRepository(cfg, ldappool.New(cfg.LDAP)), nil)
+
+	if _, err := svc.SearchStudents(context.Background(), "i24s"); err == nil || err.Error() != "LDAP connection failed" {
+		t.Errorf("expected error %q, got %v", "LDAP connection failed", err)
+	}
+}
@@ -2,8 +2,6 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -18,26 +16,22 @@ type UserService struct {
 	repos           Repositories
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
-	cfg             *config.App
-	adminPassword   string
+	profiles        profileLoader
+	gate            accessGate
+	personal        personalTokens
+	auth            authChain
 }
 
-func NewUserService(tm auth.Manager, repos Repositories, accessTTL time.Duration, refreshTTL time.Duration, appCfg *config.App) *UserService {
-	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
-		logger.Fatal(fmt.Errorf("failed to generate admin password: %w", err))
-	}
-	adminPass := hex.EncodeToString(b)
-
-	logger.Info(fmt.Sprintf("Built-in admin password: %s", adminPass))
-
+func NewUserService(tm auth.Manager, repos Repositories, accessTTL time.Duration, refreshTTL time.Duration, profileCfg *config.ProfileConfig, chain authChain) *UserService {
 	return &UserService{
 		tokenManager:    &tm,
 		repos:           repos,
 		accessTokenTTL:  accessTTL,
 		refreshTokenTTL: refreshTTL,
-		cfg:             appCfg,
-		adminPassword:   adminPass,
+		profiles:        newProfileLoader(repos, profileCfg),
+		gate:            accessGate{repos: repos},
+		personal:        newPersonalTokens(&tm, repos, profileCfg),
+		auth:            chain,
 	}
 }
 
@@ -60,63 +54,24 @@ func (u *UserService) SignIn(ctx context.Context, input SignInInput) (Tokens, *d
 		return Tokens{}, nil, ctx.Err()
 	}
 
-	var user *domain.User
-	var err error
-
-	if input.UserID == "admin" && input.Password == u.adminPassword {
-		user = &domain.User{
-			ID:       "admin",
-			Username: "Администратор",
-			Role:     "admin",
-		}
-	} else if u.cfg.Test {
-		switch input.UserID {
-		case "admin":
-			user = &domain.User{
-				ID:       input.UserID,
-				Username: "Администратор 1",
-				Role:     "admin",
-			}
-
-		case "t001":
-			user = &domain.User{
-				ID:       input.UserID,
-				Username: "Преподаватель 1",
-				Role:     "teacher",
-			}
-
-		case "t002":
-			user = &domain.User{
-				ID:       input.UserID,
-				Username: "Преподаватель 2",
-				Role:     "teacher",
-			}
-		default:
-			user = &domain.User{
-				ID:       input.UserID,
-				Username: "Студент Обыкновенный",
-				Role:     "student",
-			}
-		}
-	} else {
-		if err := u.repos.UserRepo.Authentication(ctx, input.UserID, input.Password); err != nil {
-			logger.Error(fmt.Errorf("authentication failed for user %s: %w", input.UserID, err))
-			return Tokens{}, nil, fmt.Errorf("authentication failed: %w", err)
-		}
+	policy, err := u.gate.policy(ctx)
+	if err != nil {
+		return Tokens{}, nil, err
+	}
 
-		if ctx.Err() != nil {
-			return Tokens{}, nil, ctx.Err()
-		}
+	// Groups are only needed here to match a group-based access policy.
+	extended, source, err := u.auth.Authenticate(ctx, input, policy != nil && len(policy.AllowGroups) > 0)
+	if err != nil {
+		return Tokens{}, nil, err
+	}
 
-		user, err = u.repos.UserRepo.GetByID(ctx, input.UserID, input.Password)
-		if err != nil {
-			logger.Error(fmt.Errorf("find user data failed for user %s: %w", input.UserID, err))
-			return Tokens{}, nil, fmt.Errorf("find user data failed: %w", err)
-		}
+	if err := u.gate.check(ctx, extended, policy); err != nil {
+		return Tokens{}, nil, err
+	}
+	user := extended.User()
 
-		if ctx.Err() != nil {
-			return Tokens{}, nil, ctx.Err()
-		}
+	if ctx.Err() != nil {
+		return Tokens{}, nil, ctx.Err()
 	}
 
 	tokens, err := u.generateTokens(user)
@@ -135,15 +90,22 @@ func (u *UserService) SignIn(ctx context.Context, input SignInInput) (Tokens, *d
 		return Tokens{}, nil, ctx.Err()
 	}
 
+	if err := u.repos.ProfileRepo.RecordSignIn(ctx, &domain.UserProfile{
+		ID:       user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Roles:    user.Roles,
+		Source:   source,
+	}); err != nil {
+		logger.Error(fmt.Errorf("failed to save profile for user %s: %w", input.UserID, err))
+		return Tokens{}, nil, fmt.Errorf("failed to save user profile: %w", err)
+	}
+
 	session := domain.RefreshSession{
-		JTI:           jti,
-		UserID:        input.UserID,
-		Username:      user.Username,
-		Role:          user.Role,
-		AcademicGroup: "",
-		Profile:       "",
-		ExpiresAt:     time.Now().Add(u.refreshTokenTTL),
-		CreatedAt:     time.Now(),
+		JTI:       jti,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(u.refreshTokenTTL),
+		CreatedAt: time.Now(),
 	}
 
 	if err := u.repos.SessionRepo.SaveRefreshToken(ctx, &session); err != nil {
@@ -224,13 +186,15 @@ func (u *UserService) RefreshTokens(ctx context.Context, refreshToken string) (T
 		return Tokens{}, fmt.Errorf("token not found or already used")
 	}
 
-	var user *domain.User
-
-	user, err = u.repos.SessionRepo.GetUserByID(ctx, userID)
+	profile, err := u.profiles.load(ctx, userID, true)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to get user data from session for ID %s: %w", userID, err))
-		return Tokens{}, fmt.Errorf("failed to get user data: %w", err)
+		return Tokens{}, err
+	}
+
+	if err := u.gate.checkProfile(ctx, profile); err != nil {
+		return Tokens{}, err
 	}
+	user := profile.User()
 
 	tokens, err := u.generateTokens(user)
 	if err != nil {
@@ -245,14 +209,10 @@ func (u *UserService) RefreshTokens(ctx context.Context, refreshToken string) (T
 	}
 
 	newSession := domain.RefreshSession{
-		JTI:           newJti,
-		UserID:        userID,
-		Username:      user.Username,
-		Role:          user.Role,
-		AcademicGroup: "",
-		Profile:       "",
-		ExpiresAt:     time.Now().Add(u.refreshTokenTTL),
-		CreatedAt:     time.Now(),
+		JTI:       newJti,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(u.refreshTokenTTL),
+		CreatedAt: time.Now(),
 	}
 
 	if err := u.repos.SessionRepo.ReplaceRefreshToken(ctx, oldJti, &newSession); err != nil {
@@ -276,35 +236,46 @@ func (u *UserService) ValidateAccessToken(ctx context.Context, accessToken strin
 		return nil, fmt.Errorf("empty access token")
 	}
 
-	err := u.tokenManager.Validate(accessToken)
-	if err != nil {
-		logger.Error(fmt.Errorf("token expired"))
-		return nil, fmt.Errorf("token expired")
+	if auth.IsPersonalToken(accessToken) {
+		user, err := u.personal.authenticate(ctx, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		return user.User(), nil
 	}
 
-	userID, err := u.tokenManager.ExtractClaim(accessToken, "user_id")
+	claims, err := u.tokenManager.ValidateAccessToken(accessToken)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to extract user_id from token: %w", err))
-		return nil, err
+		logger.Error(fmt.Errorf("access token validation failed: %w", err))
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("user_id claim missing or invalid")
 	}
 
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
 
-	var user *domain.User
-
-	user, err = u.repos.SessionRepo.GetUserByID(ctx, userID)
+	profile, err := u.profiles.load(ctx, userID, false)
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to get user data from session for ID %s: %w", userID, err))
-		return nil, fmt.Errorf("failed to get user data: %w", err)
+		return nil, err
 	}
 
+	user := profile.User()
+	user.Impersonator = auth.ActorClaim(claims)
 	return user, nil
 }
 
 func (u *UserService) generateTokens(user *domain.User) (Tokens, error) {
-	newAccess, err := u.tokenManager.NewAccessToken(user.ID, user.Username, user.Role, "", "", "", "")
+	newAccess, err := u.tokenManager.NewAccessToken(auth.AccessTokenClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		Roles:    user.Roles,
+	})
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to generate access token for user %s: %w", user.ID, err))
 		return Tokens{}, fmt.Errorf("failed to generate access token: %w", err)
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/auth"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+)
+
+func TestSignInStoresDirectoryID(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	repos, _, sessions := newLocalAccountRepos(t, srv.URL())
+	repos.AccessRepo = &memoryAccess{}
+
+	cfg := &config.Config{JWT: config.JWTConfig{AccessTokenTTL: "60m", RefreshTokenTTL: "720h", SigningKey: "test-key"}}
+	tm := auth.NewManager(cfg)
+	chain := authChain{ldapAuthenticator{repos: repos}}
+	profileCfg := &config.ProfileConfig{}
+
+	ctx := context.Background()
+	input := SignInInput{UserID: "I24S0001", Password: "i24s0001-pass"}
+
+	// Sessions are revoked by the directory ID, whatever case was typed.
+	if _, _, err := NewUserService(*tm, repos, 0, 0, profileCfg, chain).SignIn(ctx, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := NewAppUserService(*tm, repos, 0, 0, profileCfg, chain).SignIn(ctx, input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sessions.saved) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions.saved))
+	}
+	for _, session := range sessions.saved {
+		if session.UserID != "i24s0001" {
+			t.Errorf("expected session of %q, got %q", "i24s0001", session.UserID)
+		}
+	}
+}
@@ -11,46 +11,81 @@ import (
 	"github.com/google/uuid"
 )
 
+// Token types carried in the typ claim.
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
 type Manager struct {
 	cfg *config.Config
 }
 
+type AccessTokenClaims struct {
+	UserID        string
+	Username      string
+	Role          string
+	Roles         []string
+	AcademicGroup string
+	Profile       string
+	Subgroup      string
+	EnglishGroup  string
+	ExtraGroups   map[string]string
+
+	Actor string        // Impersonating user, issued as the act claim
+	TTL   time.Duration // Overrides the configured lifetime when set
+}
+
 func NewManager(cfg *config.Config) *Manager {
 	return &Manager{
 		cfg: cfg,
 	}
 }
 
-func (m *Manager) NewAccessToken(userId, userName, role, academicGroup, profile, subgroup, englishGroup string) (string, error) {
-	if userId == "" || userName == "" || role == "" {
+func (m *Manager) NewAccessToken(c AccessTokenClaims) (string, error) {
+	if c.UserID == "" || c.Username == "" || c.Role == "" {
 		return "", errors.New("userId, userName and role cannot be empty")
 	}
 
-	ttl, err := time.ParseDuration(m.cfg.JWT.AccessTokenTTL)
-	if err != nil {
-		logger.Error(errors.New("failed to parse access token TTL: " + err.Error()))
-		return "", err
+	ttl := c.TTL
+	if ttl == 0 {
+		var err error
+		ttl, err = time.ParseDuration(m.cfg.JWT.AccessTokenTTL)
+		if err != nil {
+			logger.Error(errors.New("failed to parse access token TTL: " + err.Error()))
+			return "", err
+		}
 	}
 
 	claims := jwt.MapClaims{
-		"user_id":  userId,
-		"username": userName,
-		"role":     role,
+		"user_id":  c.UserID,
+		"username": c.Username,
+		"role":     c.Role,
+		"typ":      TypeAccess,
 		"exp":      time.Now().Add(ttl).Unix(),
 		"iat":      time.Now().Unix(),
 	}
 
-	if academicGroup != "" {
-		claims["academic_group"] = academicGroup
+	if len(c.Roles) > 0 {
+		claims["roles"] = c.Roles
+	}
+	if c.AcademicGroup != "" {
+		claims["academic_group"] = c.AcademicGroup
+	}
+	if c.Profile != "" {
+		claims["profile"] = c.Profile
 	}
-	if profile != "" {
-		claims["profile"] = profile
+	if c.Subgroup != "" {
+		claims["subgroup"] = c.Subgroup
 	}
-	if subgroup != "" {
-		claims["subgroup"] = subgroup
+	if c.EnglishGroup != "" {
+		claims["english_group"] = c.EnglishGroup
 	}
-	if englishGroup != "" {
-		claims["english_group"] = englishGroup
+	if len(c.ExtraGroups) > 0 {
+		claims["extra_groups"] = c.ExtraGroups
+	}
+	if c.Actor != "" {
+		claims["act"] = map[string]string{"sub": c.Actor}
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -80,6 +115,7 @@ func (m *Manager) NewRefreshToken(userId string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userId,
 		"jti":     jti,
+		"typ":     TypeRefresh,
 		"exp":     time.Now().Add(ttl).Unix(),
 		"iat":     time.Now().Unix(),
 	}
@@ -167,6 +203,32 @@ func (m *Manager) Validate(tokenString string) error {
 	return nil
 }
 
+// ValidateAccessToken checks an access token and returns its claims. Refresh
+// tokens are signed with the same key and must not pass as bearer tokens.
+// Tokens issued before the typ claim are told apart by jti and role.
+func (m *Manager) ValidateAccessToken(tokenString string) (map[string]interface{}, error) {
+	if err := m.Validate(tokenString); err != nil {
+		return nil, err
+	}
+
+	claims, err := m.GetAllClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	typ, hasTyp := claims["typ"].(string)
+	_, hasJTI := claims["jti"]
+	role, _ := claims["role"].(string)
+	switch {
+	case hasTyp && typ != TypeAccess:
+		return nil, errors.New("not an access token")
+	case !hasTyp && (hasJTI || role == ""):
+		return nil, errors.New("not an access token")
+	}
+
+	return claims, nil
+}
+
 func (m *Manager) ValidateRefreshToken(tokenString string) error {
 	if err := m.Validate(tokenString); err != nil {
 		return err
@@ -209,3 +271,48 @@ func (m *Manager) GetAllClaims(tokenString string) (map[string]interface{}, erro
 
 	return result, nil
 }
+
+// StringSliceClaim converts a JSON array claim into a string slice.
+func StringSliceClaim(claims map[string]interface{}, name string) []string {
+	raw, ok := claims[name].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		}
+	}
+
+	return values
+}
+
+// ActorClaim returns the impersonating user of a token, or "" for a token
+// used by its own subject.
+func ActorClaim(claims map[string]interface{}) string {
+	act, ok := claims["act"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	sub, _ := act["sub"].(string)
+	return sub
+}
+
+// StringMapClaim converts a JSON object claim into a string map.
+func StringMapClaim(claims map[string]interface{}, name string) map[string]string {
+	raw, ok := claims[name].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			values[k] = s
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// NewOpaqueToken returns a random token signed for the given purpose and the
+// hash it should be stored under. Only the hash is meant to be persisted.
+func (m *Manager) NewOpaqueToken(purpose string) (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	random := base64.RawURLEncoding.EncodeToString(b)
+	token := random + "." + m.opaqueSignature(purpose, random)
+
+	return token, HashToken(token), nil
+}
+
+// VerifyOpaqueToken checks the token signature and returns its storage hash.
+func (m *Manager) VerifyOpaqueToken(purpose, token string) (string, error) {
+	random, signature, ok := strings.Cut(token, ".")
+	if !ok || random == "" || signature == "" {
+		return "", errors.New("malformed token")
+	}
+
+	expected := m.opaqueSignature(purpose, random)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", errors.New("invalid token signature")
+	}
+
+	return HashToken(token), nil
+}
+
+func (m *Manager) opaqueSignature(purpose, random string) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.JWT.SigningKey))
+	mac.Write([]byte(purpose + ":" + random))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PersonalTokenPrefix marks personal access tokens so they can be told apart
+// from JWTs without a lookup.
+const PersonalTokenPrefix = "pat_"
+
+const personalTokenPurpose = "personal_access_token"
+
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// NewPersonalToken returns a personal access token and its storage hash.
+func (m *Manager) NewPersonalToken() (string, string, error) {
+	token, _, err := m.NewOpaqueToken(personalTokenPurpose)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = PersonalTokenPrefix + token
+	return token, HashToken(token), nil
+}
+
+// VerifyPersonalToken checks the token signature and returns its storage hash.
+func (m *Manager) VerifyPersonalToken(token string) (string, error) {
+	opaque, ok := strings.CutPrefix(token, PersonalTokenPrefix)
+	if !ok {
+		return "", errors.New("not a personal access token")
+	}
+
+	if _, err := m.VerifyOpaqueToken(personalTokenPurpose, opaque); err != nil {
+		return "", err
+	}
+
+	return HashToken(token), nil
+}
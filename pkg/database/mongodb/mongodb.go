@@ -65,6 +65,119 @@ func createIndexes(client *mongo.Client, cfg *config.Config) error {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	resetColl := client.Database(cfg.Mongo.DBName).Collection(cfg.Mongo.ResetCollName)
+
+	resetIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetName("token_hash_idx").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "userid", Value: 1}},
+			Options: options.Index().SetName("userid_idx"),
+		},
+		{
+			Keys: bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().
+				SetName("expires_at_idx").
+				SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = resetColl.Indexes().CreateMany(ctx, resetIndexModels)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset indexes: %w", err)
+	}
+
+	attemptsColl := client.Database(cfg.Mongo.DBName).Collection(cfg.Mongo.ResetAttemptsCollName)
+
+	_, err = attemptsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().
+			SetName("expires_at_idx").
+			SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create password reset attempt indexes: %w", err)
+	}
+
+	dirUsersColl := client.Database(cfg.Mongo.DBName).Collection(cfg.Mongo.DirUsersCollName)
+
+	dirUsersIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "branch", Value: 1}, {Key: "username", Value: 1}},
+			Options: options.Index().SetName("branch_username_idx"),
+		},
+		{
+			Keys:    bson.D{{Key: "synced_at", Value: 1}},
+			Options: options.Index().SetName("synced_at_idx"),
+		},
+	}
+
+	_, err = dirUsersColl.Indexes().CreateMany(ctx, dirUsersIndexModels)
+	if err != nil {
+		return fmt.Errorf("failed to create directory user indexes: %w", err)
+	}
+
+	dirGroupsColl := client.Database(cfg.Mongo.DBName).Collection(cfg.Mongo.DirGroupsCollName)
+
+	dirGroupsIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "members", Value: 1}},
+			Options: options.Index().SetName("members_idx"),
+		},
+		{
+			Keys:    bson.D{{Key: "member_uids", Value: 1}},
+			Options: options.Index().SetName("member_uids_idx"),
+		},
+		{
+			Keys:    bson.D{{Key: "synced_at", Value: 1}},
+			Options: options.Index().SetName("synced_at_idx"),
+		},
+	}
+
+	_, err = dirGroupsColl.Indexes().CreateMany(ctx, dirGroupsIndexModels)
+	if err != nil {
+		return fmt.Errorf("failed to create directory group indexes: %w", err)
+	}
+
+	tokensColl := client.Database(cfg.Mongo.DBName).Collection(cfg.Mongo.TokensCollName)
+
+	tokensIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetName("token_hash_idx").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "userid", Value: 1}},
+			Options: options.Index().SetName("userid_idx"),
+		},
+		{
+			Keys: bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().
+				SetName("expires_at_idx").
+				SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = tokensColl.Indexes().CreateMany(ctx, tokensIndexModels)
+	if err != nil {
+		return fmt.Errorf("failed to create personal token indexes: %w", err)
+	}
+
+	suspensionsColl := client.Database(cfg.Mongo.DBName).Collection(cfg.Mongo.SuspensionsCollName)
+
+	// Suspensions without until never expire; the TTL index skips them.
+	_, err = suspensionsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "until", Value: 1}},
+		Options: options.Index().
+			SetName("until_idx").
+			SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create suspension indexes: %w", err)
+	}
+
 	logger.Info("MongoDB indexes created successfully")
 	return nil
 }
@@ -0,0 +1,293 @@
+package ldappool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	StrategyPriority   = "priority"
+	StrategyRoundRobin = "round_robin"
+
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+const (
+	defaultDialTimeout      = 5 * time.Second
+	defaultTimeout          = 10 * time.Second
+	defaultFailureThreshold = 3
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+// ErrUnavailable is returned when every endpoint failed or is short-circuited.
+var ErrUnavailable = errors.New("no LDAP endpoint available")
+
+type EndpointStatus struct {
+	URL                 string     `json:"url"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	TotalFailures       uint64     `json:"total_failures"`
+	TotalSuccesses      uint64     `json:"total_successes"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+type endpoint struct {
+	url string
+
+	mu             sync.Mutex
+	state          string
+	failures       int
+	openedAt       time.Time
+	trialInFlight  bool
+	totalFailures  uint64
+	totalSuccesses uint64
+	lastError      string
+	lastFailureAt  time.Time
+	lastSuccessAt  time.Time
+}
+
+type Pool struct {
+	endpoints   []*endpoint
+	strategy    string
+	dialTimeout time.Duration
+	timeout     time.Duration
+	threshold   int
+	openTimeout time.Duration
+	next        atomic.Uint64
+}
+
+func New(cfg config.LDAPConfig) *Pool {
+	urls := cfg.Endpoints
+	if len(urls) == 0 && cfg.URL != "" {
+		urls = config.SplitList(cfg.URL)
+	}
+
+	p := &Pool{
+		strategy:    cfg.Strategy,
+		dialTimeout: cfg.DialTimeout,
+		timeout:     cfg.Timeout,
+		threshold:   cfg.FailureThreshold,
+		openTimeout: cfg.OpenTimeout,
+	}
+
+	if p.strategy == "" {
+		p.strategy = StrategyPriority
+	}
+	if p.dialTimeout <= 0 {
+		p.dialTimeout = defaultDialTimeout
+	}
+	if p.timeout <= 0 {
+		p.timeout = defaultTimeout
+	}
+	if p.threshold <= 0 {
+		p.threshold = defaultFailureThreshold
+	}
+	if p.openTimeout <= 0 {
+		p.openTimeout = defaultOpenTimeout
+	}
+
+	for _, url := range urls {
+		p.endpoints = append(p.endpoints, &endpoint{url: url, state: StateClosed})
+	}
+
+	return p
+}
+
+// Do dials an endpoint and runs fn on the connection. Connection-level errors
+// from dialing or from fn open the endpoint's breaker and the call is retried
+// on the next endpoint; any other error (e.g. invalid credentials) is returned
+// as is.
+func (p *Pool) Do(ctx context.Context, fn func(l *ldap.Conn) error) error {
+	return p.do(ctx, fn, true)
+}
+
+// DoOnce is Do for writes that must not run twice, such as a password change.
+// Only failed dials move on to the next endpoint; a connection lost while fn
+// runs is returned at once, since the server may already have applied it.
+func (p *Pool) DoOnce(ctx context.Context, fn func(l *ldap.Conn) error) error {
+	return p.do(ctx, fn, false)
+}
+
+func (p *Pool) do(ctx context.Context, fn func(l *ldap.Conn) error, retry bool) error {
+	var lastErr error
+	attempted := 0
+
+	for _, ep := range p.candidates() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !ep.allow(p.openTimeout) {
+			continue
+		}
+		attempted++
+
+		l, err := ldap.DialURL(ep.url, ldap.DialWithDialer(&net.Dialer{Timeout: p.dialTimeout}))
+		if err != nil {
+			ep.failure(err, p.threshold)
+			logger.Warn(fmt.Sprintf("LDAP endpoint %s unreachable: %v", ep.url, err))
+			lastErr = err
+			continue
+		}
+		l.SetTimeout(p.timeout)
+
+		err = fn(l)
+		l.Close()
+
+		if err != nil && IsConnectionError(err) {
+			ep.failure(err, p.threshold)
+			logger.Warn(fmt.Sprintf("LDAP endpoint %s failed mid-operation: %v", ep.url, err))
+			if !retry {
+				return fmt.Errorf("%w: connection lost mid-operation, outcome unknown: %v", ErrUnavailable, err)
+			}
+			lastErr = err
+			continue
+		}
+
+		ep.success()
+		return err
+	}
+
+	if attempted == 0 {
+		return fmt.Errorf("%w: all circuit breakers are open", ErrUnavailable)
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+func (p *Pool) Status() []EndpointStatus {
+	statuses := make([]EndpointStatus, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		statuses = append(statuses, ep.status(p.openTimeout))
+	}
+	return statuses
+}
+
+func (p *Pool) candidates() []*endpoint {
+	n := len(p.endpoints)
+	if p.strategy != StrategyRoundRobin || n < 2 {
+		return p.endpoints
+	}
+
+	start := int(p.next.Add(1)-1) % n
+	ordered := make([]*endpoint, 0, n)
+	for i := 0; i < n; i++ {
+		ordered = append(ordered, p.endpoints[(start+i)%n])
+	}
+	return ordered
+}
+
+// IsConnectionError reports whether err means the server could not be reached
+// or is temporarily unable to serve, as opposed to a rejected operation.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if ldap.IsErrorAnyOf(err, ldap.ErrorNetwork, ldap.LDAPResultBusy, ldap.LDAPResultUnavailable) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (e *endpoint) allow(openTimeout time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch e.state {
+	case StateOpen:
+		if time.Since(e.openedAt) < openTimeout {
+			return false
+		}
+		e.state = StateHalfOpen
+		e.trialInFlight = true
+		return true
+	case StateHalfOpen:
+		if e.trialInFlight {
+			return false
+		}
+		e.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (e *endpoint) failure(err error, threshold int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.failures++
+	e.totalFailures++
+	e.lastError = err.Error()
+	e.lastFailureAt = time.Now()
+	e.trialInFlight = false
+
+	if e.state == StateHalfOpen || e.failures >= threshold {
+		if e.state != StateOpen {
+			logger.Warn(fmt.Sprintf("LDAP endpoint %s circuit opened after %d consecutive failures", e.url, e.failures))
+		}
+		e.state = StateOpen
+		e.openedAt = time.Now()
+	}
+}
+
+func (e *endpoint) success() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state != StateClosed {
+		logger.Info(fmt.Sprintf("LDAP endpoint %s recovered, circuit closed", e.url))
+	}
+
+	e.state = StateClosed
+	e.failures = 0
+	e.trialInFlight = false
+	e.totalSuccesses++
+	e.lastSuccessAt = time.Now()
+}
+
+func (e *endpoint) status(openTimeout time.Duration) EndpointStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := EndpointStatus{
+		URL:                 e.url,
+		State:               e.state,
+		ConsecutiveFailures: e.failures,
+		TotalFailures:       e.totalFailures,
+		TotalSuccesses:      e.totalSuccesses,
+		LastError:           e.lastError,
+	}
+
+	if !e.lastFailureAt.IsZero() {
+		t := e.lastFailureAt
+		s.LastFailureAt = &t
+	}
+	if !e.lastSuccessAt.IsZero() {
+		t := e.lastSuccessAt
+		s.LastSuccessAt = &t
+	}
+	if e.state == StateOpen {
+		t := e.openedAt.Add(openTimeout)
+		s.OpenUntil = &t
+	}
+
+	return s
+}
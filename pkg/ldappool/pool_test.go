@@ -0,0 +1,102 @@
+package ldappool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/ldaptest"
+	"github.com/go-ldap/ldap/v3"
+)
+
+func TestPoolOpensBreakerOnUnreachableEndpoints(t *testing.T) {
+	pool := New(config.LDAPConfig{
+		URL:              "ldap://127.0.0.1:1, ldap://127.0.0.1:2",
+		DialTimeout:      200 * time.Millisecond,
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+	})
+
+	called := false
+	fn := func(l *ldap.Conn) error {
+		called = true
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := pool.Do(context.Background(), fn); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("expected ErrUnavailable, got %v", err)
+		}
+	}
+
+	if called {
+		t.Fatal("fn must not run without a connection")
+	}
+
+	for _, s := range pool.Status() {
+		if s.State != StateOpen {
+			t.Errorf("expected endpoint %s to be open, got %s", s.URL, s.State)
+		}
+		if s.ConsecutiveFailures != 2 {
+			t.Errorf("expected 2 failures for %s, got %d", s.URL, s.ConsecutiveFailures)
+		}
+	}
+
+	err := pool.Do(context.Background(), fn)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected short-circuited ErrUnavailable, got %v", err)
+	}
+}
+
+func TestDoOnceDoesNotRetryMidOperation(t *testing.T) {
+	srv := ldaptest.Start(t, ldaptest.ITCollege)
+	pool := New(config.LDAPConfig{
+		Endpoints: []string{srv.URL(), srv.URL()},
+		Timeout:   time.Second,
+	})
+
+	calls := 0
+	fn := func(l *ldap.Conn) error {
+		calls++
+		return ldap.NewError(ldap.ErrorNetwork, errors.New("connection reset"))
+	}
+
+	if err := pool.Do(context.Background(), fn); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected Do to try both endpoints, got %d calls", calls)
+	}
+
+	calls = 0
+	if err := pool.DoOnce(context.Background(), fn); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected DoOnce to run once, got %d calls", calls)
+	}
+}
+
+func TestRoundRobinRotatesCandidates(t *testing.T) {
+	pool := New(config.LDAPConfig{
+		Endpoints: []string{"ldap://a", "ldap://b", "ldap://c"},
+		Strategy:  StrategyRoundRobin,
+	})
+
+	first := pool.candidates()[0].url
+	second := pool.candidates()[0].url
+	if first == second {
+		t.Errorf("expected rotation, got %s twice", first)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	if !IsConnectionError(ldap.NewError(ldap.ErrorNetwork, errors.New("connection closed"))) {
+		t.Error("network error must be a connection error")
+	}
+	if IsConnectionError(ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("bad password"))) {
+		t.Error("invalid credentials must not be retried")
+	}
+}
@@ -0,0 +1,227 @@
+package ldaptest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const timestampLayout = "20060102150405Z"
+
+// operationalAttributes are only returned when requested by name or with "+".
+var operationalAttributes = map[string]bool{
+	"memberof":             true,
+	"entrydn":              true,
+	"createtimestamp":      true,
+	"modifytimestamp":      true,
+	"pwdaccountlockedtime": true,
+	"pwdreset":             true,
+	"pwdchangedtime":       true,
+}
+
+// dnAttributes hold DN values and are compared in normalized form.
+var dnAttributes = map[string]bool{
+	"member":       true,
+	"uniquemember": true,
+	"memberof":     true,
+	"manager":      true,
+	"owner":        true,
+	"entrydn":      true,
+}
+
+type attribute struct {
+	name   string
+	values []string
+}
+
+// Entry is a directory entry with its attributes in insertion order.
+type Entry struct {
+	DN    string
+	attrs []*attribute
+}
+
+func NewEntry(dn string) *Entry {
+	return &Entry{DN: dn}
+}
+
+func (e *Entry) Add(name string, values ...string) {
+	if a := e.attr(name); a != nil {
+		a.values = append(a.values, values...)
+		return
+	}
+	e.attrs = append(e.attrs, &attribute{name: name, values: append([]string(nil), values...)})
+}
+
+// Replace sets the values of an attribute; no values removes it.
+func (e *Entry) Replace(name string, values ...string) {
+	for i, a := range e.attrs {
+		if !strings.EqualFold(a.name, name) {
+			continue
+		}
+		if len(values) == 0 {
+			e.attrs = append(e.attrs[:i], e.attrs[i+1:]...)
+		} else {
+			a.values = append([]string(nil), values...)
+		}
+		return
+	}
+	if len(values) > 0 {
+		e.Add(name, values...)
+	}
+}
+
+func (e *Entry) Get(name string) []string {
+	if a := e.attr(name); a != nil {
+		return a.values
+	}
+	return nil
+}
+
+func (e *Entry) attr(name string) *attribute {
+	for _, a := range e.attrs {
+		if strings.EqualFold(a.name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+type directory struct {
+	mu      sync.RWMutex
+	entries []*Entry
+	byDN    map[string]*Entry
+	inChain bool
+}
+
+// LoadTimestamp is the createTimestamp and modifyTimestamp of loaded entries
+// that do not set their own, so later modifications always sort after it.
+const LoadTimestamp = "20240901000000Z"
+
+func newDirectory(entries []*Entry) (*directory, error) {
+	d := &directory{byDN: make(map[string]*Entry, len(entries))}
+
+	for _, e := range entries {
+		key := normalizeDN(e.DN)
+		if _, ok := d.byDN[key]; ok {
+			return nil, fmt.Errorf("duplicate entry %s", e.DN)
+		}
+		if e.Get("createTimestamp") == nil {
+			e.Add("createTimestamp", LoadTimestamp)
+		}
+		if e.Get("modifyTimestamp") == nil {
+			e.Add("modifyTimestamp", LoadTimestamp)
+		}
+		d.byDN[key] = e
+		d.entries = append(d.entries, e)
+	}
+
+	return d, nil
+}
+
+func (d *directory) lookup(dn string) *Entry {
+	return d.byDN[normalizeDN(dn)]
+}
+
+// values returns the values of name on e, computing virtual attributes the
+// way the memberof overlay and entryDN do. The caller must hold d.mu.
+func (d *directory) values(e *Entry, name string) []string {
+	switch strings.ToLower(name) {
+	case "memberof":
+		return d.memberOf(e.DN)
+	case "entrydn":
+		return []string{e.DN}
+	default:
+		return e.Get(name)
+	}
+}
+
+func (d *directory) memberOf(dn string) []string {
+	key := normalizeDN(dn)
+	var groups []string
+	for _, g := range d.entries {
+		for _, attr := range []string{"member", "uniqueMember"} {
+			if containsDN(g.Get(attr), key) {
+				groups = append(groups, g.DN)
+				break
+			}
+		}
+	}
+	return groups
+}
+
+// search returns the entries under base within scope matching filter, in
+// load order. The caller must hold d.mu.
+func (d *directory) search(base string, scope int, filter func(*Entry) bool) ([]*Entry, bool) {
+	baseKey := normalizeDN(base)
+	if baseKey != "" && d.byDN[baseKey] == nil {
+		return nil, false
+	}
+
+	var matched []*Entry
+	for _, e := range d.entries {
+		if !inScope(normalizeDN(e.DN), baseKey, scope) {
+			continue
+		}
+		if filter(e) {
+			matched = append(matched, e)
+		}
+	}
+
+	return matched, true
+}
+
+func (d *directory) touch(e *Entry) {
+	e.Replace("modifyTimestamp", time.Now().UTC().Format(timestampLayout))
+}
+
+func inScope(dn, base string, scope int) bool {
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == base
+	case ldap.ScopeSingleLevel:
+		return dn != base && parentDN(dn) == base
+	default:
+		return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+func parentDN(dn string) string {
+	if i := strings.Index(dn, ","); i >= 0 {
+		return dn[i+1:]
+	}
+	return ""
+}
+
+// normalizeDN lower-cases attribute types and values and drops insignificant
+// spaces so DNs can be compared as strings.
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		parts := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			parts = append(parts, strings.ToLower(attr.Type)+"="+ldap.EscapeDN(strings.ToLower(attr.Value)))
+		}
+		sort.Strings(parts)
+		rdns = append(rdns, strings.Join(parts, "+"))
+	}
+
+	return strings.Join(rdns, ",")
+}
+
+func containsDN(values []string, key string) bool {
+	for _, v := range values {
+		if normalizeDN(v) == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package ldaptest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// matchFilter evaluates an encoded search filter against e. The caller must
+// hold d.mu.
+func (d *directory) matchFilter(f *ber.Packet, e *Entry) (bool, error) {
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, child := range f.Children {
+			ok, err := d.matchFilter(child, e)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case ldap.FilterOr:
+		for _, child := range f.Children {
+			ok, err := d.matchFilter(child, e)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+
+	case ldap.FilterNot:
+		if len(f.Children) != 1 {
+			return false, fmt.Errorf("malformed not filter")
+		}
+		ok, err := d.matchFilter(f.Children[0], e)
+		return !ok, err
+
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch:
+		name, value, err := assertion(f)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range d.values(e, name) {
+			if equalValues(name, v, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		name, value, err := assertion(f)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range d.values(e, name) {
+			c := compareValues(v, value)
+			if (f.Tag == ldap.FilterGreaterOrEqual && c >= 0) || (f.Tag == ldap.FilterLessOrEqual && c <= 0) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case ldap.FilterPresent:
+		name := packetString(f)
+		if strings.EqualFold(name, "objectClass") {
+			return true, nil
+		}
+		return len(d.values(e, name)) > 0, nil
+
+	case ldap.FilterSubstrings:
+		if len(f.Children) != 2 {
+			return false, fmt.Errorf("malformed substrings filter")
+		}
+		name := packetString(f.Children[0])
+		for _, v := range d.values(e, name) {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case ldap.FilterExtensibleMatch:
+		var rule, name, value string
+		for _, child := range f.Children {
+			switch child.Tag {
+			case 1:
+				rule = packetString(child)
+			case 2:
+				name = packetString(child)
+			case 3:
+				value = packetString(child)
+			}
+		}
+
+		// Like an unknown rule on a real server, anything else evaluates to
+		// Undefined and never matches.
+		if rule != MatchingRuleInChain || !d.inChain || name == "" {
+			return false, nil
+		}
+		return d.memberInChain(e, name, normalizeDN(value), make(map[string]bool)), nil
+	}
+
+	return false, fmt.Errorf("unsupported filter tag %d", f.Tag)
+}
+
+// memberInChain reports whether target is reachable from e by following the
+// DN values of attr, i.e. LDAP_MATCHING_RULE_IN_CHAIN.
+func (d *directory) memberInChain(e *Entry, attr, target string, visited map[string]bool) bool {
+	key := normalizeDN(e.DN)
+	if visited[key] {
+		return false
+	}
+	visited[key] = true
+
+	for _, v := range d.values(e, attr) {
+		if normalizeDN(v) == target {
+			return true
+		}
+		if child := d.lookup(v); child != nil && d.memberInChain(child, attr, target, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func assertion(f *ber.Packet) (string, string, error) {
+	if len(f.Children) != 2 {
+		return "", "", fmt.Errorf("malformed attribute value assertion")
+	}
+	return packetString(f.Children[0]), packetString(f.Children[1]), nil
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	pos := 0
+	for _, part := range parts {
+		sub := strings.ToLower(packetString(part))
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, sub) {
+				return false
+			}
+			pos = len(sub)
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value[pos:], sub)
+			if i < 0 {
+				return false
+			}
+			pos += i + len(sub)
+		case ldap.FilterSubstringsFinal:
+			if len(value)-pos < len(sub) || !strings.HasSuffix(value, sub) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func equalValues(name, a, b string) bool {
+	if dnAttributes[strings.ToLower(name)] {
+		return normalizeDN(a) == normalizeDN(b)
+	}
+	return strings.EqualFold(a, b)
+}
+
+// compareValues orders integers numerically and everything else (including
+// generalized time) lexically.
+func compareValues(a, b string) int {
+	ai, errA := strconv.ParseInt(a, 10, 64)
+	bi, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case ai < bi:
+			return -1
+		case ai > bi:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// packetString returns the value of a primitive packet, whether it was decoded
+// as a universal octet string or left as raw context-specific data.
+func packetString(p *ber.Packet) string {
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+	if p.Data != nil {
+		return p.Data.String()
+	}
+	return ""
+}
@@ -0,0 +1,14 @@
+package ldaptest
+
+import _ "embed"
+
+// ITCollege is a small directory with the layout of dc=it-college,dc=ru.
+//
+//go:embed testdata/it-college.ldif
+var ITCollege string
+
+// Service account of the ITCollege fixture.
+const (
+	ServiceDN       = "cn=service,dc=it-college,dc=ru"
+	ServicePassword = "service-pass"
+)
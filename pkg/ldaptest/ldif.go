@@ -0,0 +1,113 @@
+package ldaptest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ParseLDIF reads content records (dn plus attributes) from an LDIF document.
+// Change records, URLs and options are not supported.
+func ParseLDIF(data string) ([]*Entry, error) {
+	var entries []*Entry
+	var lines []string
+
+	flush := func() error {
+		if len(lines) == 0 {
+			return nil
+		}
+		entry, err := parseRecord(lines)
+		lines = lines[:0]
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, " "):
+			if len(lines) == 0 {
+				return nil, fmt.Errorf("ldif: continuation line without a preceding attribute")
+			}
+			lines[len(lines)-1] += line[1:]
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func parseRecord(lines []string) (*Entry, error) {
+	var entry *Entry
+
+	for _, line := range lines {
+		name, value, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry == nil {
+			if strings.EqualFold(name, "version") {
+				return nil, nil
+			}
+			if !strings.EqualFold(name, "dn") {
+				return nil, fmt.Errorf("ldif: record must start with dn, got %q", name)
+			}
+			entry = NewEntry(value)
+			continue
+		}
+
+		if strings.EqualFold(name, "changetype") {
+			return nil, fmt.Errorf("ldif: change records are not supported (%s)", entry.DN)
+		}
+
+		entry.Add(name, value)
+	}
+
+	return entry, nil
+}
+
+func parseLine(line string) (string, string, error) {
+	i := strings.Index(line, ":")
+	if i <= 0 {
+		return "", "", fmt.Errorf("ldif: malformed line %q", line)
+	}
+
+	name := line[:i]
+	rest := line[i+1:]
+
+	if strings.HasPrefix(rest, ":") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(rest[1:]))
+		if err != nil {
+			return "", "", fmt.Errorf("ldif: invalid base64 value for %s: %w", name, err)
+		}
+		return name, string(decoded), nil
+	}
+
+	if strings.HasPrefix(rest, "<") {
+		return "", "", fmt.Errorf("ldif: URL values are not supported (%s)", name)
+	}
+
+	return name, strings.TrimLeft(rest, " "), nil
+}
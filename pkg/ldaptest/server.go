@@ -0,0 +1,573 @@
+// Package ldaptest provides an in-memory LDAP server for tests. It implements
+// the subset of LDAPv3 the service relies on: simple bind (with the password
+// policy response control), search with filters, scopes, size limits and the
+// paged results control, and the password modify extended operation. Group
+// membership is exposed through a computed memberOf, like the memberof
+// overlay.
+package ldaptest
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const passwordModifyOID = "1.3.6.1.4.1.4203.1.11.1"
+
+// MatchingRuleInChain is the transitive membership matching rule of Active
+// Directory.
+const MatchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+type Server struct {
+	dir      *directory
+	ln       net.Listener
+	managers map[string]bool
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type Option func(*Server)
+
+// WithManager lets dn change other entries' passwords, like an ACL granting
+// the service account write access to userPassword.
+func WithManager(dn string) Option {
+	return func(s *Server) {
+		s.managers[normalizeDN(dn)] = true
+	}
+}
+
+// WithInChainMatching enables MatchingRuleInChain in extensible filters. By
+// default the server behaves like OpenLDAP and never matches it.
+func WithInChainMatching() Option {
+	return func(s *Server) {
+		s.dir.inChain = true
+	}
+}
+
+// Start runs a server seeded from ldif and stops it when the test ends.
+func Start(tb testing.TB, ldif string, opts ...Option) *Server {
+	tb.Helper()
+
+	s, err := NewServer(ldif, opts...)
+	if err != nil {
+		tb.Fatalf("ldaptest: %v", err)
+	}
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+func NewServer(ldif string, opts ...Option) (*Server, error) {
+	entries, err := ParseLDIF(ldif)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := newDirectory(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	s := &Server{
+		dir:      dir,
+		ln:       ln,
+		managers: make(map[string]bool),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Server) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.ln.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Attribute returns the values of name on the entry at dn, including virtual
+// attributes such as memberOf.
+func (s *Server) Attribute(dn, name string) []string {
+	s.dir.mu.RLock()
+	defer s.dir.mu.RUnlock()
+
+	e := s.dir.lookup(dn)
+	if e == nil {
+		return nil
+	}
+	return append([]string(nil), s.dir.values(e, name)...)
+}
+
+// Modify replaces the values of name on the entry at dn; no values removes
+// the attribute. modifyTimestamp is bumped unless it is the attribute set.
+func (s *Server) Modify(dn, name string, values ...string) error {
+	s.dir.mu.Lock()
+	defer s.dir.mu.Unlock()
+
+	e := s.dir.lookup(dn)
+	if e == nil {
+		return fmt.Errorf("no such entry: %s", dn)
+	}
+	e.Replace(name, values...)
+	if !strings.EqualFold(name, "modifyTimestamp") {
+		s.dir.touch(e)
+	}
+
+	return nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			c.Close()
+		}()
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	boundDN := ""
+
+	for {
+		p, err := ber.ReadPacket(c)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+
+		id, ok := p.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+
+		op := p.Children[1]
+		controls := requestControls(p)
+
+		var responses []*ber.Packet
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			var resp *ber.Packet
+			resp, boundDN = s.bind(op, controls)
+			responses = append(responses, resp)
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationAbandonRequest:
+			continue
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(op, controls)
+		case ldap.ApplicationExtendedRequest:
+			responses = append(responses, message(s.extended(op, boundDN)))
+		default:
+			responses = append(responses, message(result(op.Tag+1, ldap.LDAPResultUnwillingToPerform, "operation not supported")))
+		}
+
+		for _, resp := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+			for _, child := range resp.Children {
+				envelope.AppendChild(child)
+			}
+			if _, err := c.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet, controls map[string]*ber.Packet) (*ber.Packet, string) {
+	if len(op.Children) < 3 {
+		return message(result(ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "malformed bind request")), ""
+	}
+
+	name := packetString(op.Children[1])
+	auth := op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		return message(result(ldap.ApplicationBindResponse, ldap.LDAPResultAuthMethodNotSupported, "only simple bind is supported")), ""
+	}
+	password := packetString(auth)
+
+	if name == "" {
+		return message(result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, "")), ""
+	}
+	if password == "" {
+		return message(result(ldap.ApplicationBindResponse, ldap.LDAPResultUnwillingToPerform, "unauthenticated bind (DN with no password) disallowed")), ""
+	}
+
+	s.dir.mu.RLock()
+	defer s.dir.mu.RUnlock()
+
+	_, wantPolicy := controls[ldap.ControlTypeBeheraPasswordPolicy]
+
+	e := s.dir.lookup(name)
+	if e == nil || !containsValue(e.Get("userPassword"), password) {
+		return message(result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "")), ""
+	}
+
+	if len(e.Get("pwdAccountLockedTime")) > 0 {
+		resp := message(result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, ""))
+		if wantPolicy {
+			resp.AppendChild(responseControls(passwordPolicyControl(ldap.BeheraAccountLocked)))
+		}
+		return resp, ""
+	}
+
+	resp := message(result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, ""))
+	if wantPolicy && containsValue(e.Get("pwdReset"), "TRUE") {
+		resp.AppendChild(responseControls(passwordPolicyControl(ldap.BeheraChangeAfterReset)))
+	}
+
+	return resp, e.DN
+}
+
+func (s *Server) search(op *ber.Packet, controls map[string]*ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{message(result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "malformed search request"))}
+	}
+
+	base := packetString(op.Children[0])
+	scope, _ := op.Children[1].Value.(int64)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	typesOnly, _ := op.Children[5].Value.(bool)
+	filter := op.Children[6]
+
+	var attrs []string
+	for _, a := range op.Children[7].Children {
+		attrs = append(attrs, packetString(a))
+	}
+
+	s.dir.mu.RLock()
+	defer s.dir.mu.RUnlock()
+
+	var filterErr error
+	matched, ok := s.dir.search(base, int(scope), func(e *Entry) bool {
+		m, err := s.dir.matchFilter(filter, e)
+		if err != nil {
+			filterErr = err
+		}
+		return m
+	})
+	if !ok {
+		return []*ber.Packet{message(result(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, ""))}
+	}
+	if filterErr != nil {
+		return []*ber.Packet{message(result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, filterErr.Error()))}
+	}
+
+	code := uint16(ldap.LDAPResultSuccess)
+	var pagingResponse *ber.Packet
+
+	if raw, ok := controls[ldap.ControlTypePaging]; ok {
+		page, next, err := pageOf(raw, matched)
+		if err != nil {
+			return []*ber.Packet{message(result(ldap.ApplicationSearchResultDone, ldap.LDAPResultUnwillingToPerform, err.Error()))}
+		}
+		matched = page
+
+		paging := ldap.NewControlPaging(0)
+		paging.SetCookie([]byte(next))
+		pagingResponse = paging.Encode()
+	} else if sizeLimit > 0 && int64(len(matched)) > sizeLimit {
+		matched = matched[:sizeLimit]
+		code = ldap.LDAPResultSizeLimitExceeded
+	}
+
+	responses := make([]*ber.Packet, 0, len(matched)+1)
+	for _, e := range matched {
+		responses = append(responses, message(s.entryPacket(e, attrs, typesOnly)))
+	}
+
+	done := message(result(ldap.ApplicationSearchResultDone, code, ""))
+	if pagingResponse != nil {
+		done.AppendChild(responseControls(pagingResponse))
+	}
+
+	return append(responses, done)
+}
+
+// pageOf slices matched according to a paged results control. The cookie is
+// the offset of the next page.
+func pageOf(raw *ber.Packet, matched []*Entry) ([]*Entry, string, error) {
+	control, err := ldap.DecodeControl(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid paging control: %w", err)
+	}
+	paging, ok := control.(*ldap.ControlPaging)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid paging control")
+	}
+
+	offset := 0
+	if len(paging.Cookie) > 0 {
+		offset, err = strconv.Atoi(string(paging.Cookie))
+		if err != nil || offset < 0 || offset > len(matched) {
+			return nil, "", fmt.Errorf("invalid paging cookie")
+		}
+	}
+
+	if paging.PagingSize == 0 {
+		return nil, "", nil
+	}
+
+	end := offset + int(paging.PagingSize)
+	if end >= len(matched) {
+		return matched[offset:], "", nil
+	}
+
+	return matched[offset:end], strconv.Itoa(end), nil
+}
+
+func (s *Server) entryPacket(e *Entry, requested []string, typesOnly bool) *ber.Packet {
+	resp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "DN"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for _, a := range s.dir.selectAttributes(e, requested) {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, a.name, "Type"))
+
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		if !typesOnly {
+			for _, v := range a.values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	resp.AppendChild(list)
+
+	return resp
+}
+
+// selectAttributes applies the requested attribute list: none or "*" means
+// all user attributes, "+" all operational ones, "1.1" none. userPassword is
+// never returned.
+func (d *directory) selectAttributes(e *Entry, requested []string) []attribute {
+	all := len(requested) == 0
+	operational := false
+	var explicit []string
+
+	for _, r := range requested {
+		switch r {
+		case "*":
+			all = true
+		case "+":
+			operational = true
+		case "1.1", "":
+		default:
+			explicit = append(explicit, r)
+		}
+	}
+
+	var out []attribute
+	seen := map[string]bool{"userpassword": true}
+
+	add := func(name string, values []string) {
+		key := strings.ToLower(name)
+		if seen[key] || len(values) == 0 {
+			return
+		}
+		seen[key] = true
+		out = append(out, attribute{name: name, values: values})
+	}
+
+	for _, r := range explicit {
+		add(r, d.values(e, r))
+	}
+
+	if all {
+		for _, a := range e.attrs {
+			if !operationalAttributes[strings.ToLower(a.name)] {
+				add(a.name, a.values)
+			}
+		}
+	}
+
+	if operational {
+		for _, name := range []string{"memberOf", "entryDN", "createTimestamp", "modifyTimestamp", "pwdChangedTime", "pwdAccountLockedTime", "pwdReset"} {
+			add(name, d.values(e, name))
+		}
+	}
+
+	return out
+}
+
+func (s *Server) extended(op *ber.Packet, boundDN string) *ber.Packet {
+	if len(op.Children) == 0 || packetString(op.Children[0]) != passwordModifyOID {
+		return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "unsupported extended operation")
+	}
+
+	if boundDN == "" {
+		return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultInsufficientAccessRights, "password modify requires an authenticated session")
+	}
+
+	var identity, oldPass, newPass string
+	if len(op.Children) > 1 && op.Children[1].Data.Len() > 0 {
+		value, err := ber.DecodePacketErr(op.Children[1].Data.Bytes())
+		if err != nil {
+			return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "malformed password modify request")
+		}
+		for _, child := range value.Children {
+			switch child.Tag {
+			case 0:
+				identity = packetString(child)
+			case 1:
+				oldPass = packetString(child)
+			case 2:
+				newPass = packetString(child)
+			}
+		}
+	}
+
+	target := identity
+	if target == "" {
+		target = boundDN
+	}
+
+	s.dir.mu.Lock()
+	defer s.dir.mu.Unlock()
+
+	e := s.dir.lookup(target)
+	if e == nil {
+		return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultNoSuchObject, "")
+	}
+
+	self := normalizeDN(e.DN) == normalizeDN(boundDN)
+	if !self && !s.managers[normalizeDN(boundDN)] {
+		return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultInsufficientAccessRights, "no write access to userPassword")
+	}
+
+	if oldPass != "" && !containsValue(e.Get("userPassword"), oldPass) {
+		return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform, "unwilling to verify old password")
+	}
+
+	if newPass == "" {
+		return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform, "password generation is not supported")
+	}
+
+	e.Replace("userPassword", newPass)
+	e.Replace("pwdChangedTime", time.Now().UTC().Format(timestampLayout))
+	if self {
+		e.Replace("pwdReset")
+	}
+	s.dir.touch(e)
+
+	return result(ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess, "")
+}
+
+// requestControls indexes the request's controls by OID, keeping the raw
+// packets so only the ones the server understands are decoded.
+func requestControls(p *ber.Packet) map[string]*ber.Packet {
+	controls := make(map[string]*ber.Packet)
+	if len(p.Children) < 3 {
+		return controls
+	}
+
+	for _, c := range p.Children[2].Children {
+		if len(c.Children) > 0 {
+			controls[packetString(c.Children[0])] = c
+		}
+	}
+
+	return controls
+}
+
+func responseControls(controls ...*ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+	for _, c := range controls {
+		packet.AppendChild(c)
+	}
+	return packet
+}
+
+func passwordPolicyControl(code int) *ber.Packet {
+	value := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Password Policy Response")
+	value.AppendChild(ber.NewInteger(ber.ClassContext, ber.TypePrimitive, 1, int64(code), "Error"))
+
+	control := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Control")
+	control.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, ldap.ControlTypeBeheraPasswordPolicy, "Control Type"))
+	control.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, string(value.Bytes()), "Control Value"))
+
+	return control
+}
+
+// message wraps a protocol op so handle can prepend the message ID; extra
+// children (response controls) are appended after it.
+func message(op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Message")
+	p.AppendChild(op)
+	return p
+}
+
+func result(tag ber.Tag, code uint16, diagnostic string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, ldap.LDAPResultCodeMap[code])
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, diagnostic, "Diagnostic Message"))
+	return p
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+// Package lrucache is a size-bounded in-memory cache whose entries also
+// expire after a fixed TTL.
+package lrucache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Stats are counters since the cache was created; Flush does not reset them.
+type Stats struct {
+	Capacity  int    `json:"capacity"`
+	Size      int    `json:"size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+type entry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// Cache is safe for concurrent use. A cache with capacity or ttl of zero
+// stores nothing and only counts misses.
+type Cache[V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	items    map[string]*list.Element
+	stats    Stats
+	now      func() time.Time
+}
+
+func New[V any](capacity int, ttl time.Duration) *Cache[V] {
+	if capacity < 0 || ttl <= 0 {
+		capacity = 0
+	}
+
+	return &Cache[V]{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[V])
+		if c.now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, true
+		}
+		c.remove(el)
+	}
+
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// Set stores value under key, evicting the least recently used entry when
+// the cache is full.
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.capacity == 0 {
+		return
+	}
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[V])
+		e.value = value
+		e.expires = expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[V]{key: key, value: value, expires: expires})
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Flush removes every entry and returns how many there were.
+func (c *Cache[V]) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.order.Len()
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+
+	return n
+}
+
+func (c *Cache[V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Capacity = c.capacity
+	stats.Size = c.order.Len()
+
+	return stats
+}
+
+func (c *Cache[V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[V]).key)
+}
@@ -0,0 +1,69 @@
+package lrucache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New[int](2, time.Minute)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected a=1, got %d, %v", v, ok)
+	}
+
+	stats := c.Stats()
+	if stats.Size != 2 || stats.Evictions != 1 || stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	now := time.Now()
+	c := New[string](10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("q", "value")
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("q"); !ok {
+		t.Error("expected q before the TTL")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.Get("q"); ok {
+		t.Error("expected q to expire")
+	}
+	if size := c.Stats().Size; size != 0 {
+		t.Errorf("expected the expired entry to be dropped, size %d", size)
+	}
+}
+
+func TestCacheFlush(t *testing.T) {
+	c := New[int](10, time.Minute)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	if n := c.Flush(); n != 2 {
+		t.Errorf("expected 2 flushed entries, got %d", n)
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected an empty cache after flush")
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := New[int](0, time.Minute)
+	c.Set("a", 1)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("expected a disabled cache to store nothing")
+	}
+}
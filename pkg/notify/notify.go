@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-auth-svc/internal/config"
+	"github.com/anton1ks96/college-auth-svc/pkg/logger"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func New(cfg *config.Config) (Notifier, error) {
+	switch cfg.Reset.Notifier {
+	case "smtp":
+		if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+			return nil, fmt.Errorf("smtp notifier requires smtp.host and smtp.from")
+		}
+		return NewSMTPNotifier(cfg.SMTP), nil
+	case "", "log":
+		return NewFileNotifier(cfg.Reset.FilePath), nil
+	default:
+		return nil, fmt.Errorf("unknown notifier %q", cfg.Reset.Notifier)
+	}
+}
+
+type SMTPNotifier struct {
+	cfg config.SMTPConfig
+}
+
+func NewSMTPNotifier(cfg config.SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg}
+}
+
+func (s *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	if err := smtp.SendMail(addr, auth, s.cfg.From, []string{msg.To}, []byte(b.String())); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
+
+// FileNotifier appends messages to a file, or writes them to the log when no
+// path is set. Meant for development and tests.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (f *FileNotifier) Send(ctx context.Context, msg Message) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if f.path == "" {
+		logger.Info(fmt.Sprintf("notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body))
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "--- %s\nTo: %s\nSubject: %s\n\n%s\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,44 @@
+package passhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	encoded, err := Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+
+	if ok, err := Verify("Correct-Horse-1", encoded); err != nil || !ok {
+		t.Errorf("expected the password to match, got %v, %v", ok, err)
+	}
+	if ok, err := Verify("correct-horse-1", encoded); err != nil || ok {
+		t.Errorf("expected a wrong password not to match, got %v, %v", ok, err)
+	}
+
+	other, _ := Hash("Correct-Horse-1")
+	if other == encoded {
+		t.Error("expected a fresh salt for every hash")
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"plain",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=0,t=2,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!$aGFzaA",
+	} {
+		if _, err := Verify("x", encoded); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%q: expected %v, got %v", encoded, ErrInvalidHash, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// The SHA1 vectors of RFC 6238, appendix B, truncated to 6 digits.
+func TestCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := Code(key, time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("at %d: expected %q, got %q", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := NewSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key, err := DecodeSecret(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	code := Code(key, now.Add(-30*time.Second))
+
+	step, ok := Validate(key, code, now)
+	if !ok {
+		t.Fatal("expected the previous step to be accepted")
+	}
+	if step != now.Unix()/30-1 {
+		t.Errorf("expected step %d, got %d", now.Unix()/30-1, step)
+	}
+
+	if _, ok := Validate(key, code, now.Add(2*time.Minute)); ok {
+		t.Error("expected an old code to be rejected")
+	}
+	if _, ok := Validate(key, "12345", now); ok {
+		t.Error("expected a short code to be rejected")
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	if _, err := DecodeSecret("gezd gnbv gy3t qojq"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := DecodeSecret("not base32!"); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
@@ -0,0 +1,198 @@
+// Package translit normalises Russian names and converts them between
+// Cyrillic and Latin script so that names typed in either script, or with
+// small typos, can be matched against directory entries.
+package translit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// toLatin follows GOST 7.79-2000 system B, the ASCII variant of ISO 9.
+var toLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "x", 'ц': "cz", 'ч': "ch", 'ш': "sh", 'щ': "shh", 'ъ': "``",
+	'ы': "y'", 'ь': "`", 'э': "e`", 'ю': "yu", 'я': "ya",
+}
+
+// toCyrillic accepts GOST 7.79 system B as well as the passport-style
+// spellings people usually type ("kh", "ts", "iy"). Longer sequences are
+// tried first.
+var toCyrillic = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"},
+	{"iya", "ия"}, {"shh", "щ"}, {"sch", "щ"},
+	{"iy", "ий"}, {"yy", "ый"}, {"ij", "ий"},
+	{"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"tz", "ц"}, {"cz", "ц"}, {"ch", "ч"},
+	{"sh", "ш"}, {"yu", "ю"}, {"ju", "ю"}, {"ya", "я"}, {"ja", "я"},
+	{"yo", "е"}, {"jo", "е"}, {"ye", "е"}, {"je", "е"},
+	{"a", "а"}, {"b", "б"}, {"v", "в"}, {"w", "в"}, {"g", "г"}, {"d", "д"},
+	{"e", "е"}, {"z", "з"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"q", "к"},
+	{"l", "л"}, {"m", "м"}, {"n", "н"}, {"o", "о"}, {"p", "п"}, {"r", "р"},
+	{"s", "с"}, {"t", "т"}, {"u", "у"}, {"f", "ф"}, {"h", "х"}, {"x", "х"},
+	{"c", "ц"}, {"y", "ы"},
+}
+
+// Normalize lower-cases s, folds ё into е and collapses everything that is
+// not a letter or digit into single spaces.
+func Normalize(s string) string {
+	var b strings.Builder
+	space := false
+
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r == 'ё':
+			r = 'е'
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			space = b.Len() > 0
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// ToLatin transliterates the Cyrillic letters of s to Latin. Other
+// characters are kept; the result is lower case.
+func ToLatin(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if latin, ok := toLatin[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToCyrillic transliterates the Latin letters of s to Cyrillic. Other
+// characters are kept; the result is lower case.
+func ToCyrillic(s string) string {
+	s = strings.ToLower(s)
+
+	var b strings.Builder
+	for len(s) > 0 {
+		matched := false
+		for _, t := range toCyrillic {
+			if strings.HasPrefix(s, t.latin) {
+				b.WriteString(t.cyrillic)
+				s = s[len(t.latin):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		r := []rune(s)[0]
+		b.WriteRune(r)
+		s = s[len(string(r)):]
+	}
+
+	return b.String()
+}
+
+// Distance returns the Levenshtein distance between a and b in runes.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// Score matches query against text word by word in either script. Every
+// query word has to match a word of text, either as a prefix or within a
+// few typos depending on its length. ok reports whether text matched;
+// score is the total number of typos, so lower is better.
+func Score(query, text string) (score int, ok bool) {
+	query = Normalize(query)
+	text = Normalize(text)
+	if query == "" {
+		return 0, true
+	}
+
+	best := -1
+	for _, pair := range [][2]string{
+		{query, text},
+		{Normalize(ToCyrillic(query)), text},
+		{query, Normalize(ToLatin(text))},
+	} {
+		if s, ok := scoreWords(strings.Fields(pair[0]), strings.Fields(pair[1])); ok && (best < 0 || s < best) {
+			best = s
+		}
+	}
+
+	return best, best >= 0
+}
+
+func scoreWords(query, text []string) (int, bool) {
+	total := 0
+	for _, q := range query {
+		best := -1
+		for _, t := range text {
+			if d, ok := matchWord(q, t); ok && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best < 0 {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
+
+// matchWord compares q with t and with the prefix of t of the same length,
+// so that partially typed words still match.
+func matchWord(q, t string) (int, bool) {
+	if strings.HasPrefix(t, q) {
+		return 0, true
+	}
+
+	d := Distance(q, t)
+	if rq, rt := []rune(q), []rune(t); len(rt) > len(rq) {
+		d = min(d, Distance(q, string(rt[:len(rq)])))
+	}
+
+	return d, d <= allowedTypos(q)
+}
+
+func allowedTypos(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,69 @@
+package translit
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("  Семёнов,  Пётр-Алексеевич "); got != "семенов петр алексеевич" {
+		t.Errorf("expected %q, got %q", "семенов петр алексеевич", got)
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	if got := ToLatin("Щукин Цезарь"); got != "shhukin czezar`" {
+		t.Errorf("expected %q, got %q", "shhukin czezar`", got)
+	}
+	if got := ToCyrillic("Kolomatskiy Ivan"); got != "коломацкий иван" {
+		t.Errorf("expected %q, got %q", "коломацкий иван", got)
+	}
+	if got := ToCyrillic("Zhukova Yuliya"); got != "жукова юлия" {
+		t.Errorf("expected %q, got %q", "жукова юлия", got)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"иван", "иван", 0},
+		{"иван", "ивна", 2},
+		{"коломацкий", "колмацкий", 1},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q): expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		text   string
+		want   int
+		wantOK bool
+	}{
+		{"prefix", "колом", "Коломацкий Иван", 0, true},
+		{"ё folded", "Семёнов", "Семенов Петр", 0, true},
+		{"latin query", "kolomatskiy", "Коломацкий Иван", 0, true},
+		{"gost query", "kolomaczkij", "Коломацкий Иван", 0, true},
+		{"typo", "коломатский", "Коломацкий Иван", 2, true},
+		{"several words", "ivan kolomackiy", "Коломацкий Иван", 0, true},
+		{"too many typos", "иванов", "Петров Иван", 0, false},
+		{"short words are exact", "ива", "Ира", 0, false},
+		{"every word must match", "иван петров", "Коломацкий Иван", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Score(tt.query, tt.text)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("expected %d, %v, got %d, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, "ИТ24-11: список")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.WriteRow([]string{"ID", "ФИО"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.WriteRow([]string{"i24s0001", "Иванов <Иван> & Co"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected a zip archive: %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(body)
+	}
+
+	if !strings.Contains(files["xl/workbook.xml"], `name="ИТ24-11_ список"`) {
+		t.Errorf("expected a sanitised sheet name, got %s", files["xl/workbook.xml"])
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="B1" t="inlineStr"><is><t xml:space="preserve">ФИО</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">i24s0001</t></is></c>`,
+		`Иванов &lt;Иван&gt; &amp; Co`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("expected sheet to contain %q, got %s", want, sheet)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("column %d: expected %q, got %q", i, want, got)
+		}
+	}
+}